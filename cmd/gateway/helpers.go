@@ -27,7 +27,9 @@ import (
 	"github.com/kazhuravlev/database-gateway/internal/config"
 	"github.com/kazhuravlev/database-gateway/internal/migrator"
 	"github.com/kazhuravlev/database-gateway/internal/pgdb"
+	"github.com/kazhuravlev/database-gateway/internal/policy"
 	"github.com/kazhuravlev/database-gateway/internal/policy/opa"
+	"github.com/kazhuravlev/database-gateway/internal/policy/opahttp"
 	"github.com/kazhuravlev/database-gateway/internal/storage"
 	"github.com/kazhuravlev/database-gateway/internal/storage/migrations"
 	"github.com/kazhuravlev/just"
@@ -47,10 +49,19 @@ func withConfig(action func(c *cli.Context, cfg config.Config) error) cli.Action
 			cfg.Policy.Path = filepath.Join(filepath.Dir(configFilename), cfg.Policy.Path)
 		}
 
+		if err := cfg.ResolveSecrets(c.Context, newSecrets()); err != nil {
+			return fmt.Errorf("resolve secrets: %w", err)
+		}
+
 		return action(c, *cfg)
 	}
 }
 
+// newSecrets returns resolver of secret references in config. Register resolvers of other secret stores here.
+func newSecrets() *config.Secrets {
+	return config.NewSecrets()
+}
+
 func newMigrator(cfg config.PostgresConfig) (*migrator.Migrator, error) { //nolint:gocritic
 	dbConn, err := pgdb.ConnectToPg(cfg)
 	if err != nil {
@@ -104,21 +115,83 @@ func withApp(
 			return fmt.Errorf("init storage: %w", err)
 		}
 
-		modules, err := opa.LoadModules(cfg.Policy.Path)
+		authorizer, err := newAuthorizer(ctx, cfg.Policy, logger)
 		if err != nil {
-			return fmt.Errorf("load policy modules: %w", err)
+			return fmt.Errorf("init authorizer: %w", err)
 		}
 
-		authorizer, err := opa.New(ctx, modules)
-		if err != nil {
-			return fmt.Errorf("init opa authorizer: %w", err)
+		appOpts := []app.OptOptionsSetter{app.WithSecrets(newSecrets())}
+		if cfg.BreakGlass.MaxTTL != 0 {
+			appOpts = append(appOpts, app.WithBreakGlassMaxTTL(cfg.BreakGlass.MaxTTL.D()))
+		}
+		if cfg.Preview.TTL != 0 {
+			appOpts = append(appOpts, app.WithPreviewTTL(cfg.Preview.TTL.D()))
+		}
+		if cfg.Async.Workers != 0 {
+			appOpts = append(appOpts, app.WithAsyncWorkers(cfg.Async.Workers))
+		}
+		if cfg.Async.QueueSize != 0 {
+			appOpts = append(appOpts, app.WithAsyncQueueSize(cfg.Async.QueueSize))
+		}
+		if cfg.HealthCheck.Interval != 0 {
+			appOpts = append(appOpts, app.WithHealthCheckInterval(cfg.HealthCheck.Interval.D()))
+		}
+		if cfg.HealthCheck.Timeout != 0 {
+			appOpts = append(appOpts, app.WithHealthCheckTimeout(cfg.HealthCheck.Timeout.D()))
 		}
 
-		appInst, err := app.New(app.NewOptions(logger, cfg.Targets, cfg.Users, authorizer, storageInst))
+		appInst, err := app.New(app.NewOptions(logger, cfg.Targets, cfg.Users, authorizer, storageInst, appOpts...))
 		if err != nil {
 			return fmt.Errorf("create app instance: %w", err)
 		}
+		defer appInst.Close()
 
 		return cmd(ctx, c, cfg, appInst, logger)
 	}
 }
+
+// newAuthorizer creates a remote authorizer when it is configured. Otherwise, it creates an embedded OPA authorizer
+// and reloads it on policy changes until ctx is done.
+func newAuthorizer(ctx context.Context, cfg config.PolicyConfig, logger *slog.Logger) (policy.Authorizer, error) { //nolint:ireturn
+	if remote := cfg.Remote; remote != nil {
+		opts := []opahttp.OptOptionsSetter{opahttp.WithAuthHeader(remote.AuthHeader)}
+		if remote.Timeout != 0 {
+			opts = append(opts, opahttp.WithTimeout(remote.Timeout.D()))
+		}
+
+		authorizer, err := opahttp.New(opahttp.NewOptions(logger.With(slog.String("mod", "policy")), remote.URL, opts...))
+		if err != nil {
+			return nil, fmt.Errorf("init remote opa authorizer: %w", err)
+		}
+
+		return authorizer, nil
+	}
+
+	modules, err := opa.LoadModules(cfg.Path)
+	if err != nil {
+		return nil, fmt.Errorf("load policy modules: %w", err)
+	}
+
+	authorizer, err := opa.New(ctx, modules)
+	if err != nil {
+		return nil, fmt.Errorf("init opa authorizer: %w", err)
+	}
+
+	policyWatcher, err := opa.NewWatcher(opa.NewWatcherOptions(
+		logger.With(slog.String("mod", "policy")),
+		authorizer,
+		cfg.Path,
+		modules,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("init policy watcher: %w", err)
+	}
+
+	go func() {
+		if err := policyWatcher.Run(ctx); err != nil {
+			logger.Error("policy watcher stopped", slog.String("error", err.Error()))
+		}
+	}()
+
+	return authorizer, nil
+}
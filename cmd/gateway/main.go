@@ -18,6 +18,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
@@ -31,12 +32,17 @@ import (
 	"github.com/kazhuravlev/database-gateway/internal/config"
 	"github.com/kazhuravlev/database-gateway/internal/facade"
 	"github.com/kazhuravlev/database-gateway/internal/pgdb"
+	"github.com/kazhuravlev/database-gateway/internal/policy/opa"
+	"github.com/kazhuravlev/database-gateway/internal/policy/policytest"
 	"github.com/kazhuravlev/database-gateway/internal/uuid6"
 	_ "github.com/lib/pq"
 	"github.com/urfave/cli/v2"
 )
 
-const keyConfig = "config"
+const (
+	keyConfig = "config"
+	keyCases  = "cases"
+)
 
 func main() {
 	application := &cli.App{ //nolint:exhaustruct
@@ -54,6 +60,18 @@ func main() {
 				Name:   "run",
 				Action: withConfig(withApp(cmdRun)),
 			},
+			{
+				Name:        "policy-test",
+				Description: "Evaluate policy modules against a YAML/JSON fixture of cases",
+				Flags: []cli.Flag{
+					&cli.StringFlag{ //nolint:exhaustruct
+						Name:     keyCases,
+						Usage:    "path to the fixture file with test cases",
+						Required: true,
+					},
+				},
+				Action: withConfig(cmdPolicyTest),
+			},
 			{
 				Name:   "jet-generate",
 				Action: withConfig(cmdGenerateModels),
@@ -111,6 +129,49 @@ func cmdRun(
 	return nil
 }
 
+func cmdPolicyTest(c *cli.Context, cfg config.Config) error { //nolint:gocritic
+	// NOTE: the cases are evaluated against local modules only. Testing them instead of the configured remote policy
+	//  would report results of a policy that is not used.
+	if cfg.Policy.Remote != nil {
+		return errors.New("policy-test does not support policy.remote, it tests modules of policy.path") //nolint:err113
+	}
+
+	modules, err := opa.LoadModules(cfg.Policy.Path)
+	if err != nil {
+		return fmt.Errorf("load policy modules: %w", err)
+	}
+
+	authorizer, err := opa.New(c.Context, modules)
+	if err != nil {
+		return fmt.Errorf("init opa authorizer: %w", err)
+	}
+
+	fixture, err := policytest.LoadFixture(c.String(keyCases))
+	if err != nil {
+		return fmt.Errorf("load fixture: %w", err)
+	}
+
+	failed := 0
+	for _, res := range policytest.Run(authorizer, *fixture) {
+		if res.Passed() {
+			_, _ = fmt.Fprintf(c.App.Writer, "PASS %s\n", res.Case.Name)
+
+			continue
+		}
+
+		failed++
+		_, _ = fmt.Fprintf(c.App.Writer, "FAIL %s: expected allow=%t, got allow=%t\n", res.Case.Name, res.Case.Allow, res.Got)
+	}
+
+	_, _ = fmt.Fprintf(c.App.Writer, "%d passed, %d failed\n", len(fixture.Cases)-failed, failed)
+
+	if failed != 0 {
+		return fmt.Errorf("%d of %d policy cases failed", failed, len(fixture.Cases)) //nolint:err113
+	}
+
+	return nil
+}
+
 func cmdGenerateModels(_ *cli.Context, cfg config.Config) error { //nolint:gocritic
 	// map[TABLE_NAME]map[FIELD_NAME]FIELD_TYPE
 	customFields := map[string]map[string]template.Type{
@@ -120,9 +181,31 @@ func cmdGenerateModels(_ *cli.Context, cfg config.Config) error { //nolint:gocri
 			"target_id": template.NewType(config.TargetID("")),
 			"response":  template.NewType([]byte{}),
 		},
+		"break_glass_grants": {
+			"id":         template.NewType(uuid6.Nil()),
+			"user_id":    template.NewType(config.UserID("")),
+			"target_id":  template.NewType(config.TargetID("")),
+			"revoked_by": template.NewType(config.UserID("")),
+		},
+		"audit_log": {
+			"id":        template.NewType(uuid6.Nil()),
+			"user_id":   template.NewType(config.UserID("")),
+			"target_id": template.NewType(config.TargetID("")),
+			"details":   template.NewType([]byte{}),
+		},
+		"query_approvals": {
+			"id":          template.NewType(uuid6.Nil()),
+			"user_id":     template.NewType(config.UserID("")),
+			"target_id":   template.NewType(config.TargetID("")),
+			"vectors":     template.NewType([]byte{}),
+			"reviewer_id": template.NewType(config.UserID("")),
+		},
 	}
 
-	postgresDSN := pgdb.BuildDBDsn(cfg.Storage)
+	postgresDSN, err := pgdb.BuildDBDsn(cfg.Storage)
+	if err != nil {
+		return fmt.Errorf("build storage dsn: %w", err)
+	}
 
 	dbTemplate := template.Default(postgres2.Dialect).
 		UseSchema(func(schema metadata.Schema) template.Schema {
@@ -3,16 +3,23 @@ package gateway
 default allow_target := false
 default allow_query := false
 
-# Break-glass access is explicit and narrow.
-allow_target if {
+# Break-glass access is explicit and narrow. The gateway adds "breakglass:<target>" to subjects only while the user
+# holds an active grant for that target.
+allow_target if break_glass
+
+allow_query if {
+	break_glass
+	input.op == "select"
+}
+
+break_glass if {
 	"role:oncall" in input.subjects
 	input.target == "taxi-prod"
 }
 
-allow_query if {
-	"role:oncall" in input.subjects
+break_glass if {
+	"breakglass:taxi-prod" in input.subjects
 	input.target == "taxi-prod"
-	input.op == "select"
 }
 
 allow_query if {
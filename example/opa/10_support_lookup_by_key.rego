@@ -0,0 +1,26 @@
+package gateway
+
+default allow_target := false
+default allow_query := false
+
+# Support can read clients only one-by-one: the query must filter by `id` and can not sort by personal data.
+sensitive_sort_columns := {"phone", "email"}
+
+allow_target if {
+	"role:support" in input.subjects
+	input.target == "taxi-prod"
+}
+
+allow_query if {
+	"role:support" in input.subjects
+	input.target == "taxi-prod"
+	input.op == "select"
+	input.table == "public.clients"
+	"id" in input.filter
+	not sorts_by_sensitive_column
+}
+
+sorts_by_sensitive_column if {
+	some column in input.sort
+	column in sensitive_sort_columns
+}
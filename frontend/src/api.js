@@ -189,10 +189,93 @@ export function listQueries(token, limit) {
   return rpcCall(token, "queries.list.v1", typeof limit === "number" ? { limit } : {});
 }
 
-export function runQuery(token, targetID, query) {
-  return rpcCall(token, "query.run.v1", {
+function formatQueryDenied(denied) {
+  const message = `Access denied: ${denied.op} on ${denied.table}`;
+  if (!Array.isArray(denied.reasons) || denied.reasons.length === 0) {
+    return message;
+  }
+
+  return `${message}: ${denied.reasons.join("; ")}`;
+}
+
+export async function runQuery(token, targetID, query, queryID = "", preview = false, primary = false) {
+  const result = await rpcCall(token, "query.run.v1", {
     target_id: targetID,
-    query
+    query,
+    ...(queryID ? { query_id: queryID } : {}),
+    ...(preview ? { preview: true } : {}),
+    ...(primary ? { primary: true } : {})
+  });
+  if (result?.denied) {
+    throw new Error(formatQueryDenied(result.denied));
+  }
+  if (result?.pending) {
+    throw new Error(`Query is waiting for approval (request ${result.pending.approval_id})`);
+  }
+  if (result?.cancelled) {
+    throw new Error(`Query cancelled: ${result.cancelled.reason}`);
+  }
+
+  return result;
+}
+
+export async function submitQuery(token, targetID, query, queryID = "", primary = false) {
+  const result = await rpcCall(token, "query.submit.v1", {
+    target_id: targetID,
+    query,
+    ...(queryID ? { query_id: queryID } : {}),
+    ...(primary ? { primary: true } : {})
+  });
+  if (result?.denied) {
+    throw new Error(formatQueryDenied(result.denied));
+  }
+  if (result?.pending) {
+    throw new Error(`Query is waiting for approval (request ${result.pending.approval_id})`);
+  }
+  return result;
+}
+
+export function getQueryStatus(token, queryID) {
+  return rpcCall(token, "query.status.v1", {
+    query_id: queryID
+  });
+}
+
+export function cancelQuery(token, queryID) {
+  return rpcCall(token, "query.cancel.v1", {
+    query_id: queryID
+  });
+}
+
+export function commitQuery(token, queryID) {
+  return rpcCall(token, "query.commit.v1", {
+    query_id: queryID
+  });
+}
+
+export function rollbackQuery(token, queryID) {
+  return rpcCall(token, "query.rollback.v1", {
+    query_id: queryID
+  });
+}
+
+export function listApprovals(token, pending = false) {
+  return rpcCall(token, "approvals.list.v1", {
+    pending
+  });
+}
+
+export function approveQuery(token, approvalID, comment = "") {
+  return rpcCall(token, "approvals.approve.v1", {
+    id: approvalID,
+    comment
+  });
+}
+
+export function rejectQuery(token, approvalID, comment = "") {
+  return rpcCall(token, "approvals.reject.v1", {
+    id: approvalID,
+    comment
   });
 }
 
@@ -202,6 +285,10 @@ export function listAdminRequests(token, page) {
   });
 }
 
+export function listAdminTargetPools(token) {
+  return rpcCall(token, "admin.target-pools.list.v1", {});
+}
+
 export function getQueryResults(token, queryResultID) {
   return rpcCall(token, "query-results.get.v1", {
     id: queryResultID
@@ -228,3 +315,21 @@ export function deleteBookmark(token, bookmarkID) {
     id: bookmarkID
   });
 }
+
+export function requestBreakGlass(token, targetID, reason, duration) {
+  return rpcCall(token, "break-glass.request.v1", {
+    target_id: targetID,
+    reason,
+    duration
+  });
+}
+
+export function listBreakGlassGrants(token) {
+  return rpcCall(token, "break-glass.list.v1", {});
+}
+
+export function revokeBreakGlass(token, grantID) {
+  return rpcCall(token, "break-glass.revoke.v1", {
+    id: grantID
+  });
+}
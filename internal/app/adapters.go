@@ -17,37 +17,124 @@
 package app
 
 import (
-	"bytes"
+	"database/sql/driver"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"math"
+	"strconv"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/kazhuravlev/database-gateway/internal/config"
+	"github.com/kazhuravlev/database-gateway/internal/storage"
 	"github.com/kazhuravlev/database-gateway/internal/structs"
+	"github.com/kazhuravlev/database-gateway/internal/validator"
 	"github.com/kazhuravlev/just"
 )
 
-func adaptPgType(val any) string {
+// adaptPgValue converts a value decoded by pgx into a JSON-ready cell of structs.QTable.
+func adaptPgValue(oid uint32, val any) any { //nolint:cyclop
 	switch val := val.(type) {
-	default:
-		return fmt.Sprint(val)
+	case nil, bool, string, int64, json.Number, map[string]any:
+		return val
+	case int8:
+		return int64(val)
+	case int16:
+		return int64(val)
+	case int32:
+		return int64(val)
+	case int:
+		return int64(val)
+	case uint32:
+		return int64(val)
+	case float32:
+		return adaptFloat(float64(val), 32) //nolint:mnd
+	case float64:
+		return adaptFloat(val, 64) //nolint:mnd
+	case []byte:
+		return `\x` + hex.EncodeToString(val)
+	case [16]byte:
+		return uuid.UUID(val).String()
+	case time.Time:
+		if oid == pgtype.DateOID || oid == pgtype.DateArrayOID {
+			return val.Format(time.DateOnly)
+		}
+
+		return val.Format(time.RFC3339Nano)
+	case []any:
+		return just.SliceMap(val, func(elem any) any {
+			return adaptPgValue(oid, elem)
+		})
 	case pgtype.Numeric:
-		// TODO: is that really best solution?
-		res, err := val.MarshalJSON()
+		if !val.Valid {
+			return nil
+		}
+
+		text, err := val.Value()
+		if err != nil {
+			return "--bad payload--"
+		}
+
+		// NOTE: NaN and infinities are not valid json numbers.
+		if val.NaN || val.InfinityModifier != pgtype.Finite {
+			return text
+		}
+
+		return json.Number(text.(string)) //nolint:forcetypeassert // text encoding always produces a string
+	case fmt.Stringer:
+		return val.String()
+	case driver.Valuer:
+		res, err := val.Value()
 		if err != nil {
 			return "--bad payload--"
 		}
 
-		return string(bytes.Trim(res, `"`))
+		return adaptPgValue(oid, res)
+	default:
+		return fmt.Sprint(val)
+	}
+}
+
+// adaptFloat keeps finite floats as numbers. NaN and infinities are not valid json numbers, so they become text.
+func adaptFloat(val float64, bitSize int) any {
+	if math.IsNaN(val) || math.IsInf(val, 0) {
+		return strconv.FormatFloat(val, 'g', -1, bitSize)
+	}
+
+	return val
+}
+
+// cellSize estimates the size of the cell when it is stored in the result payload.
+func cellSize(cell any) int64 {
+	switch cell := cell.(type) {
+	case nil:
+		return 0
+	case string:
+		return int64(len(cell))
+	case json.Number:
+		return int64(len(cell))
+	case bool, int64, float64:
+		return 8 //nolint:mnd
+	default:
+		buf, err := json.Marshal(cell)
+		if err != nil {
+			return 0
+		}
+
+		return int64(len(buf))
 	}
 }
 
-func adaptTarget(target config.Target) structs.Server { //nolint:gocritic
+func adaptTarget(target config.Target, health structs.TargetHealth) structs.Server { //nolint:gocritic
 	return structs.Server{
 		ID:          target.ID,
 		Description: target.Description,
 		Tags:        adaptTags(target.Tags),
 		Type:        target.Type,
 		Tables:      target.Tables,
+		Health:      health,
 	}
 }
 
@@ -59,3 +146,55 @@ func adaptTags(tags []string) []structs.Tag {
 		}
 	})
 }
+
+func adaptQueryVector(vec validator.Vec, schema *validator.DbSchema) structs.QueryVector { //nolint:gocritic
+	return structs.QueryVector{
+		Op:      vec.Op.S(),
+		Table:   schema.CanonicalTable(vec.Tbl),
+		Columns: vec.Cols,
+		Filter:  vec.Filter,
+		Group:   vec.Group,
+		Sort:    vec.Sort,
+	}
+}
+
+func adaptQueryApproval(item storage.QueryApproval) structs.QueryApproval { //nolint:gocritic
+	var vectors []structs.QueryVector
+	if err := json.Unmarshal(item.Vectors, &vectors); err != nil {
+		vectors = nil
+	}
+
+	return structs.QueryApproval{
+		ID:            item.ID.S(),
+		UserID:        item.UserID,
+		TargetID:      item.TargetID,
+		Query:         item.Query,
+		Vectors:       vectors,
+		Status:        item.Status,
+		ReviewerID:    item.ReviewerID,
+		ReviewComment: item.ReviewComment,
+		Error:         item.Error,
+		QueryResultID: just.If(item.QueryResultID.IsNil(), "", item.QueryResultID.S()),
+		CreatedAt:     item.CreatedAt.Format("2006-01-02 15:04:05"),
+		ReviewedAt:    formatOptionalTime(item.ReviewedAt),
+	}
+}
+
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+
+	return t.Format("2006-01-02 15:04:05")
+}
+
+func adaptBreakGlassGrant(item storage.BreakGlassGrant) structs.BreakGlassGrant { //nolint:gocritic
+	return structs.BreakGlassGrant{
+		ID:        item.ID.S(),
+		UserID:    item.UserID,
+		TargetID:  item.TargetID,
+		Reason:    item.Reason,
+		CreatedAt: item.CreatedAt,
+		ExpiresAt: item.ExpiresAt,
+	}
+}
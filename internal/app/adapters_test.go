@@ -0,0 +1,108 @@
+// Database Gateway provides access to servers with ACL for safe and restricted database interactions.
+// Copyright (C) 2024  Kirill Zhuravlev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package app //nolint:testpackage
+
+import (
+	"encoding/json"
+	"math"
+	"math/big"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdaptPgValue(t *testing.T) {
+	t.Parallel()
+
+	ts := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+
+	testCases := []struct {
+		name string
+		oid  uint32
+		val  any
+		want any
+	}{
+		{name: "null", oid: pgtype.TextOID, val: nil, want: nil},
+		{name: "text", oid: pgtype.TextOID, val: "hello", want: "hello"},
+		{name: "bool", oid: pgtype.BoolOID, val: true, want: true},
+		{name: "int4", oid: pgtype.Int4OID, val: int32(42), want: int64(42)},
+		{name: "int8", oid: pgtype.Int8OID, val: int64(math.MaxInt64), want: int64(math.MaxInt64)},
+		{name: "float8", oid: pgtype.Float8OID, val: 1.5, want: 1.5},
+		{name: "float8 nan", oid: pgtype.Float8OID, val: math.NaN(), want: "NaN"},
+		{
+			name: "numeric",
+			oid:  pgtype.NumericOID,
+			val:  pgtype.Numeric{Int: big.NewInt(12345), Exp: -2, Valid: true}, //nolint:exhaustruct
+			want: json.Number("123.45"),
+		},
+		{
+			name: "numeric nan",
+			oid:  pgtype.NumericOID,
+			val:  pgtype.Numeric{NaN: true, Valid: true}, //nolint:exhaustruct
+			want: "NaN",
+		},
+		{name: "bytea", oid: pgtype.ByteaOID, val: []byte{0xde, 0xad}, want: `\xdead`},
+		{
+			name: "uuid",
+			oid:  pgtype.UUIDOID,
+			val:  [16]byte{0x01, 0x8f, 0x1c, 0x2e, 0x00, 0x00, 0x70, 0x00, 0x80, 0x00, 0, 0, 0, 0, 0, 0x01},
+			want: "018f1c2e-0000-7000-8000-000000000001",
+		},
+		{name: "timestamptz", oid: pgtype.TimestamptzOID, val: ts, want: "2024-05-06T07:08:09Z"},
+		{name: "date", oid: pgtype.DateOID, val: ts, want: "2024-05-06"},
+		{
+			name: "interval",
+			oid:  pgtype.IntervalOID,
+			val:  pgtype.Interval{Days: 1, Microseconds: 2 * 3600 * 1e6, Valid: true}, //nolint:exhaustruct
+			want: "1 day 02:00:00",
+		},
+		{
+			name: "jsonb",
+			oid:  pgtype.JSONBOID,
+			val:  map[string]any{"a": 1.0},
+			want: map[string]any{"a": 1.0},
+		},
+		{
+			name: "int4 array with null",
+			oid:  pgtype.Int4ArrayOID,
+			val:  []any{int32(1), nil},
+			want: []any{int64(1), nil},
+		},
+		{name: "inet", oid: pgtype.InetOID, val: netip.MustParsePrefix("10.0.0.1/32"), want: "10.0.0.1/32"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, tc.want, adaptPgValue(tc.oid, tc.val))
+		})
+	}
+}
+
+func TestCellSize(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, int64(0), cellSize(nil))
+	require.Equal(t, int64(5), cellSize("hello"))
+	require.Equal(t, int64(6), cellSize(json.Number("123.45")))
+	require.Equal(t, int64(8), cellSize(int64(1)))
+	require.Equal(t, int64(8), cellSize([]any{int64(1), nil}))
+}
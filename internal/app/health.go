@@ -0,0 +1,177 @@
+// Database Gateway provides access to servers with ACL for safe and restricted database interactions.
+// Copyright (C) 2024  Kirill Zhuravlev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package app
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/kazhuravlev/database-gateway/internal/config"
+	"github.com/kazhuravlev/database-gateway/internal/structs"
+)
+
+// healthRegistry caches the last probe of every target.
+type healthRegistry struct {
+	mu    *sync.RWMutex
+	items map[config.TargetID]structs.TargetHealth
+}
+
+func newHealthRegistry() *healthRegistry {
+	return &healthRegistry{
+		mu:    new(sync.RWMutex),
+		items: make(map[config.TargetID]structs.TargetHealth),
+	}
+}
+
+func (r *healthRegistry) get(targetID config.TargetID) structs.TargetHealth {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	health, ok := r.items[targetID]
+	if !ok {
+		return unknownHealth()
+	}
+
+	return health
+}
+
+func (r *healthRegistry) set(targetID config.TargetID, health structs.TargetHealth) { //nolint:gocritic
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.items[targetID] = health
+}
+
+func unknownHealth() structs.TargetHealth {
+	return structs.TargetHealth{
+		Status:                structs.TargetStatusUnknown,
+		CheckedAt:             nil,
+		LatencyMs:             0,
+		Version:               "",
+		IsReplica:             false,
+		ReplicationLagSeconds: nil,
+		Error:                 "",
+	}
+}
+
+// runHealthChecks probes all targets right away and then every healthCheckInterval until stop is closed.
+func (s *Service) runHealthChecks(stop <-chan struct{}) {
+	ticker := time.NewTicker(s.opts.healthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		s.probeTargets()
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Service) probeTargets() {
+	var wg sync.WaitGroup
+	for _, target := range poolTargets(s.opts.targets) {
+		wg.Go(func() {
+			ctx, cancel := context.WithTimeout(context.Background(), s.opts.healthCheckTimeout)
+			defer cancel()
+
+			health := s.probeTarget(ctx, target)
+			if health.Status == structs.TargetStatusDown {
+				s.opts.logger.Warn("target is down",
+					slog.String("target", target.ID.S()),
+					slog.String("error", health.Error))
+			}
+
+			s.health.set(target.ID, health)
+		})
+	}
+	wg.Wait()
+}
+
+// probeTarget pings the target and reads its version. Replication lag is read only when the target is a replica.
+// The probe uses the pool of the target, so the first probe opens the pool and the ssh tunnel of every target right
+// after start, and queries reuse them later.
+func (s *Service) probeTarget(ctx context.Context, target config.Target) structs.TargetHealth { //nolint:gocritic
+	checkedAt := time.Now()
+	health := unknownHealth()
+	health.CheckedAt = &checkedAt
+
+	down := func(err error) structs.TargetHealth {
+		health.Status = structs.TargetStatusDown
+		health.Error = err.Error()
+
+		return health
+	}
+
+	conn, err := s.getConnection(ctx, target)
+	if err != nil {
+		return down(fmt.Errorf("get connection: %w", err))
+	}
+
+	if err := conn.Ping(ctx); err != nil {
+		return down(fmt.Errorf("ping: %w", err))
+	}
+	health.LatencyMs = time.Since(checkedAt).Milliseconds()
+
+	const statusQuery = `select current_setting('server_version'), pg_is_in_recovery()`
+	if err := conn.QueryRow(ctx, statusQuery).Scan(&health.Version, &health.IsReplica); err != nil {
+		return down(fmt.Errorf("read server status: %w", err))
+	}
+
+	// NOTE: the lag is nil until the replica replays its first transaction.
+	if health.IsReplica {
+		const lagQuery = `select extract(epoch from now() - pg_last_xact_replay_timestamp())::float8`
+		if err := conn.QueryRow(ctx, lagQuery).Scan(&health.ReplicationLagSeconds); err != nil {
+			return down(fmt.Errorf("read replication lag: %w", err))
+		}
+	}
+
+	health.Status = structs.TargetStatusUp
+
+	return health
+}
+
+func adaptPoolStats(targetID config.TargetID, pool *pgxpool.Pool) structs.TargetPoolStats {
+	if pool == nil {
+		return structs.TargetPoolStats{ //nolint:exhaustruct
+			TargetID:  targetID,
+			Connected: false,
+		}
+	}
+
+	stat := pool.Stat()
+
+	return structs.TargetPoolStats{
+		TargetID:             targetID,
+		Connected:            true,
+		MaxConns:             stat.MaxConns(),
+		TotalConns:           stat.TotalConns(),
+		IdleConns:            stat.IdleConns(),
+		AcquiredConns:        stat.AcquiredConns(),
+		ConstructingConns:    stat.ConstructingConns(),
+		AcquireCount:         stat.AcquireCount(),
+		EmptyAcquireCount:    stat.EmptyAcquireCount(),
+		CanceledAcquireCount: stat.CanceledAcquireCount(),
+		AcquireDurationMs:    stat.AcquireDuration().Milliseconds(),
+	}
+}
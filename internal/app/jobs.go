@@ -0,0 +1,264 @@
+// Database Gateway provides access to servers with ACL for safe and restricted database interactions.
+// Copyright (C) 2024  Kirill Zhuravlev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/kazhuravlev/database-gateway/internal/config"
+	"github.com/kazhuravlev/database-gateway/internal/storage"
+	"github.com/kazhuravlev/database-gateway/internal/structs"
+	"github.com/kazhuravlev/database-gateway/internal/uuid6"
+	"github.com/kazhuravlev/database-gateway/internal/validator"
+	"github.com/kazhuravlev/just"
+)
+
+// asyncJob is a submitted query. ctx is registered in the query registry, so CancelQuery stops the job both while it
+// waits in the queue and while it runs.
+type asyncJob struct {
+	req  execQueryReq
+	ctx  context.Context //nolint:containedctx
+	done func()
+}
+
+// SubmitQuery validates the query and puts it into the queue. The query runs in background, use GetQueryState to
+// follow it and GetQueryResults to read results when it has succeeded.
+func (s *Service) SubmitQuery(
+	ctx context.Context,
+	user structs.User,
+	srvID config.TargetID,
+	queryID uuid6.UUID,
+	query string,
+	route QueryRoute,
+) (uuid6.UUID, error) {
+	fullRoundTripStartedAt := time.Now()
+	if queryID.IsNil() {
+		queryID = uuid6.New()
+	}
+
+	prepared, err := s.prepareQuery(ctx, user, srvID, query)
+	if err != nil {
+		return uuid6.Nil(), err
+	}
+
+	if requiresApproval(prepared.target, prepared.vectors) {
+		queryVectors := just.SliceMap(prepared.vectors, func(vec validator.Vec) structs.QueryVector {
+			return adaptQueryVector(vec, prepared.schema)
+		})
+		approvalID, err := s.requestApproval(ctx, user, srvID, query, prepared.targetQuery, queryVectors)
+		if err != nil {
+			return uuid6.Nil(), fmt.Errorf("request approval: %w", err)
+		}
+
+		return uuid6.Nil(), &ApprovalRequiredError{ApprovalID: approvalID}
+	}
+
+	req := prepared.execReq(queryID, user, query, fullRoundTripStartedAt, route)
+
+	// NOTE: the job outlives the request, so it must not be cancelled together with the request.
+	jobCtx, done, err := s.running.start(context.WithoutCancel(ctx), queryID, user.ID)
+	if err != nil {
+		return uuid6.Nil(), fmt.Errorf("register query: %w", err)
+	}
+
+	if err := s.ensureNewQueryID(ctx, queryID); err != nil {
+		done()
+
+		return uuid6.Nil(), err
+	}
+
+	if err := s.storeQueuedQuery(ctx, req); err != nil {
+		done()
+
+		return uuid6.Nil(), err
+	}
+
+	if err := s.enqueueJob(asyncJob{req: req, ctx: jobCtx, done: done}); err != nil {
+		done()
+		s.finishJob(req, structs.QueryStatusFailed, emptyQTable(), structs.QMeta{}, err.Error()) //nolint:exhaustruct
+
+		return uuid6.Nil(), fmt.Errorf("submit query: %w", err)
+	}
+
+	return queryID, nil
+}
+
+// enqueueJob puts the job into the queue without waiting for a free slot.
+func (s *Service) enqueueJob(job asyncJob) error { //nolint:gocritic
+	s.jobsMu.RLock()
+	defer s.jobsMu.RUnlock()
+
+	if s.jobsClosed {
+		return ErrClosed
+	}
+
+	select {
+	case s.jobs <- job:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// GetQueryState returns the status of the query. Users can follow their own queries, admins can follow any query.
+func (s *Service) GetQueryState(ctx context.Context, user structs.User, queryID uuid6.UUID) (*QueryState, error) {
+	res, err := s.opts.storage.GetQueryResultsStatus(s.opts.storage.Conn(ctx), queryID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, fmt.Errorf("unknown query id: %w", ErrNotFound)
+		}
+
+		return nil, fmt.Errorf("get query status: %w", err)
+	}
+
+	if !canReadQueryResults(user, res.UserID) {
+		return nil, fmt.Errorf("user does not have access to this query: %w", ErrNotFound)
+	}
+
+	return &QueryState{
+		ID:        res.ID,
+		TargetID:  res.TargetID,
+		Status:    res.Status,
+		Error:     res.Error,
+		CreatedAt: res.CreatedAt,
+	}, nil
+}
+
+func (s *Service) storeQueuedQuery(ctx context.Context, req execQueryReq) error { //nolint:gocritic
+	buf, err := json.Marshal(storedQueryResultPayload{
+		Table: emptyQTable(),
+		Meta:  structs.QMeta{}, //nolint:exhaustruct
+	})
+	if err != nil {
+		return fmt.Errorf("marshal qtable: %w", err)
+	}
+
+	insertReq := storage.InsertQueryResultsReq{
+		ID:        req.ID,
+		UserID:    req.UserID,
+		TargetID:  req.Target.ID,
+		CreatedAt: req.StartedAt,
+		Query:     req.Query,
+		Response:  buf,
+		Status:    structs.QueryStatusQueued,
+		Error:     "",
+	}
+	if err := s.opts.storage.InsertQueryResults(s.opts.storage.Conn(ctx), insertReq); err != nil {
+		return fmt.Errorf("insert queued query: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Service) runJobs() {
+	for job := range s.jobs {
+		s.runJob(job)
+	}
+}
+
+func (s *Service) runJob(job asyncJob) { //nolint:gocritic
+	defer job.done()
+
+	ctx := context.Background()
+
+	// NOTE: the job could be cancelled while it was waiting in the queue.
+	if err := job.ctx.Err(); err != nil {
+		reason, _ := cancelReason(job.ctx, err)
+		s.finishJob(job.req, structs.QueryStatusCancelled, emptyQTable(), cancelledMeta(job.req, 0, reason), "")
+
+		return
+	}
+
+	if err := s.opts.storage.StartQueryResults(s.opts.storage.Conn(ctx), job.req.ID); err != nil {
+		s.opts.logger.Error("start queued query",
+			slog.String("query_id", job.req.ID.S()),
+			slog.String("error", err.Error()))
+		// NOTE: the query must not stay queued forever, so it is marked failed even though it has not started.
+		s.finishJob(job.req, structs.QueryStatusFailed, emptyQTable(), structs.QMeta{}, err.Error()) //nolint:exhaustruct
+
+		return
+	}
+
+	conn, replicaID, err := s.queryConnection(ctx, job.req)
+	if err != nil {
+		s.finishJob(job.req, structs.QueryStatusFailed, emptyQTable(), structs.QMeta{}, err.Error()) //nolint:exhaustruct
+
+		return
+	}
+
+	job.req.Replica = replicaID
+
+	queryStartedAt := time.Now()
+	out, err := runTargetQuery(job.ctx, conn, job.req)
+	networkRoundTripDuration := time.Since(queryStartedAt)
+	if err != nil {
+		if reason, ok := cancelReason(job.ctx, err); ok {
+			meta := cancelledMeta(job.req, networkRoundTripDuration, reason)
+			s.finishJob(job.req, structs.QueryStatusCancelled, emptyQTable(), meta, "")
+
+			return
+		}
+
+		s.finishJob(job.req, structs.QueryStatusFailed, emptyQTable(), structs.QMeta{}, err.Error()) //nolint:exhaustruct
+
+		return
+	}
+
+	s.describeColumns(ctx, conn, out)
+
+	s.finishJob(job.req, structs.QueryStatusSucceeded, *out.table, out.meta(job.req, networkRoundTripDuration), "")
+}
+
+// finishJob stores the outcome of the job. Errors are only logged, because nobody waits for the job.
+func (s *Service) finishJob( //nolint:gocritic
+	req execQueryReq,
+	status structs.QueryStatus,
+	qTable structs.QTable,
+	meta structs.QMeta,
+	errMsg string,
+) {
+	buf, err := json.Marshal(storedQueryResultPayload{
+		Table: qTable,
+		Meta:  meta,
+	})
+	if err != nil {
+		s.opts.logger.Error("marshal job results",
+			slog.String("query_id", req.ID.S()),
+			slog.String("error", err.Error()))
+
+		return
+	}
+
+	ctx := context.Background()
+	finishReq := storage.FinishQueryResultsReq{
+		ID:       req.ID,
+		Status:   status,
+		Response: buf,
+		Error:    errMsg,
+	}
+	if err := s.opts.storage.FinishQueryResults(s.opts.storage.Conn(ctx), finishReq); err != nil {
+		s.opts.logger.Error("finish job",
+			slog.String("query_id", req.ID.S()),
+			slog.String("status", status.S()),
+			slog.String("error", err.Error()))
+	}
+}
@@ -0,0 +1,174 @@
+// Database Gateway provides access to servers with ACL for safe and restricted database interactions.
+// Copyright (C) 2024  Kirill Zhuravlev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package app //nolint:testpackage
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kazhuravlev/database-gateway/internal/config"
+	"github.com/kazhuravlev/database-gateway/internal/storage"
+	"github.com/kazhuravlev/database-gateway/internal/structs"
+	"github.com/kazhuravlev/database-gateway/internal/uuid6"
+	"github.com/stretchr/testify/require"
+)
+
+var errStorageDown = errors.New("storage is down")
+
+// execStub is a database/sql driver that records statements. The first failExecs statements fail. Queries return
+// one row with storedID when it is set and no rows otherwise.
+type execStub struct {
+	mu         sync.Mutex
+	failExecs  int
+	statements [][]driver.NamedValue
+	storedID   string
+}
+
+func (d *execStub) Connect(context.Context) (driver.Conn, error) { return d, nil }
+func (d *execStub) Driver() driver.Driver                        { return nil }
+func (d *execStub) Prepare(string) (driver.Stmt, error)          { return nil, driver.ErrSkip }
+func (d *execStub) Close() error                                 { return nil }
+func (d *execStub) Begin() (driver.Tx, error)                    { return nil, driver.ErrSkip }
+
+func (d *execStub) ExecContext(_ context.Context, _ string, args []driver.NamedValue) (driver.Result, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.failExecs > 0 {
+		d.failExecs--
+
+		return nil, errStorageDown
+	}
+
+	d.statements = append(d.statements, args)
+
+	return driver.RowsAffected(1), nil
+}
+
+func (d *execStub) QueryContext(context.Context, string, []driver.NamedValue) (driver.Rows, error) {
+	return &stubDriverRows{id: d.storedID}, nil
+}
+
+type stubDriverRows struct {
+	id string
+}
+
+func (r *stubDriverRows) Columns() []string { return []string{"query_results.id"} }
+func (r *stubDriverRows) Close() error      { return nil }
+
+func (r *stubDriverRows) Next(dest []driver.Value) error {
+	if r.id == "" {
+		return io.EOF
+	}
+
+	dest[0], r.id = r.id, ""
+
+	return nil
+}
+
+func newStubStorage(t *testing.T, stub *execStub) *storage.Service {
+	t.Helper()
+
+	db := sql.OpenDB(stub)
+	t.Cleanup(func() { _ = db.Close() })
+
+	store, err := storage.New(storage.NewOptions(slog.Default(), db))
+	require.NoError(t, err)
+
+	return store
+}
+
+func TestEnsureNewQueryID(t *testing.T) {
+	t.Parallel()
+
+	storedID := uuid6.New()
+	previewedID := uuid6.New()
+
+	svc := &Service{ //nolint:exhaustruct
+		opts: Options{ //nolint:exhaustruct
+			logger:  slog.Default(),
+			storage: newStubStorage(t, &execStub{storedID: storedID.ToUUID().String()}), //nolint:exhaustruct
+		},
+		previews: newPreviewRegistry(),
+	}
+	require.NoError(t, svc.previews.reserve(previewedID, "alice@example.com"))
+
+	t.Run("stored_id", func(t *testing.T) {
+		t.Parallel()
+
+		require.ErrorIs(t, svc.ensureNewQueryID(context.Background(), storedID), ErrConflict)
+	})
+
+	t.Run("previewed_id", func(t *testing.T) {
+		t.Parallel()
+
+		require.ErrorIs(t, svc.ensureNewQueryID(context.Background(), previewedID), ErrConflict)
+	})
+
+	t.Run("new_id", func(t *testing.T) {
+		t.Parallel()
+
+		svc := &Service{ //nolint:exhaustruct
+			opts: Options{ //nolint:exhaustruct
+				logger:  slog.Default(),
+				storage: newStubStorage(t, &execStub{}), //nolint:exhaustruct
+			},
+			previews: newPreviewRegistry(),
+		}
+
+		require.NoError(t, svc.ensureNewQueryID(context.Background(), uuid6.New()))
+	})
+}
+
+func TestRunJobFailsWhenStartFails(t *testing.T) {
+	t.Parallel()
+
+	stub := &execStub{failExecs: 1} //nolint:exhaustruct
+	store := newStubStorage(t, stub)
+
+	svc := &Service{ //nolint:exhaustruct
+		opts: Options{ //nolint:exhaustruct
+			logger:  slog.Default(),
+			storage: store,
+		},
+	}
+
+	var doneCalled bool
+	job := asyncJob{
+		req: execQueryReq{ //nolint:exhaustruct
+			ID:        uuid6.New(),
+			Target:    config.Target{ID: "pg-1"}, //nolint:exhaustruct
+			UserID:    "alice@example.com",
+			StartedAt: time.Now(),
+		},
+		ctx:  context.Background(),
+		done: func() { doneCalled = true },
+	}
+
+	svc.runJob(job)
+
+	require.True(t, doneCalled)
+	require.Len(t, stub.statements, 1, "job should be finished after start failed")
+	require.Contains(t, stub.statements[0], driver.NamedValue{Ordinal: 1, Value: structs.QueryStatusFailed.S()})
+}
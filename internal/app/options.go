@@ -18,6 +18,7 @@ package app
 
 import (
 	"log/slog"
+	"time"
 
 	"github.com/kazhuravlev/database-gateway/internal/config"
 	"github.com/kazhuravlev/database-gateway/internal/policy"
@@ -31,4 +32,15 @@ type Options struct {
 	users      config.UsersProviderOIDC `option:"mandatory" validate:"required"`
 	authorizer policy.Authorizer        `option:"mandatory" validate:"required"`
 	storage    *storage.Service         `option:"mandatory" validate:"required"`
+
+	breakGlassMaxTTL time.Duration `default:"4h" validate:"min=1m"`
+	previewTTL       time.Duration `default:"1m" validate:"min=1s"`
+	asyncWorkers     int           `default:"4" validate:"min=1"`
+	asyncQueueSize   int           `default:"64" validate:"min=1"`
+
+	healthCheckInterval time.Duration `default:"30s" validate:"min=1s"`
+	healthCheckTimeout  time.Duration `default:"5s" validate:"min=100ms"`
+
+	// secrets resolves references in passwords of targets. Passwords are used literally when it is nil.
+	secrets *config.Secrets
 }
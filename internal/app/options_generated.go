@@ -5,6 +5,7 @@ package app
 import (
 	fmt461e464ebed9 "fmt"
 	"log/slog"
+	"time"
 
 	"github.com/kazhuravlev/database-gateway/internal/config"
 	"github.com/kazhuravlev/database-gateway/internal/policy"
@@ -27,6 +28,13 @@ func NewOptions(
 
 	// Setting defaults from field tag (if present)
 
+	o.breakGlassMaxTTL, _ = time.ParseDuration("4h")
+	o.previewTTL, _ = time.ParseDuration("1m")
+	o.asyncWorkers = 4
+	o.asyncQueueSize = 64
+	o.healthCheckInterval, _ = time.ParseDuration("30s")
+	o.healthCheckTimeout, _ = time.ParseDuration("5s")
+
 	o.logger = logger
 	o.targets = targets
 	o.users = users
@@ -39,6 +47,35 @@ func NewOptions(
 	return o
 }
 
+func WithBreakGlassMaxTTL(opt time.Duration) OptOptionsSetter {
+	return func(o *Options) { o.breakGlassMaxTTL = opt }
+}
+
+func WithPreviewTTL(opt time.Duration) OptOptionsSetter {
+	return func(o *Options) { o.previewTTL = opt }
+}
+
+func WithAsyncWorkers(opt int) OptOptionsSetter {
+	return func(o *Options) { o.asyncWorkers = opt }
+}
+
+func WithAsyncQueueSize(opt int) OptOptionsSetter {
+	return func(o *Options) { o.asyncQueueSize = opt }
+}
+
+func WithHealthCheckInterval(opt time.Duration) OptOptionsSetter {
+	return func(o *Options) { o.healthCheckInterval = opt }
+}
+
+func WithHealthCheckTimeout(opt time.Duration) OptOptionsSetter {
+	return func(o *Options) { o.healthCheckTimeout = opt }
+}
+
+// secrets resolves references in passwords of targets. Passwords are used literally when it is nil.
+func WithSecrets(opt *config.Secrets) OptOptionsSetter {
+	return func(o *Options) { o.secrets = opt }
+}
+
 func (o *Options) Validate() error {
 	errs := new(errors461e464ebed9.ValidationErrors)
 	errs.Add(errors461e464ebed9.NewValidationError("logger", _validate_Options_logger(o)))
@@ -46,6 +83,12 @@ func (o *Options) Validate() error {
 	errs.Add(errors461e464ebed9.NewValidationError("users", _validate_Options_users(o)))
 	errs.Add(errors461e464ebed9.NewValidationError("authorizer", _validate_Options_authorizer(o)))
 	errs.Add(errors461e464ebed9.NewValidationError("storage", _validate_Options_storage(o)))
+	errs.Add(errors461e464ebed9.NewValidationError("breakGlassMaxTTL", _validate_Options_breakGlassMaxTTL(o)))
+	errs.Add(errors461e464ebed9.NewValidationError("previewTTL", _validate_Options_previewTTL(o)))
+	errs.Add(errors461e464ebed9.NewValidationError("asyncWorkers", _validate_Options_asyncWorkers(o)))
+	errs.Add(errors461e464ebed9.NewValidationError("asyncQueueSize", _validate_Options_asyncQueueSize(o)))
+	errs.Add(errors461e464ebed9.NewValidationError("healthCheckInterval", _validate_Options_healthCheckInterval(o)))
+	errs.Add(errors461e464ebed9.NewValidationError("healthCheckTimeout", _validate_Options_healthCheckTimeout(o)))
 	return errs.AsError()
 }
 
@@ -83,3 +126,45 @@ func _validate_Options_storage(o *Options) error {
 	}
 	return nil
 }
+
+func _validate_Options_breakGlassMaxTTL(o *Options) error {
+	if err := validator461e464ebed9.GetValidatorFor(o).Var(o.breakGlassMaxTTL, "min=1m"); err != nil {
+		return fmt461e464ebed9.Errorf("field `breakGlassMaxTTL` did not pass the test: %w", err)
+	}
+	return nil
+}
+
+func _validate_Options_previewTTL(o *Options) error {
+	if err := validator461e464ebed9.GetValidatorFor(o).Var(o.previewTTL, "min=1s"); err != nil {
+		return fmt461e464ebed9.Errorf("field `previewTTL` did not pass the test: %w", err)
+	}
+	return nil
+}
+
+func _validate_Options_asyncWorkers(o *Options) error {
+	if err := validator461e464ebed9.GetValidatorFor(o).Var(o.asyncWorkers, "min=1"); err != nil {
+		return fmt461e464ebed9.Errorf("field `asyncWorkers` did not pass the test: %w", err)
+	}
+	return nil
+}
+
+func _validate_Options_asyncQueueSize(o *Options) error {
+	if err := validator461e464ebed9.GetValidatorFor(o).Var(o.asyncQueueSize, "min=1"); err != nil {
+		return fmt461e464ebed9.Errorf("field `asyncQueueSize` did not pass the test: %w", err)
+	}
+	return nil
+}
+
+func _validate_Options_healthCheckInterval(o *Options) error {
+	if err := validator461e464ebed9.GetValidatorFor(o).Var(o.healthCheckInterval, "min=1s"); err != nil {
+		return fmt461e464ebed9.Errorf("field `healthCheckInterval` did not pass the test: %w", err)
+	}
+	return nil
+}
+
+func _validate_Options_healthCheckTimeout(o *Options) error {
+	if err := validator461e464ebed9.GetValidatorFor(o).Var(o.healthCheckTimeout, "min=100ms"); err != nil {
+		return fmt461e464ebed9.Errorf("field `healthCheckTimeout` did not pass the test: %w", err)
+	}
+	return nil
+}
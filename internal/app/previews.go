@@ -0,0 +1,293 @@
+// Database Gateway provides access to servers with ACL for safe and restricted database interactions.
+// Copyright (C) 2024  Kirill Zhuravlev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/kazhuravlev/database-gateway/internal/config"
+	"github.com/kazhuravlev/database-gateway/internal/structs"
+	"github.com/kazhuravlev/database-gateway/internal/uuid6"
+	"github.com/kazhuravlev/database-gateway/internal/validator"
+)
+
+const (
+	previewOutcomeCommitted  = "committed"
+	previewOutcomeRolledBack = "rolled_back"
+	previewOutcomeExpired    = "expired"
+
+	previewExpireTimeout = 10 * time.Second
+)
+
+// pendingPreview is a previewed write. tx is nil while the query is still running.
+type pendingPreview struct {
+	ownerID   config.UserID
+	tx        pgx.Tx
+	timer     *time.Timer
+	req       execQueryReq
+	startedAt time.Time
+	table     structs.QTable
+	meta      structs.QMeta
+}
+
+// previewRegistry keeps open transactions of previewed writes. Every preview pins a connection of the target, so a
+// user can hold only one preview at a time.
+type previewRegistry struct {
+	mu       *sync.Mutex
+	previews map[uuid6.UUID]*pendingPreview
+}
+
+func newPreviewRegistry() *previewRegistry {
+	return &previewRegistry{
+		mu:       new(sync.Mutex),
+		previews: make(map[uuid6.UUID]*pendingPreview),
+	}
+}
+
+// reserve takes the slot of the user before the query runs. Call release when the query fails.
+func (r *previewRegistry) reserve(queryID uuid6.UUID, ownerID config.UserID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.previews[queryID]; ok {
+		return fmt.Errorf("query %s is already previewed: %w", queryID, ErrConflict)
+	}
+
+	for _, preview := range r.previews {
+		if preview.ownerID == ownerID {
+			return fmt.Errorf("commit or rollback the previous preview first: %w", ErrConflict)
+		}
+	}
+
+	r.previews[queryID] = &pendingPreview{ownerID: ownerID} //nolint:exhaustruct
+
+	return nil
+}
+
+// has reports whether the query is previewed or still running as a preview.
+func (r *previewRegistry) has(queryID uuid6.UUID) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, ok := r.previews[queryID]
+
+	return ok
+}
+
+func (r *previewRegistry) release(queryID uuid6.UUID) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.previews, queryID)
+}
+
+// activate makes the reserved preview available for take. onExpire is called after ttl unless the preview was
+// taken before.
+func (r *previewRegistry) activate(queryID uuid6.UUID, preview *pendingPreview, ttl time.Duration, onExpire func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	preview.timer = time.AfterFunc(ttl, onExpire)
+	r.previews[queryID] = preview
+}
+
+// take removes the preview of the user from the registry. Only the owner can finish the preview.
+func (r *previewRegistry) take(queryID uuid6.UUID, userID config.UserID) (*pendingPreview, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	preview, ok := r.previews[queryID]
+	if !ok || preview.tx == nil || preview.ownerID != userID {
+		return nil, fmt.Errorf("preview is not found: %w", ErrNotFound)
+	}
+
+	preview.timer.Stop()
+	delete(r.previews, queryID)
+
+	return preview, nil
+}
+
+// expire removes the preview regardless of the owner. It reports false when the preview was already taken.
+func (r *previewRegistry) expire(queryID uuid6.UUID) (*pendingPreview, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	preview, ok := r.previews[queryID]
+	if !ok || preview.tx == nil {
+		return nil, false
+	}
+
+	delete(r.previews, queryID)
+
+	return preview, true
+}
+
+// expireAll removes every activated preview. Reserved previews are left to their queries.
+func (r *previewRegistry) expireAll() []*pendingPreview {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var res []*pendingPreview
+	for queryID, preview := range r.previews {
+		if preview.tx == nil {
+			continue
+		}
+
+		preview.timer.Stop()
+		delete(r.previews, queryID)
+		res = append(res, preview)
+	}
+
+	return res
+}
+
+// isPreviewable reports whether the query updates or deletes rows. Selects are allowed as parts of such query,
+// inserts are not.
+func isPreviewable(vectors []validator.Vec) bool {
+	hasInsert := slices.ContainsFunc(vectors, func(vec validator.Vec) bool {
+		return vec.Op == config.OpInsert
+	})
+	hasChange := slices.ContainsFunc(vectors, func(vec validator.Vec) bool {
+		return vec.Op == config.OpUpdate || vec.Op == config.OpDelete
+	})
+
+	return hasChange && !hasInsert
+}
+
+// previewQuery runs the write inside a transaction and keeps the transaction open until the user commits or rolls
+// it back. Nothing is stored in history until then.
+func (s *Service) previewQuery(ctx context.Context, req execQueryReq) (*QueryPreview, error) { //nolint:gocritic
+	conn, err := s.getConnection(ctx, req.Target)
+	if err != nil {
+		return nil, fmt.Errorf("get connection by id: %w", err)
+	}
+
+	if err := s.previews.reserve(req.ID, req.UserID); err != nil {
+		return nil, fmt.Errorf("reserve preview: %w", err)
+	}
+
+	queryCtx, done, err := s.running.start(ctx, req.ID, req.UserID)
+	if err != nil {
+		s.previews.release(req.ID)
+
+		return nil, fmt.Errorf("register query: %w", err)
+	}
+	defer done()
+
+	if err := s.ensureQueryNotStored(ctx, req.ID); err != nil {
+		s.previews.release(req.ID)
+
+		return nil, err
+	}
+
+	queryStartedAt := time.Now()
+	tx, err := conn.BeginTx(queryCtx, pgx.TxOptions{}) //nolint:exhaustruct
+	if err != nil {
+		s.previews.release(req.ID)
+
+		return nil, fmt.Errorf("begin preview transaction: %w", err)
+	}
+
+	err = setLocalRole(queryCtx, tx, req.DBRole)
+	var out *queryOutput
+	if err == nil {
+		out, err = collectQTable(queryCtx, tx, req.TargetQuery, req.Limits)
+	}
+	networkRoundTripDuration := time.Since(queryStartedAt)
+	if err != nil {
+		_ = tx.Rollback(context.WithoutCancel(ctx))
+		s.previews.release(req.ID)
+
+		return nil, s.handleQueryError(ctx, queryCtx, req, queryStartedAt, networkRoundTripDuration, err)
+	}
+
+	s.describeColumns(ctx, conn, out)
+
+	meta := out.meta(req, networkRoundTripDuration)
+	meta.AffectedRows = out.rowsAffected
+
+	expiresAt := time.Now().Add(s.opts.previewTTL)
+	preview := &pendingPreview{
+		ownerID:   req.UserID,
+		tx:        tx,
+		timer:     nil,
+		req:       req,
+		startedAt: queryStartedAt,
+		table:     *out.table,
+		meta:      meta,
+	}
+	s.previews.activate(req.ID, preview, s.opts.previewTTL, func() {
+		s.expirePreview(req.ID)
+	})
+
+	return &QueryPreview{
+		ID:           req.ID,
+		Table:        *out.table,
+		AffectedRows: out.rowsAffected,
+		ExpiresAt:    expiresAt,
+	}, nil
+}
+
+// finishPreview commits or rolls back the transaction and stores the outcome in history. Failed commit is stored as
+// rolled back.
+func (s *Service) finishPreview(ctx context.Context, preview *pendingPreview, outcome string) error {
+	// NOTE: the transaction must be finished even when the client has gone away, otherwise the connection leaks.
+	ctx = context.WithoutCancel(ctx)
+
+	var finishErr error
+	if outcome == previewOutcomeCommitted {
+		if err := preview.tx.Commit(ctx); err != nil {
+			outcome = previewOutcomeRolledBack
+			finishErr = fmt.Errorf("commit preview: %w", err)
+		}
+	} else if err := preview.tx.Rollback(ctx); err != nil {
+		finishErr = fmt.Errorf("rollback preview: %w", err)
+	}
+
+	meta := preview.meta
+	meta.PreviewOutcome = outcome
+	storeErr := s.storeQueryResults(ctx, preview.req, preview.startedAt, preview.table, meta)
+
+	return errors.Join(finishErr, storeErr)
+}
+
+func (s *Service) expirePreview(queryID uuid6.UUID) {
+	preview, ok := s.previews.expire(queryID)
+	if !ok {
+		return
+	}
+
+	s.finishExpiredPreview(preview)
+}
+
+func (s *Service) finishExpiredPreview(preview *pendingPreview) {
+	ctx, cancel := context.WithTimeout(context.Background(), previewExpireTimeout)
+	defer cancel()
+
+	if err := s.finishPreview(ctx, preview, previewOutcomeExpired); err != nil {
+		s.opts.logger.Error("expire preview",
+			slog.String("query_id", preview.req.ID.S()),
+			slog.String("error", err.Error()))
+	}
+}
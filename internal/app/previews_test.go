@@ -0,0 +1,158 @@
+// Database Gateway provides access to servers with ACL for safe and restricted database interactions.
+// Copyright (C) 2024  Kirill Zhuravlev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package app //nolint:testpackage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/kazhuravlev/database-gateway/internal/config"
+	"github.com/kazhuravlev/database-gateway/internal/uuid6"
+	"github.com/kazhuravlev/database-gateway/internal/validator"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeTx marks a preview as active. Registry never calls its methods.
+type fakeTx struct {
+	pgx.Tx
+}
+
+func TestPreviewRegistry(t *testing.T) {
+	t.Parallel()
+
+	const (
+		owner config.UserID = "alice@example.com"
+		other config.UserID = "bob@example.com"
+	)
+
+	activate := func(registry *previewRegistry, queryID uuid6.UUID, ttl time.Duration, onExpire func()) {
+		preview := &pendingPreview{ownerID: owner, tx: fakeTx{}} //nolint:exhaustruct
+		registry.activate(queryID, preview, ttl, onExpire)
+	}
+
+	t.Run("owner takes active preview", func(t *testing.T) {
+		t.Parallel()
+
+		registry := newPreviewRegistry()
+		queryID := uuid6.New()
+
+		require.NoError(t, registry.reserve(queryID, owner))
+		activate(registry, queryID, time.Hour, func() {})
+
+		preview, err := registry.take(queryID, owner)
+		require.NoError(t, err)
+		require.Equal(t, owner, preview.ownerID)
+
+		_, err = registry.take(queryID, owner)
+		require.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("other user cannot take", func(t *testing.T) {
+		t.Parallel()
+
+		registry := newPreviewRegistry()
+		queryID := uuid6.New()
+
+		require.NoError(t, registry.reserve(queryID, owner))
+		activate(registry, queryID, time.Hour, func() {})
+
+		_, err := registry.take(queryID, other)
+		require.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("running preview cannot be taken", func(t *testing.T) {
+		t.Parallel()
+
+		registry := newPreviewRegistry()
+		queryID := uuid6.New()
+
+		require.NoError(t, registry.reserve(queryID, owner))
+
+		_, err := registry.take(queryID, owner)
+		require.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("one preview per user", func(t *testing.T) {
+		t.Parallel()
+
+		registry := newPreviewRegistry()
+
+		require.NoError(t, registry.reserve(uuid6.New(), owner))
+		require.ErrorIs(t, registry.reserve(uuid6.New(), owner), ErrConflict)
+		require.NoError(t, registry.reserve(uuid6.New(), other))
+	})
+
+	t.Run("released slot can be reserved again", func(t *testing.T) {
+		t.Parallel()
+
+		registry := newPreviewRegistry()
+		queryID := uuid6.New()
+
+		require.NoError(t, registry.reserve(queryID, owner))
+		registry.release(queryID)
+		require.NoError(t, registry.reserve(uuid6.New(), owner))
+	})
+
+	t.Run("expired preview cannot be taken", func(t *testing.T) {
+		t.Parallel()
+
+		registry := newPreviewRegistry()
+		queryID := uuid6.New()
+		expired := make(chan bool, 1)
+
+		require.NoError(t, registry.reserve(queryID, owner))
+		activate(registry, queryID, time.Millisecond, func() {
+			_, ok := registry.expire(queryID)
+			expired <- ok
+		})
+
+		require.True(t, <-expired)
+		_, err := registry.take(queryID, owner)
+		require.ErrorIs(t, err, ErrNotFound)
+	})
+}
+
+func TestIsPreviewable(t *testing.T) {
+	t.Parallel()
+
+	selectVec := validator.Vec{Op: config.OpSelect, Tbl: "orders", Cols: []string{"client_id"}}
+	updateVec := validator.Vec{Op: config.OpUpdate, Tbl: "clients", Cols: []string{"name"}}
+	deleteVec := validator.Vec{Op: config.OpDelete, Tbl: "clients", Cols: []string{"id"}}
+	insertVec := validator.Vec{Op: config.OpInsert, Tbl: "clients", Cols: []string{"name"}}
+
+	testCases := []struct {
+		name    string
+		vectors []validator.Vec
+		want    bool
+	}{
+		{name: "update", vectors: []validator.Vec{updateVec}, want: true},
+		{name: "delete", vectors: []validator.Vec{deleteVec}, want: true},
+		{name: "update with subquery", vectors: []validator.Vec{updateVec, selectVec}, want: true},
+		{name: "select", vectors: []validator.Vec{selectVec}, want: false},
+		{name: "insert", vectors: []validator.Vec{insertVec}, want: false},
+		{name: "delete with insert", vectors: []validator.Vec{deleteVec, insertVec}, want: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, tc.want, isPreviewable(tc.vectors))
+		})
+	}
+}
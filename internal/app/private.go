@@ -18,14 +18,28 @@ package app
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/go-jet/jet/v2/qrm"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/kazhuravlev/database-gateway/internal/config"
+	"github.com/kazhuravlev/database-gateway/internal/pgdb"
+	"github.com/kazhuravlev/database-gateway/internal/policy"
+	"github.com/kazhuravlev/database-gateway/internal/storage"
 	"github.com/kazhuravlev/database-gateway/internal/structs"
+	"github.com/kazhuravlev/database-gateway/internal/uuid6"
 	"github.com/kazhuravlev/database-gateway/internal/validator"
 	"github.com/kazhuravlev/just"
+	"github.com/labstack/gommon/log"
 )
 
 func (s *Service) getConnection(ctx context.Context, target config.Target) (*pgxpool.Pool, error) { //nolint:gocritic
@@ -40,33 +54,142 @@ func (s *Service) getConnection(ctx context.Context, target config.Target) (*pgx
 	}
 
 	s.opts.logger.Info("connect to target", slog.String("target", string(target.ID)))
+
+	poolCfg, err := buildPoolConfig(target, s.opts.secrets)
+	if err != nil {
+		return nil, err
+	}
+
+	var tunnel *sshTunnel
+	if tunnelCfg := target.Connection.SSHTunnel; tunnelCfg != nil {
+		tunnel, err = newSSHTunnel(*tunnelCfg)
+		if err != nil {
+			return nil, fmt.Errorf("create ssh tunnel: %w", err)
+		}
+
+		tunnel.apply(poolCfg)
+	}
+
+	dbpool, err := pgxpool.NewWithConfig(ctx, poolCfg)
+	if err != nil {
+		_ = tunnel.Close()
+
+		return nil, fmt.Errorf("create db pool: %w", err)
+	}
+
+	s.connsMu.Lock()
+	defer s.connsMu.Unlock()
+
+	if s.connsClosed {
+		dbpool.Close()
+		_ = tunnel.Close()
+
+		return nil, fmt.Errorf("service is closed: %w", ErrClosed)
+	}
+
+	// NOTE: concurrent requests may connect to the same target. Only the first pool is kept.
+	if pool, ok := s.conns[target.ID]; ok {
+		dbpool.Close()
+		_ = tunnel.Close()
+
+		return pool, nil
+	}
+
+	s.conns[target.ID] = dbpool
+	if tunnel != nil {
+		s.tunnels[target.ID] = tunnel
+	}
+
+	return dbpool, nil
+}
+
+// defaultApplicationName marks sessions of the gateway in pg_stat_activity of targets.
+const defaultApplicationName = "database-gateway"
+
+// buildPoolConfig converts the connection settings of the target into pool config. Zero values keep pgxpool defaults.
+func buildPoolConfig(target config.Target, secrets *config.Secrets) (*pgxpool.Config, error) { //nolint:gocritic
 	pgCfg := target.Connection
 
 	urlExample := fmt.Sprintf(
-		"postgres://%s:%s@%s:%d/%s?sslmode=%s",
+		"postgres://%s@%s:%d/%s?sslmode=%s",
 		pgCfg.User,
-		pgCfg.Password,
 		pgCfg.Host,
 		pgCfg.Port,
 		pgCfg.DB,
-		just.If(pgCfg.UseSSL, "enable", "disable"),
+		config.TLSModeDisable,
 	)
-	dbpool, err := pgxpool.New(ctx, urlExample)
+	poolCfg, err := pgxpool.ParseConfig(urlExample)
 	if err != nil {
-		return nil, fmt.Errorf("create db pool: %w", err)
+		return nil, fmt.Errorf("parse db pool config: %w", err)
 	}
-	// TODO: we cann disconnect on shutdown. But this is not so important.
-	// dbpool.Close()
 
-	s.connsMu.Lock()
-	s.conns[target.ID] = dbpool
-	s.connsMu.Unlock()
+	// NOTE: password is not a part of the url, so it may contain any characters.
+	if secrets != nil && secrets.IsReference(pgCfg.Password) {
+		// NOTE: the reference is resolved for every new connection, so a rotated password is picked up on reconnect.
+		poolCfg.BeforeConnect = func(ctx context.Context, connCfg *pgx.ConnConfig) error {
+			password, err := secrets.Resolve(ctx, pgCfg.Password)
+			if err != nil {
+				return fmt.Errorf("resolve password: %w", err)
+			}
 
-	return dbpool, nil
+			connCfg.Password = password
+
+			return nil
+		}
+	} else {
+		poolCfg.ConnConfig.Password = pgCfg.Password
+	}
+
+	// NOTE: pgx would fall back to plain text for some modes, so tls is configured by hand and always enforced.
+	tlsCfg, err := pgdb.BuildTLSConfig(pgCfg.TLS, pgCfg.Host)
+	if err != nil {
+		return nil, fmt.Errorf("build tls config: %w", err)
+	}
+	poolCfg.ConnConfig.TLSConfig = tlsCfg
+
+	if pgCfg.MaxPoolSize > 0 {
+		poolCfg.MaxConns = int32(pgCfg.MaxPoolSize) //nolint:gosec
+	}
+
+	if pgCfg.MinPoolSize > 0 {
+		poolCfg.MinConns = int32(pgCfg.MinPoolSize) //nolint:gosec
+	}
+
+	if lifetime := pgCfg.MaxConnLifetime.D(); lifetime > 0 {
+		poolCfg.MaxConnLifetime = lifetime
+	}
+
+	if idleTime := pgCfg.MaxConnIdleTime.D(); idleTime > 0 {
+		poolCfg.MaxConnIdleTime = idleTime
+	}
+
+	if period := pgCfg.HealthCheckPeriod.D(); period > 0 {
+		poolCfg.HealthCheckPeriod = period
+	}
+
+	poolCfg.ConnConfig.RuntimeParams["application_name"] = just.If(
+		pgCfg.ApplicationName != "",
+		pgCfg.ApplicationName,
+		defaultApplicationName,
+	)
+
+	if timeout := target.StatementTimeout.D(); timeout > 0 {
+		poolCfg.ConnConfig.RuntimeParams["statement_timeout"] = strconv.FormatInt(timeout.Milliseconds(), 10)
+	}
+
+	return poolCfg, nil
 }
 
-func (s *Service) getTargetByID(_ context.Context, user structs.User, tID config.TargetID) (*config.Target, *validator.DbSchema, error) {
-	subjects := userSubjects(user)
+func (s *Service) getTargetByID(ctx context.Context, user structs.User, tID config.TargetID) (*config.Target, *validator.DbSchema, error) {
+	subjects, _, err := s.getSubjects(ctx, user)
+	if err != nil {
+		return nil, nil, fmt.Errorf("get subjects: %w", err)
+	}
+
+	return s.getTargetForSubjects(subjects, tID)
+}
+
+func (s *Service) getTargetForSubjects(subjects []string, tID config.TargetID) (*config.Target, *validator.DbSchema, error) {
 	for i := range s.opts.targets {
 		target := s.opts.targets[i]
 		if target.ID == tID {
@@ -80,3 +203,835 @@ func (s *Service) getTargetByID(_ context.Context, user structs.User, tID config
 
 	return nil, nil, fmt.Errorf("target not found: %w", ErrNotFound)
 }
+
+// getSubjects returns policy subjects of the user together with active break-glass grants they came from.
+func (s *Service) getSubjects(ctx context.Context, user structs.User) ([]string, []storage.BreakGlassGrant, error) {
+	grants, err := s.activeGrants(ctx, user.ID, time.Now())
+	if err != nil {
+		return nil, nil, fmt.Errorf("list active grants: %w", err)
+	}
+
+	return grantSubjects(user, grants), grants, nil
+}
+
+type execQueryReq struct {
+	ID     uuid6.UUID
+	Target config.Target
+	// UserID is the owner of stored results. For approved queries this is the author, not the approver.
+	UserID          config.UserID
+	Query           string
+	TargetQuery     string
+	StartedAt       time.Time
+	ParsingDuration time.Duration
+	VectorsCount    int
+	Limits          config.ResultLimits
+	ReadOnly        bool
+	// SelectOnly is true when every vector of the query is a select. Such queries are read through a cursor when
+	// limits are set.
+	SelectOnly bool
+	// DBRole is the database role the query runs as. Empty when the target does not impersonate users.
+	DBRole string
+	// UseReplica lets the query run on a replica of the target. Replica is the id of the chosen one, empty for the
+	// primary.
+	UseReplica bool
+	Replica    string
+}
+
+// execQuery runs an already validated query on the target and stores the results in history. Cancelled queries are
+// stored too, with an empty table and the reason in meta.
+func (s *Service) execQuery(ctx context.Context, req execQueryReq) (uuid6.UUID, *structs.QTable, error) { //nolint:gocritic
+	conn, replicaID, err := s.queryConnection(ctx, req)
+	if err != nil {
+		return uuid6.Nil(), nil, err
+	}
+	req.Replica = replicaID
+
+	queryCtx, done, err := s.running.start(ctx, req.ID, req.UserID)
+	if err != nil {
+		return uuid6.Nil(), nil, fmt.Errorf("register query: %w", err)
+	}
+	defer done()
+
+	if err := s.ensureNewQueryID(ctx, req.ID); err != nil {
+		return uuid6.Nil(), nil, err
+	}
+
+	queryStartedAt := time.Now()
+	out, err := runTargetQuery(queryCtx, conn, req)
+	networkRoundTripDuration := time.Since(queryStartedAt)
+	if err != nil {
+		return uuid6.Nil(), nil, s.handleQueryError(ctx, queryCtx, req, queryStartedAt, networkRoundTripDuration, err)
+	}
+
+	s.describeColumns(ctx, conn, out)
+
+	meta := out.meta(req, networkRoundTripDuration)
+	if err := s.storeQueryResults(ctx, req, queryStartedAt, *out.table, meta); err != nil {
+		return uuid6.Nil(), nil, err
+	}
+
+	return req.ID, out.table, nil
+}
+
+// ensureNewQueryID refuses ids of queries that are already previewed or stored in history. Query ids can be sent by
+// clients, and a query with a taken id would run on the target and then fail to be stored. Register the query as
+// running before the check, so a concurrent query with the same id can not be stored in between.
+func (s *Service) ensureNewQueryID(ctx context.Context, queryID uuid6.UUID) error {
+	if s.previews.has(queryID) {
+		return fmt.Errorf("query %s is previewed: %w", queryID, ErrConflict)
+	}
+
+	return s.ensureQueryNotStored(ctx, queryID)
+}
+
+func (s *Service) ensureQueryNotStored(ctx context.Context, queryID uuid6.UUID) error {
+	_, err := s.opts.storage.GetQueryResultsStatus(s.opts.storage.Conn(ctx), queryID)
+	switch {
+	case err == nil:
+		return fmt.Errorf("query %s already exists: %w", queryID, ErrConflict)
+	case errors.Is(err, storage.ErrNotFound):
+		return nil
+	default:
+		return fmt.Errorf("check query id: %w", err)
+	}
+}
+
+// handleQueryError stores cancelled queries in history and turns the error into QueryCancelledError. Other errors
+// are returned as is.
+func (s *Service) handleQueryError( //nolint:gocritic
+	ctx context.Context,
+	queryCtx context.Context,
+	req execQueryReq,
+	queryStartedAt time.Time,
+	networkRoundTripDuration time.Duration,
+	err error,
+) error {
+	reason, ok := cancelReason(queryCtx, err)
+	if !ok {
+		return err
+	}
+
+	meta := cancelledMeta(req, networkRoundTripDuration, reason)
+	// NOTE: the request context can be cancelled already when the client has gone away.
+	if err := s.storeQueryResults(context.WithoutCancel(ctx), req, queryStartedAt, emptyQTable(), meta); err != nil {
+		return err
+	}
+
+	return &QueryCancelledError{QueryID: req.ID, Reason: reason}
+}
+
+func cancelledMeta(req execQueryReq, networkRoundTripDuration time.Duration, reason string) structs.QMeta { //nolint:gocritic
+	return structs.QMeta{ //nolint:exhaustruct
+		ExecutionTimeMS:    time.Since(req.StartedAt).Milliseconds(),
+		ParsingTimeMS:      req.ParsingDuration.Milliseconds(),
+		NetworkRoundTripMS: networkRoundTripDuration.Milliseconds(),
+		VectorsCount:       req.VectorsCount,
+		Cancelled:          true,
+		CancelReason:       reason,
+		DBRole:             req.DBRole,
+		Replica:            req.Replica,
+	}
+}
+
+func emptyQTable() structs.QTable {
+	return structs.QTable{Headers: []string{}, Columns: []structs.QColumn{}, Rows: [][]any{}}
+}
+
+// preparedQuery is a query that passed all preflight checks and can be executed on the target.
+type preparedQuery struct {
+	target          config.Target
+	schema          *validator.DbSchema
+	targetQuery     string
+	vectors         []validator.Vec
+	parsingDuration time.Duration
+	dbRole          string
+}
+
+func (p *preparedQuery) execReq(
+	queryID uuid6.UUID,
+	user structs.User,
+	query string,
+	startedAt time.Time,
+	route QueryRoute,
+) execQueryReq {
+	return execQueryReq{
+		ID:              queryID,
+		Target:          p.target,
+		UserID:          user.ID,
+		Query:           query,
+		TargetQuery:     p.targetQuery,
+		StartedAt:       startedAt,
+		ParsingDuration: p.parsingDuration,
+		VectorsCount:    len(p.vectors),
+		Limits:          resultLimits(p.target, user.Role),
+		ReadOnly:        readOnlyQuery(p.target, p.vectors),
+		SelectOnly:      isSelectOnly(p.vectors),
+		DBRole:          p.dbRole,
+		UseReplica:      route != QueryRoutePrimary && isSelectOnly(p.vectors),
+		Replica:         "",
+	}
+}
+
+// queryRewriter turns the query of the user into the query that is sent to the target.
+type queryRewriter func(query string, schema *validator.DbSchema) (string, error)
+
+// previewRewriter expands stars and adds RETURNING with allowlisted columns, so a preview shows the changed rows.
+func previewRewriter(query string, schema *validator.DbSchema) (string, error) {
+	query, err := validator.ExpandStar(query, schema)
+	if err != nil {
+		return "", err //nolint:wrapcheck
+	}
+
+	return validator.AddReturning(query, schema) //nolint:wrapcheck
+}
+
+// prepareQuery checks the query against the schema and the policy of the user.
+func (s *Service) prepareQuery(
+	ctx context.Context,
+	user structs.User,
+	srvID config.TargetID,
+	query string,
+) (*preparedQuery, error) {
+	return s.prepareRewrittenQuery(ctx, user, srvID, query, validator.ExpandStar)
+}
+
+// prepareRewrittenQuery is prepareQuery with a custom rewrite of the query. The rewritten query is checked, so
+// columns added by rewrite pass the schema and the policy as well.
+func (s *Service) prepareRewrittenQuery(
+	ctx context.Context,
+	user structs.User,
+	srvID config.TargetID,
+	query string,
+	rewrite queryRewriter,
+) (*preparedQuery, error) {
+	subjects, grants, err := s.getSubjects(ctx, user)
+	if err != nil {
+		return nil, fmt.Errorf("get subjects: %w", err)
+	}
+
+	srv, schema, err := s.getTargetForSubjects(subjects, srvID)
+	if err != nil {
+		return nil, fmt.Errorf("get target by id: %w", err)
+	}
+
+	policyQuery := func(vec validator.Vec) policy.Query {
+		return policy.Query{
+			Target:  srvID.S(),
+			Op:      vec.Op.S(),
+			Table:   schema.CanonicalTable(vec.Tbl),
+			Columns: vec.Cols,
+			Filter:  vec.Filter,
+			Group:   vec.Group,
+			Sort:    vec.Sort,
+		}
+	}
+	haveAccess := func(vec validator.Vec) bool {
+		return s.opts.authorizer.AllowQuery(subjects, policyQuery(vec))
+	}
+
+	parsingStartedAt := time.Now()
+	// NOTE: the target receives the rewritten query, so only allowlisted columns are selected. History keeps the
+	//  original query of the user.
+	targetQuery, err := rewrite(query, schema)
+	if err != nil {
+		log.Error("err", err.Error())
+
+		return nil, fmt.Errorf("preflight check: rewrite query: %w", err)
+	}
+
+	vectors, err := validator.MakeVectors(targetQuery)
+	parsingDuration := time.Since(parsingStartedAt)
+	if err != nil {
+		log.Error("err", err.Error())
+
+		return nil, fmt.Errorf("preflight check: make vectors: %w", err)
+	}
+
+	if err := validator.ValidateSchema(vectors, schema); err != nil {
+		log.Error("err", err.Error())
+
+		return nil, fmt.Errorf("preflight check: validate schema: %w", err)
+	}
+
+	if err := validator.ValidateAccess(vectors, haveAccess); err != nil {
+		if denied, ok := just.ErrAs[*validator.AccessDeniedError](err); ok {
+			denied.Vec.Tbl = schema.CanonicalTable(denied.Vec.Tbl)
+			denied.Reasons = s.opts.authorizer.QueryDenyReasons(subjects, policyQuery(denied.Vec))
+		}
+
+		log.Error("err", err.Error())
+
+		return nil, fmt.Errorf("preflight check: validate access: %w", err)
+	}
+
+	dbRole, err := impersonatedRole(*srv, user)
+	if err != nil {
+		return nil, err
+	}
+
+	// NOTE: every query that runs while the user holds a grant for this target is audited, even when the grant was
+	//  not needed to pass the policy.
+	for _, grant := range grants {
+		if grant.TargetID != srvID {
+			continue
+		}
+
+		details := map[string]string{"grant_id": grant.ID.S(), "query": query}
+		if err := s.writeAudit(ctx, s.opts.storage.Conn(ctx), user.ID, auditActionBreakGlassQuery, srvID, details); err != nil {
+			return nil, fmt.Errorf("audit break glass query: %w", err)
+		}
+	}
+
+	return &preparedQuery{
+		target:          *srv,
+		schema:          schema,
+		targetQuery:     targetQuery,
+		vectors:         vectors,
+		parsingDuration: parsingDuration,
+		dbRole:          dbRole,
+	}, nil
+}
+
+// runTargetQuery runs the query on the target. Read-only queries run inside a read-only transaction, so the
+// target rejects any write they may attempt. Impersonated queries run inside a transaction that switches the role.
+func runTargetQuery(ctx context.Context, conn *pgxpool.Pool, req execQueryReq) (*queryOutput, error) { //nolint:gocritic
+	if !req.ReadOnly && req.DBRole == "" {
+		return collectQTable(ctx, conn, req.TargetQuery, req.Limits)
+	}
+
+	txOptions := pgx.TxOptions{} //nolint:exhaustruct
+	if req.ReadOnly {
+		txOptions.AccessMode = pgx.ReadOnly
+	}
+
+	tx, err := conn.BeginTx(ctx, txOptions)
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	// NOTE: rollback is a no-op after commit. It uses a detached context to release the connection even when
+	// the query was cancelled.
+	defer tx.Rollback(context.WithoutCancel(ctx)) //nolint:errcheck
+
+	if err := setLocalRole(ctx, tx, req.DBRole); err != nil {
+		return nil, err
+	}
+
+	var out *queryOutput
+	if req.SelectOnly && (req.Limits.MaxRows > 0 || req.Limits.MaxBytes > 0) {
+		out, err = collectCursor(ctx, tx, req.TargetQuery, req.Limits)
+	} else {
+		out, err = collectQTable(ctx, tx, req.TargetQuery, req.Limits)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commit transaction: %w", err)
+	}
+
+	return out, nil
+}
+
+type execer interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+}
+
+// setLocalRole switches the role until the end of the transaction. Empty role keeps the role of the connection.
+func setLocalRole(ctx context.Context, tx execer, dbRole string) error {
+	if dbRole == "" {
+		return nil
+	}
+
+	if _, err := tx.Exec(ctx, "set local role "+pgx.Identifier{dbRole}.Sanitize()); err != nil {
+		return fmt.Errorf("set local role: %w", err)
+	}
+
+	return nil
+}
+
+// impersonatedRole returns the database role of the user on the target. Users without a mapping are forbidden to
+// query a target that impersonates users.
+func impersonatedRole(target config.Target, user structs.User) (string, error) { //nolint:gocritic
+	impersonation := target.Impersonation
+	if impersonation == nil {
+		return "", nil
+	}
+
+	if dbRole, ok := impersonation.Users[user.ID]; ok {
+		return dbRole, nil
+	}
+
+	if dbRole, ok := impersonation.Roles[user.Role]; ok {
+		return dbRole, nil
+	}
+
+	return "", fmt.Errorf("no database role for user %q on target %q: %w", user.ID, target.ID, ErrForbidden)
+}
+
+type querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+// queryOutput is the part of the query result that was read from the target.
+type queryOutput struct {
+	table        *structs.QTable
+	fields       []pgconn.FieldDescription
+	truncated    bool
+	rowsAffected int64
+}
+
+func (o *queryOutput) meta(req execQueryReq, networkRoundTripDuration time.Duration) structs.QMeta { //nolint:gocritic
+	return structs.QMeta{ //nolint:exhaustruct
+		ExecutionTimeMS:    time.Since(req.StartedAt).Milliseconds(),
+		ParsingTimeMS:      req.ParsingDuration.Milliseconds(),
+		NetworkRoundTripMS: networkRoundTripDuration.Milliseconds(),
+		RowsCount:          len(o.table.Rows),
+		ColumnsCount:       len(o.table.Headers),
+		VectorsCount:       req.VectorsCount,
+		Truncated:          o.truncated,
+		Limit:              req.Limits.MaxRows,
+		LimitBytes:         req.Limits.MaxBytes,
+		DBRole:             req.DBRole,
+		Replica:            req.Replica,
+	}
+}
+
+// collectQTable reads the result until limits are reached. The rest of the result is discarded, but the statement
+// itself is not interrupted, so writes with RETURNING still complete.
+func collectQTable(
+	ctx context.Context,
+	conn querier,
+	query string,
+	limits config.ResultLimits,
+) (*queryOutput, error) {
+	res, err := conn.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+	defer res.Close()
+
+	collector := resultCollector{limits: limits, fields: res.FieldDescriptions()} //nolint:exhaustruct
+	if _, err := collector.read(res); err != nil {
+		return nil, err
+	}
+
+	// NOTE: Close drains the rest of the result, so errors of the statement are reported by Err in both cases.
+	res.Close()
+	if err := res.Err(); err != nil {
+		return nil, fmt.Errorf("collect rows: %w", err)
+	}
+
+	return collector.output(res.Conn().TypeMap(), res.CommandTag().RowsAffected()), nil
+}
+
+const (
+	resultCursorName = "dbgw_result"
+	// resultCursorBatch is the largest number of rows fetched from the cursor at once.
+	resultCursorBatch = 1000
+)
+
+// collectCursor reads the result of a select through a cursor. Rows are fetched in batches that do not exceed the
+// row limit, so the target stops producing rows once a limit is reached. The cursor lives until the end of tx.
+func collectCursor(ctx context.Context, tx pgx.Tx, query string, limits config.ResultLimits) (*queryOutput, error) {
+	query = strings.TrimRight(strings.TrimSpace(query), ";")
+	if _, err := tx.Exec(ctx, "declare "+resultCursorName+" no scroll cursor for "+query); err != nil {
+		return nil, fmt.Errorf("declare cursor: %w", err)
+	}
+
+	collector := resultCollector{limits: limits} //nolint:exhaustruct
+	var typeMap *pgtype.Map
+	for {
+		batch := resultCursorBatch
+		if limits.MaxRows > 0 {
+			// NOTE: one extra row tells that the result was truncated.
+			batch = min(batch, limits.MaxRows-len(collector.rows)+1)
+		}
+
+		// NOTE: the same fetch statement returns rows of different shape for every cursor, so its description must
+		//  not be cached.
+		fetchSQL := "fetch forward " + strconv.Itoa(batch) + " from " + resultCursorName
+		res, err := tx.Query(ctx, fetchSQL, pgx.QueryExecModeDescribeExec)
+		if err != nil {
+			return nil, fmt.Errorf("fetch rows: %w", err)
+		}
+
+		if collector.fields == nil {
+			collector.fields = res.FieldDescriptions()
+		}
+
+		fetched, err := collector.read(res)
+		res.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		if err := res.Err(); err != nil {
+			return nil, fmt.Errorf("collect rows: %w", err)
+		}
+		typeMap = res.Conn().TypeMap()
+
+		if collector.truncated || fetched < batch {
+			break
+		}
+	}
+
+	return collector.output(typeMap, int64(len(collector.rows))), nil
+}
+
+// resultCollector keeps rows of the result until limits are reached.
+type resultCollector struct {
+	limits    config.ResultLimits
+	fields    []pgconn.FieldDescription
+	rows      [][]any
+	size      int64
+	truncated bool
+}
+
+// read appends rows of res until a limit is reached and returns the number of rows that were read from res.
+func (c *resultCollector) read(res pgx.Rows) (int, error) {
+	var fetched int
+	for res.Next() {
+		fetched++
+
+		if c.limits.MaxRows > 0 && len(c.rows) >= c.limits.MaxRows {
+			c.truncated = true
+
+			break
+		}
+
+		values, err := res.Values()
+		if err != nil {
+			return fetched, fmt.Errorf("read row values: %w", err)
+		}
+
+		row := make([]any, len(values))
+		var rowSize int64
+		for i := range values {
+			row[i] = adaptPgValue(c.fields[i].DataTypeOID, values[i])
+			rowSize += cellSize(row[i])
+		}
+
+		if c.limits.MaxBytes > 0 && c.size+rowSize > c.limits.MaxBytes {
+			c.truncated = true
+
+			break
+		}
+
+		c.size += rowSize
+		c.rows = append(c.rows, row)
+	}
+
+	return fetched, nil
+}
+
+func (c *resultCollector) output(typeMap *pgtype.Map, rowsAffected int64) *queryOutput {
+	columns := just.SliceMap(c.fields, func(fd pgconn.FieldDescription) structs.QColumn {
+		typeName := "unknown"
+		if pgType, ok := typeMap.TypeForOID(fd.DataTypeOID); ok {
+			typeName = pgType.Name
+		}
+
+		return structs.QColumn{
+			Name:     fd.Name,
+			TypeOID:  fd.DataTypeOID,
+			TypeName: typeName,
+			Nullable: true,
+		}
+	})
+
+	return &queryOutput{
+		table: &structs.QTable{
+			Headers: just.SliceMap(columns, func(col structs.QColumn) string { return col.Name }),
+			Columns: columns,
+			Rows:    just.If(c.rows == nil, [][]any{}, c.rows),
+		},
+		fields:       c.fields,
+		truncated:    c.truncated,
+		rowsAffected: rowsAffected,
+	}
+}
+
+// describeColumns resolves full type names (with modifiers, like varchar(64)) and NOT NULL constraints of result
+// columns from the catalog of the target. It is best effort: the result keeps names known to pgx when the catalog
+// is not available.
+func (s *Service) describeColumns(ctx context.Context, conn *pgxpool.Pool, out *queryOutput) {
+	if len(out.fields) == 0 {
+		return
+	}
+
+	var (
+		typeOIDs  = make([]uint32, len(out.fields))
+		typeMods  = make([]int32, len(out.fields))
+		tableOIDs = make([]uint32, len(out.fields))
+		attNums   = make([]int16, len(out.fields))
+	)
+	for i, fd := range out.fields {
+		typeOIDs[i] = fd.DataTypeOID
+		typeMods[i] = fd.TypeModifier
+		tableOIDs[i] = fd.TableOID
+		attNums[i] = int16(fd.TableAttributeNumber) //nolint:gosec // attribute numbers fit int2 in postgres
+	}
+
+	// NOTE: the catalog is read through the pool, so a failure here does not abort the transaction of the query.
+	rows, err := conn.Query(ctx, describeColumnsQuery, typeOIDs, typeMods, tableOIDs, attNums)
+	if err != nil {
+		s.opts.logger.Warn("describe result columns", slog.String("error", err.Error()))
+
+		return
+	}
+
+	described, err := pgx.CollectRows(rows, pgx.RowToStructByPos[describedColumn])
+	if err != nil {
+		s.opts.logger.Warn("describe result columns", slog.String("error", err.Error()))
+
+		return
+	}
+
+	for _, col := range described {
+		idx := int(col.Idx) - 1
+		if idx < 0 || idx >= len(out.table.Columns) {
+			continue
+		}
+
+		out.table.Columns[idx].TypeName = col.TypeName
+		out.table.Columns[idx].Nullable = !col.NotNull
+	}
+}
+
+const describeColumnsQuery = `
+select f.idx, format_type(f.type_oid, f.type_mod), coalesce(a.attnotnull, false)
+from unnest($1::oid[], $2::int4[], $3::oid[], $4::int2[]) with ordinality as f(type_oid, type_mod, rel_oid, att_num, idx)
+left join pg_attribute a on a.attrelid = f.rel_oid and a.attnum = f.att_num and f.att_num > 0`
+
+type describedColumn struct {
+	Idx      int64
+	TypeName string
+	NotNull  bool
+}
+
+// resultLimits returns limits of the target narrowed by limits of the role. Zero means no limit.
+func resultLimits(target config.Target, role config.Role) config.ResultLimits { //nolint:gocritic
+	limits := target.Limits
+	roleLimits, ok := target.RoleLimits[role]
+	if !ok {
+		return limits
+	}
+
+	return config.ResultLimits{
+		MaxRows:  stricterLimit(limits.MaxRows, roleLimits.MaxRows),
+		MaxBytes: stricterLimit(limits.MaxBytes, roleLimits.MaxBytes),
+	}
+}
+
+func stricterLimit[T int | int64](a, b T) T {
+	switch {
+	case a == 0:
+		return b
+	case b == 0:
+		return a
+	default:
+		return min(a, b)
+	}
+}
+
+func (s *Service) storeQueryResults( //nolint:gocritic
+	ctx context.Context,
+	req execQueryReq,
+	queryStartedAt time.Time,
+	qTable structs.QTable,
+	meta structs.QMeta,
+) error {
+	buf, err := json.Marshal(storedQueryResultPayload{
+		Table: qTable,
+		Meta:  meta,
+	})
+	if err != nil {
+		return fmt.Errorf("marshal qtable: %w", err)
+	}
+
+	insertReq := storage.InsertQueryResultsReq{
+		ID:        req.ID,
+		UserID:    req.UserID,
+		TargetID:  req.Target.ID,
+		CreatedAt: queryStartedAt,
+		Query:     req.Query,
+		Response:  buf,
+		Status:    just.If(meta.Cancelled, structs.QueryStatusCancelled, structs.QueryStatusSucceeded),
+		Error:     "",
+	}
+	if err := s.opts.storage.InsertQueryResults(s.opts.storage.Conn(ctx), insertReq); err != nil {
+		return fmt.Errorf("insert query results: %w", err)
+	}
+
+	return nil
+}
+
+// cancelReason reports whether the query failed because it was cancelled by the user or by the statement timeout
+// of the target.
+func cancelReason(queryCtx context.Context, err error) (string, bool) {
+	if errors.Is(context.Cause(queryCtx), errCancelledByUser) {
+		return cancelReasonUser, true
+	}
+
+	if errors.Is(context.Cause(queryCtx), errCancelledByShutdown) {
+		return cancelReasonShutdown, true
+	}
+
+	if pgErr, ok := just.ErrAs[*pgconn.PgError](err); ok && pgErr.Code == pgCodeQueryCanceled {
+		return cancelReasonStatementTimeout, true
+	}
+
+	return "", false
+}
+
+func (s *Service) requestApproval(
+	ctx context.Context,
+	user structs.User,
+	targetID config.TargetID,
+	query, targetQuery string,
+	vectors []structs.QueryVector,
+) (uuid6.UUID, error) {
+	vectorsBuf, err := json.Marshal(vectors)
+	if err != nil {
+		return uuid6.Nil(), fmt.Errorf("marshal vectors: %w", err)
+	}
+
+	req := storage.InsertQueryApprovalReq{
+		ID:          uuid6.New(),
+		UserID:      user.ID,
+		UserName:    user.Username,
+		UserRole:    user.Role,
+		TargetID:    targetID,
+		Query:       query,
+		TargetQuery: targetQuery,
+		Vectors:     vectorsBuf,
+		CreatedAt:   time.Now(),
+	}
+	if err := s.opts.storage.InsertQueryApproval(s.opts.storage.Conn(ctx), req); err != nil {
+		return uuid6.Nil(), fmt.Errorf("insert query approval: %w", err)
+	}
+
+	s.opts.logger.Info("query waits for approval",
+		slog.String("approval_id", req.ID.S()),
+		slog.String("user", user.ID.S()),
+		slog.String("target", targetID.S()))
+
+	return req.ID, nil
+}
+
+// requiresApproval reports whether the query must be reviewed before it runs. Only writes are held, reads run
+// immediately even on targets that require approval.
+func requiresApproval(target config.Target, vectors []validator.Vec) bool { //nolint:gocritic
+	if !target.RequireApproval {
+		return false
+	}
+
+	return !isSelectOnly(vectors)
+}
+
+// readOnlyQuery reports whether the query must run inside a read-only transaction. This is a safety net under the
+// parser: a volatile function called from a select still cannot write.
+func readOnlyQuery(target config.Target, vectors []validator.Vec) bool { //nolint:gocritic
+	return target.ReadOnly || isSelectOnly(vectors)
+}
+
+func isSelectOnly(vectors []validator.Vec) bool {
+	return just.SliceAll(vectors, func(vec validator.Vec) bool {
+		return vec.Op == config.OpSelect
+	})
+}
+
+// approvalAuthor returns the identity of the user who submitted the query. Approved queries run with the author's
+// database role, never with the role of the reviewer.
+func approvalAuthor(approval storage.QueryApproval) structs.User { //nolint:gocritic
+	return structs.User{
+		ID:       approval.UserID,
+		Username: approval.UserName,
+		Role:     approval.UserRole,
+	}
+}
+
+// canReviewApproval reports whether the user may approve or reject a query of the author. Only admins review, and
+// nobody reviews own queries.
+func canReviewApproval(user structs.User, authorID config.UserID) bool {
+	return user.Role == config.RoleAdmin && user.ID != authorID
+}
+
+func (s *Service) getApprovalForReview(
+	ctx context.Context,
+	user structs.User,
+	approvalID uuid6.UUID,
+) (*storage.QueryApproval, *config.Target, error) {
+	if user.Role != config.RoleAdmin {
+		return nil, nil, ErrForbidden
+	}
+
+	approval, err := s.opts.storage.GetQueryApprovalByID(s.opts.storage.Conn(ctx), approvalID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, nil, fmt.Errorf("unknown approval id: %w", ErrNotFound)
+		}
+
+		return nil, nil, fmt.Errorf("get query approval: %w", err)
+	}
+
+	if !canReviewApproval(user, approval.UserID) {
+		return nil, nil, fmt.Errorf("user cannot review own query: %w", ErrForbidden)
+	}
+
+	if approval.Status != structs.ApprovalStatusPending {
+		return nil, nil, fmt.Errorf("approval is %s: %w", approval.Status, ErrConflict)
+	}
+
+	// NOTE: the reviewer must have access to the target as well.
+	target, _, err := s.getTargetByID(ctx, user, approval.TargetID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("get target by id: %w", err)
+	}
+
+	return approval, target, nil
+}
+
+const (
+	cancelReasonUser             = "cancelled by user"
+	cancelReasonStatementTimeout = "statement timeout"
+	cancelReasonShutdown         = "gateway shutdown"
+
+	// pgCodeQueryCanceled is returned by postgres when statement_timeout is reached.
+	pgCodeQueryCanceled = "57014"
+)
+
+const (
+	auditActionBreakGlassGrant  = "break_glass.grant"
+	auditActionBreakGlassRevoke = "break_glass.revoke"
+	auditActionBreakGlassQuery  = "break_glass.query"
+)
+
+func (s *Service) writeAudit(
+	_ context.Context,
+	conn qrm.DB,
+	uid config.UserID,
+	action string,
+	targetID config.TargetID,
+	details map[string]string,
+) error {
+	buf, err := json.Marshal(details)
+	if err != nil {
+		return fmt.Errorf("marshal audit details: %w", err)
+	}
+
+	req := storage.InsertAuditRecordReq{
+		ID:        uuid6.New(),
+		UserID:    uid,
+		Action:    action,
+		TargetID:  targetID,
+		Details:   buf,
+		CreatedAt: time.Now(),
+	}
+	if err := s.opts.storage.InsertAuditRecord(conn, req); err != nil {
+		return fmt.Errorf("insert audit record: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,118 @@
+// Database Gateway provides access to servers with ACL for safe and restricted database interactions.
+// Copyright (C) 2024  Kirill Zhuravlev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package app
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/kazhuravlev/database-gateway/internal/config"
+	"github.com/kazhuravlev/database-gateway/internal/structs"
+	"github.com/kazhuravlev/just"
+)
+
+// QueryRoute tells where a select-only query may run.
+type QueryRoute string
+
+const (
+	// QueryRouteAuto runs select-only queries on a healthy replica when the target has one.
+	QueryRouteAuto QueryRoute = "auto"
+	// QueryRoutePrimary runs the query on the primary, for example when the user needs fresh data.
+	QueryRoutePrimary QueryRoute = "primary"
+)
+
+// replicaTarget turns the replica into a target of its own, so it gets its own pool, tunnel and health probe.
+func replicaTarget(target config.Target, replica config.Replica) config.Target { //nolint:gocritic
+	res := target
+	res.ID = config.TargetID(target.ID.S() + "@" + replica.ID)
+	res.Connection = replica.Connection
+	res.Replicas = nil
+
+	return res
+}
+
+// poolTargets returns targets together with their replicas. Every returned target has a pool of its own.
+func poolTargets(targets []config.Target) []config.Target {
+	res := make([]config.Target, 0, len(targets))
+	for _, target := range targets {
+		res = append(res, target)
+		for _, replica := range target.Replicas {
+			res = append(res, replicaTarget(target, replica))
+		}
+	}
+
+	return res
+}
+
+// isReplicaUsable reports whether the last probe found a replica that does not lag more than maxLag. Zero maxLag
+// disables the lag check. A replica with unknown lag is skipped when the check is enabled.
+func isReplicaUsable(health structs.TargetHealth, maxLag config.Duration) bool { //nolint:gocritic
+	if health.Status != structs.TargetStatusUp || !health.IsReplica {
+		return false
+	}
+
+	if maxLag == 0 {
+		return true
+	}
+
+	if health.ReplicationLagSeconds == nil {
+		return false
+	}
+
+	return *health.ReplicationLagSeconds <= maxLag.D().Seconds()
+}
+
+// pickReplica returns one of usable replicas of the target. Replicas take queries in turn.
+func (s *Service) pickReplica(target config.Target) (config.Replica, bool) { //nolint:gocritic
+	usable := just.SliceFilter(target.Replicas, func(replica config.Replica) bool {
+		return isReplicaUsable(s.health.get(replicaTarget(target, replica).ID), target.MaxReplicaLag)
+	})
+	if len(usable) == 0 {
+		return config.Replica{}, false //nolint:exhaustruct
+	}
+
+	idx := s.replicaTurn.Add(1) % uint64(len(usable))
+
+	return usable[idx], true
+}
+
+// queryConnection returns the pool the query should run on and the id of the chosen replica. Queries that may not
+// run on a replica, or find no usable one, run on the primary.
+func (s *Service) queryConnection(ctx context.Context, req execQueryReq) (*pgxpool.Pool, string, error) { //nolint:gocritic
+	if req.UseReplica {
+		if replica, ok := s.pickReplica(req.Target); ok {
+			conn, err := s.getConnection(ctx, replicaTarget(req.Target, replica))
+			if err == nil {
+				return conn, replica.ID, nil
+			}
+
+			s.opts.logger.Warn("replica is unavailable, fall back to primary",
+				slog.String("target", req.Target.ID.S()),
+				slog.String("replica", replica.ID),
+				slog.String("error", err.Error()))
+		}
+	}
+
+	conn, err := s.getConnection(ctx, req.Target)
+	if err != nil {
+		return nil, "", fmt.Errorf("get connection by id: %w", err)
+	}
+
+	return conn, "", nil
+}
@@ -0,0 +1,140 @@
+// Database Gateway provides access to servers with ACL for safe and restricted database interactions.
+// Copyright (C) 2024  Kirill Zhuravlev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package app //nolint:testpackage
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kazhuravlev/database-gateway/internal/config"
+	"github.com/kazhuravlev/database-gateway/internal/structs"
+	"github.com/kazhuravlev/just"
+	"github.com/stretchr/testify/require"
+)
+
+func replicaHealth(status structs.TargetStatus, isReplica bool, lag *float64) structs.TargetHealth {
+	health := unknownHealth()
+	health.Status = status
+	health.IsReplica = isReplica
+	health.ReplicationLagSeconds = lag
+
+	return health
+}
+
+func TestIsReplicaUsable(t *testing.T) {
+	t.Parallel()
+
+	maxLag := config.Duration(10 * time.Second)
+
+	testCases := []struct {
+		name   string
+		health structs.TargetHealth
+		maxLag config.Duration
+		want   bool
+	}{
+		{name: "not probed yet", health: unknownHealth(), maxLag: 0, want: false},
+		{name: "down", health: replicaHealth(structs.TargetStatusDown, true, nil), maxLag: 0, want: false},
+		{name: "promoted to primary", health: replicaHealth(structs.TargetStatusUp, false, nil), maxLag: 0, want: false},
+		{name: "no lag limit", health: replicaHealth(structs.TargetStatusUp, true, nil), maxLag: 0, want: true},
+		{name: "unknown lag", health: replicaHealth(structs.TargetStatusUp, true, nil), maxLag: maxLag, want: false},
+		{
+			name:   "lag within limit",
+			health: replicaHealth(structs.TargetStatusUp, true, just.Pointer(9.5)),
+			maxLag: maxLag,
+			want:   true,
+		},
+		{
+			name:   "lag over limit",
+			health: replicaHealth(structs.TargetStatusUp, true, just.Pointer(10.5)),
+			maxLag: maxLag,
+			want:   false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, tc.want, isReplicaUsable(tc.health, tc.maxLag))
+		})
+	}
+}
+
+func TestPickReplica(t *testing.T) {
+	t.Parallel()
+
+	target := config.Target{ //nolint:exhaustruct
+		ID: "taxi-prod",
+		Replicas: []config.Replica{
+			{ID: "replica-1", Connection: config.Connection{}}, //nolint:exhaustruct
+			{ID: "replica-2", Connection: config.Connection{}}, //nolint:exhaustruct
+			{ID: "replica-3", Connection: config.Connection{}}, //nolint:exhaustruct
+		},
+		MaxReplicaLag: config.Duration(10 * time.Second),
+	}
+
+	newService := func() *Service {
+		return &Service{ //nolint:exhaustruct
+			health:      newHealthRegistry(),
+			replicaTurn: new(atomic.Uint64),
+		}
+	}
+
+	t.Run("replicas become targets of their own", func(t *testing.T) {
+		t.Parallel()
+
+		ids := just.SliceMap(poolTargets([]config.Target{target}), func(target config.Target) config.TargetID {
+			return target.ID
+		})
+		require.Equal(t, []config.TargetID{
+			"taxi-prod",
+			"taxi-prod@replica-1",
+			"taxi-prod@replica-2",
+			"taxi-prod@replica-3",
+		}, ids)
+	})
+
+	t.Run("fall back to primary", func(t *testing.T) {
+		t.Parallel()
+
+		svc := newService()
+		svc.health.set("taxi-prod@replica-1", replicaHealth(structs.TargetStatusDown, true, nil))
+		svc.health.set("taxi-prod@replica-2", replicaHealth(structs.TargetStatusUp, true, just.Pointer(60.0)))
+
+		_, ok := svc.pickReplica(target)
+		require.False(t, ok)
+	})
+
+	t.Run("usable replicas take turns", func(t *testing.T) {
+		t.Parallel()
+
+		svc := newService()
+		svc.health.set("taxi-prod@replica-1", replicaHealth(structs.TargetStatusUp, true, just.Pointer(1.0)))
+		svc.health.set("taxi-prod@replica-2", replicaHealth(structs.TargetStatusUp, true, just.Pointer(60.0)))
+		svc.health.set("taxi-prod@replica-3", replicaHealth(structs.TargetStatusUp, true, just.Pointer(2.0)))
+
+		picked := make(map[string]int)
+		for range 4 {
+			replica, ok := svc.pickReplica(target)
+			require.True(t, ok)
+			picked[replica.ID]++
+		}
+
+		require.Equal(t, map[string]int{"replica-1": 2, "replica-3": 2}, picked)
+	})
+}
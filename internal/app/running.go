@@ -0,0 +1,107 @@
+// Database Gateway provides access to servers with ACL for safe and restricted database interactions.
+// Copyright (C) 2024  Kirill Zhuravlev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/kazhuravlev/database-gateway/internal/config"
+	"github.com/kazhuravlev/database-gateway/internal/uuid6"
+)
+
+var (
+	// errCancelledByUser is the cancellation cause of queries stopped through CancelQuery.
+	errCancelledByUser = errors.New("cancelled by user")
+	// errCancelledByShutdown is the cancellation cause of queries stopped by Close.
+	errCancelledByShutdown = errors.New("cancelled by shutdown")
+)
+
+type runningQuery struct {
+	ownerID config.UserID
+	cancel  context.CancelCauseFunc
+}
+
+// queryRegistry keeps cancel functions of queries that are executing right now.
+type queryRegistry struct {
+	mu      *sync.Mutex
+	queries map[uuid6.UUID]runningQuery
+}
+
+func newQueryRegistry() *queryRegistry {
+	return &queryRegistry{
+		mu:      new(sync.Mutex),
+		queries: make(map[uuid6.UUID]runningQuery),
+	}
+}
+
+// start registers the query and returns a context that is cancelled by cancel. Call done when the query finishes.
+func (r *queryRegistry) start(
+	ctx context.Context,
+	queryID uuid6.UUID,
+	ownerID config.UserID,
+) (context.Context, func(), error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.queries[queryID]; ok {
+		return nil, nil, fmt.Errorf("query %s is already running: %w", queryID, ErrConflict)
+	}
+
+	queryCtx, cancel := context.WithCancelCause(ctx)
+	r.queries[queryID] = runningQuery{
+		ownerID: ownerID,
+		cancel:  cancel,
+	}
+
+	done := func() {
+		r.mu.Lock()
+		delete(r.queries, queryID)
+		r.mu.Unlock()
+
+		cancel(nil)
+	}
+
+	return queryCtx, done, nil
+}
+
+// cancel stops the running query. Only the owner of the query or an admin can cancel it.
+func (r *queryRegistry) cancel(queryID uuid6.UUID, userID config.UserID, isAdmin bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	query, ok := r.queries[queryID]
+	if !ok || (!isAdmin && query.ownerID != userID) {
+		return fmt.Errorf("query is not running: %w", ErrNotFound)
+	}
+
+	query.cancel(errCancelledByUser)
+
+	return nil
+}
+
+// cancelAll stops every registered query with the given cause.
+func (r *queryRegistry) cancelAll(cause error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, query := range r.queries {
+		query.cancel(cause)
+	}
+}
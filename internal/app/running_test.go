@@ -0,0 +1,160 @@
+// Database Gateway provides access to servers with ACL for safe and restricted database interactions.
+// Copyright (C) 2024  Kirill Zhuravlev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package app //nolint:testpackage
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/kazhuravlev/database-gateway/internal/config"
+	"github.com/kazhuravlev/database-gateway/internal/uuid6"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryRegistry(t *testing.T) {
+	t.Parallel()
+
+	const (
+		owner config.UserID = "alice@example.com"
+		other config.UserID = "bob@example.com"
+	)
+
+	t.Run("owner cancels own query", func(t *testing.T) {
+		t.Parallel()
+
+		registry := newQueryRegistry()
+		queryID := uuid6.New()
+
+		queryCtx, done, err := registry.start(context.Background(), queryID, owner)
+		require.NoError(t, err)
+		defer done()
+
+		require.NoError(t, registry.cancel(queryID, owner, false))
+		require.ErrorIs(t, context.Cause(queryCtx), errCancelledByUser)
+	})
+
+	t.Run("other user cannot cancel", func(t *testing.T) {
+		t.Parallel()
+
+		registry := newQueryRegistry()
+		queryID := uuid6.New()
+
+		queryCtx, done, err := registry.start(context.Background(), queryID, owner)
+		require.NoError(t, err)
+		defer done()
+
+		require.ErrorIs(t, registry.cancel(queryID, other, false), ErrNotFound)
+		require.NoError(t, queryCtx.Err())
+	})
+
+	t.Run("admin cancels any query", func(t *testing.T) {
+		t.Parallel()
+
+		registry := newQueryRegistry()
+		queryID := uuid6.New()
+
+		queryCtx, done, err := registry.start(context.Background(), queryID, owner)
+		require.NoError(t, err)
+		defer done()
+
+		require.NoError(t, registry.cancel(queryID, other, true))
+		require.ErrorIs(t, context.Cause(queryCtx), errCancelledByUser)
+	})
+
+	t.Run("finished query cannot be cancelled", func(t *testing.T) {
+		t.Parallel()
+
+		registry := newQueryRegistry()
+		queryID := uuid6.New()
+
+		_, done, err := registry.start(context.Background(), queryID, owner)
+		require.NoError(t, err)
+		done()
+
+		require.ErrorIs(t, registry.cancel(queryID, owner, false), ErrNotFound)
+	})
+
+	t.Run("duplicate id is rejected", func(t *testing.T) {
+		t.Parallel()
+
+		registry := newQueryRegistry()
+		queryID := uuid6.New()
+
+		_, done, err := registry.start(context.Background(), queryID, owner)
+		require.NoError(t, err)
+		defer done()
+
+		_, _, err = registry.start(context.Background(), queryID, owner)
+		require.ErrorIs(t, err, ErrConflict)
+	})
+
+	t.Run("shutdown cancels every query", func(t *testing.T) {
+		t.Parallel()
+
+		registry := newQueryRegistry()
+
+		ctx1, done1, err := registry.start(context.Background(), uuid6.New(), owner)
+		require.NoError(t, err)
+		defer done1()
+
+		ctx2, done2, err := registry.start(context.Background(), uuid6.New(), other)
+		require.NoError(t, err)
+		defer done2()
+
+		registry.cancelAll(errCancelledByShutdown)
+
+		reason, ok := cancelReason(ctx1, context.Canceled)
+		require.True(t, ok)
+		require.Equal(t, cancelReasonShutdown, reason)
+		require.ErrorIs(t, context.Cause(ctx2), errCancelledByShutdown)
+	})
+}
+
+func TestCancelReason(t *testing.T) {
+	t.Parallel()
+
+	t.Run("user cancel", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithCancelCause(context.Background())
+		cancel(errCancelledByUser)
+
+		reason, ok := cancelReason(ctx, context.Canceled)
+		require.True(t, ok)
+		require.Equal(t, cancelReasonUser, reason)
+	})
+
+	t.Run("statement timeout", func(t *testing.T) {
+		t.Parallel()
+
+		err := &pgconn.PgError{Code: "57014", Message: "canceling statement due to statement timeout"} //nolint:exhaustruct
+
+		reason, ok := cancelReason(context.Background(), err)
+		require.True(t, ok)
+		require.Equal(t, cancelReasonStatementTimeout, reason)
+	})
+
+	t.Run("other error", func(t *testing.T) {
+		t.Parallel()
+
+		reason, ok := cancelReason(context.Background(), errors.New("syntax error")) //nolint:err113
+		require.False(t, ok)
+		require.Empty(t, reason)
+	})
+}
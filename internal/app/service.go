@@ -17,20 +17,23 @@
 package app
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"net/url"
+	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/go-jet/jet/v2/qrm"
 	"github.com/google/uuid"
-	"github.com/jackc/pgx/v5"
-	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/kazhuravlev/database-gateway/internal/config"
 	"github.com/kazhuravlev/database-gateway/internal/policy/opa"
@@ -39,32 +42,94 @@ import (
 	"github.com/kazhuravlev/database-gateway/internal/uuid6"
 	"github.com/kazhuravlev/database-gateway/internal/validator"
 	"github.com/kazhuravlev/just"
-	"github.com/labstack/gommon/log"
 	"golang.org/x/oauth2"
 )
 
 var (
-	ErrNotFound  = errors.New("not found")
-	ErrForbidden = errors.New("forbidden")
+	ErrNotFound         = errors.New("not found")
+	ErrForbidden        = errors.New("forbidden")
+	ErrConflict         = errors.New("conflict")
+	ErrApprovalRequired = errors.New("approval required")
+	ErrCancelled        = errors.New("cancelled")
+
+	ErrPreviewNotSupported = errors.New("preview is not supported")
+	ErrQueueFull           = errors.New("queue is full")
+	ErrClosed              = errors.New("closed")
 )
 
+// ApprovalRequiredError is returned by RunQuery when the query was stored for review instead of being executed.
+type ApprovalRequiredError struct {
+	ApprovalID uuid6.UUID
+}
+
+func (e *ApprovalRequiredError) Error() string {
+	return "query waits for approval: " + e.ApprovalID.S()
+}
+
+func (e *ApprovalRequiredError) Unwrap() error {
+	return ErrApprovalRequired
+}
+
+// QueryCancelledError is returned when the query was stopped before it finished. The cancellation is stored in
+// history under QueryID.
+type QueryCancelledError struct {
+	QueryID uuid6.UUID
+	Reason  string
+}
+
+func (e *QueryCancelledError) Error() string {
+	return fmt.Sprintf("query %s: %s", e.QueryID.S(), e.Reason)
+}
+
+func (e *QueryCancelledError) Unwrap() error {
+	return ErrCancelled
+}
+
 type storedQueryResultPayload struct {
 	Table structs.QTable `json:"table"`
 	Meta  structs.QMeta  `json:"meta"`
 }
 
+// parseStoredQueryResult decodes numbers of cells as json.Number, so big integers and numerics keep their precision.
+func parseStoredQueryResult(buf []byte) (*storedQueryResultPayload, error) {
+	dec := json.NewDecoder(bytes.NewReader(buf))
+	dec.UseNumber()
+
+	var payload storedQueryResultPayload
+	if err := dec.Decode(&payload); err != nil {
+		return nil, fmt.Errorf("decode payload: %w", err)
+	}
+
+	return &payload, nil
+}
+
 type Service struct {
 	opts Options
 
 	connsMu       *sync.RWMutex
 	conns         map[config.TargetID]*pgxpool.Pool
+	tunnels       map[config.TargetID]*sshTunnel
+	connsClosed   bool
 	oauthCfg      *oauth2.Config
 	oidcProvider  *oidc.Provider
 	tokenVerifier *oidc.IDTokenVerifier
 	oidcLogoutEP  string
 	oidcRevokeEP  string
+	activeGrants  grantsLoader
+	running       *queryRegistry
+	previews      *previewRegistry
+	jobsMu        *sync.RWMutex
+	jobs          chan asyncJob
+	jobsClosed    bool
+	workers       *sync.WaitGroup
+	health        *healthRegistry
+	stopHealth    chan struct{}
+	replicaTurn   *atomic.Uint64
 }
 
+// grantsLoader returns break-glass grants of the user that are active at now.
+type grantsLoader func(ctx context.Context, uid config.UserID, now time.Time) ([]storage.BreakGlassGrant, error)
+
 type OIDCTokens struct {
 	IDToken     string
 	AccessToken string
@@ -83,6 +148,14 @@ func New(opts Options) (*Service, error) { //nolint:gocritic
 		return nil, errors.New("no role mappings defined") //nolint:err113
 	}
 
+	// NOTE: pools are created by the first health probe in background, so broken connection settings are reported
+	//  here instead.
+	for _, target := range poolTargets(opts.targets) {
+		if _, err := buildPoolConfig(target, opts.secrets); err != nil {
+			return nil, fmt.Errorf("bad connection of target %q: %w", target.ID, err)
+		}
+	}
+
 	oidcProvider, err := oidc.NewProvider(ctx, oidcCfg.IssuerURL)
 	if err != nil {
 		return nil, fmt.Errorf("init provider: %w", err)
@@ -107,26 +180,102 @@ func New(opts Options) (*Service, error) { //nolint:gocritic
 		ClientID: accessTokenAudience,
 	})
 
-	return &Service{
+	svc := &Service{
 		opts:          opts,
 		connsMu:       new(sync.RWMutex),
 		conns:         make(map[config.TargetID]*pgxpool.Pool),
+		tunnels:       make(map[config.TargetID]*sshTunnel),
+		connsClosed:   false,
 		oidcProvider:  oidcProvider,
 		tokenVerifier: tokenVerifier,
 		oauthCfg:      oauthCfg,
 		oidcLogoutEP:  discoveryClaims.EndSessionEndpoint,
 		oidcRevokeEP:  discoveryClaims.RevocationEndpoint,
-	}, nil
+		activeGrants: func(ctx context.Context, uid config.UserID, now time.Time) ([]storage.BreakGlassGrant, error) {
+			return opts.storage.ListActiveBreakGlassGrants(opts.storage.Conn(ctx), uid, now) //nolint:wrapcheck
+		},
+		running:     newQueryRegistry(),
+		previews:    newPreviewRegistry(),
+		jobsMu:      new(sync.RWMutex),
+		jobs:        make(chan asyncJob, opts.asyncQueueSize),
+		jobsClosed:  false,
+		workers:     new(sync.WaitGroup),
+		health:      newHealthRegistry(),
+		stopHealth:  make(chan struct{}),
+		replicaTurn: new(atomic.Uint64),
+	}
+
+	svc.workers.Add(opts.asyncWorkers)
+	for range opts.asyncWorkers {
+		go func() {
+			defer svc.workers.Done()
+
+			svc.runJobs()
+		}()
+	}
+
+	svc.workers.Go(func() {
+		svc.runHealthChecks(svc.stopHealth)
+	})
+
+	return svc, nil
+}
+
+// Close stops the service on shutdown. Submitted and running queries are cancelled, open previews are rolled back,
+// connection pools and ssh tunnels of targets are closed. The service should not be used after Close.
+func (s *Service) Close() {
+	s.jobsMu.Lock()
+	if s.jobsClosed {
+		s.jobsMu.Unlock()
+
+		return
+	}
+	s.jobsClosed = true
+	close(s.jobs)
+	close(s.stopHealth)
+	s.jobsMu.Unlock()
+
+	s.running.cancelAll(errCancelledByShutdown)
+	s.workers.Wait()
+
+	for _, preview := range s.previews.expireAll() {
+		s.finishExpiredPreview(preview)
+	}
+
+	s.connsMu.Lock()
+	defer s.connsMu.Unlock()
+
+	s.connsClosed = true
+	for targetID, pool := range s.conns {
+		s.opts.logger.Info("close target pool", slog.String("target", targetID.S()))
+		pool.Close()
+		delete(s.conns, targetID)
+	}
+
+	for targetID, tunnel := range s.tunnels {
+		if err := tunnel.Close(); err != nil {
+			s.opts.logger.Warn("close ssh tunnel",
+				slog.String("target", targetID.S()),
+				slog.String("error", err.Error()))
+		}
+		delete(s.tunnels, targetID)
+	}
 }
 
 // GetTargets return targets that available for this user.
-func (s *Service) GetTargets(_ context.Context, user structs.User) ([]structs.Server, error) {
-	subjects := userSubjects(user)
+func (s *Service) GetTargets(ctx context.Context, user structs.User) ([]structs.Server, error) {
+	subjects, _, err := s.getSubjects(ctx, user)
+	if err != nil {
+		return nil, fmt.Errorf("get subjects: %w", err)
+	}
+
 	availableTargets := just.SliceFilter(s.opts.targets, func(target config.Target) bool {
 		return s.opts.authorizer.AllowTarget(subjects, target.ID.S())
 	})
 
-	servers := just.SliceMap(availableTargets, adaptTarget)
+	servers := just.SliceMap(availableTargets, func(target config.Target) structs.Server {
+		return adaptTarget(target, s.health.get(target.ID))
+	})
 
 	return servers, nil
 }
@@ -137,113 +286,129 @@ func (s *Service) GetTargetByID(ctx context.Context, user structs.User, tID conf
 		return nil, fmt.Errorf("get target: %w", err)
 	}
 
-	return just.Pointer(adaptTarget(*res)), nil //nolint:modernize // false positive
+	return just.Pointer(adaptTarget(*res, s.health.get(res.ID))), nil //nolint:modernize // false positive
+}
+
+// GetTargetPoolStats returns connection pool statistics of every target. Only admins can read them.
+func (s *Service) GetTargetPoolStats(_ context.Context, user structs.User) ([]structs.TargetPoolStats, error) {
+	if user.Role != config.RoleAdmin {
+		return nil, ErrForbidden
+	}
+
+	s.connsMu.RLock()
+	defer s.connsMu.RUnlock()
+
+	return just.SliceMap(poolTargets(s.opts.targets), func(target config.Target) structs.TargetPoolStats {
+		return adaptPoolStats(target.ID, s.conns[target.ID])
+	}), nil
 }
 
+// RunQuery validates and executes the query. queryID lets the caller cancel the query while it runs; a new id is
+// generated when it is nil. Select-only queries run on a healthy replica unless route is QueryRoutePrimary.
 func (s *Service) RunQuery(
 	ctx context.Context,
 	user structs.User,
 	srvID config.TargetID,
+	queryID uuid6.UUID,
 	query string,
+	route QueryRoute,
 ) (uuid6.UUID, *structs.QTable, error) {
 	fullRoundTripStartedAt := time.Now()
-
-	srv, schema, err := s.getTargetByID(ctx, user, srvID)
-	if err != nil {
-		return uuid6.Nil(), nil, fmt.Errorf("get target by id: %w", err)
-	}
-	subjects := userSubjects(user)
-
-	haveAccess := func(vec validator.Vec) bool {
-		return s.opts.authorizer.AllowQuery(
-			subjects,
-			srvID.S(),
-			vec.Op.S(),
-			schema.CanonicalTable(vec.Tbl),
-		)
+	if queryID.IsNil() {
+		queryID = uuid6.New()
 	}
 
-	parsingStartedAt := time.Now()
-	vectors, err := validator.MakeVectors(query)
-	parsingDuration := time.Since(parsingStartedAt)
+	prepared, err := s.prepareQuery(ctx, user, srvID, query)
 	if err != nil {
-		log.Error("err", err.Error())
-
-		return uuid6.Nil(), nil, fmt.Errorf("preflight check: make vectors: %w", err)
+		return uuid6.Nil(), nil, err
 	}
 
-	if err := validator.ValidateSchema(vectors, schema); err != nil {
-		log.Error("err", err.Error())
+	if requiresApproval(prepared.target, prepared.vectors) {
+		queryVectors := just.SliceMap(prepared.vectors, func(vec validator.Vec) structs.QueryVector {
+			return adaptQueryVector(vec, prepared.schema)
+		})
+		approvalID, err := s.requestApproval(ctx, user, srvID, query, prepared.targetQuery, queryVectors)
+		if err != nil {
+			return uuid6.Nil(), nil, fmt.Errorf("request approval: %w", err)
+		}
 
-		return uuid6.Nil(), nil, fmt.Errorf("preflight check: validate schema: %w", err)
+		return uuid6.Nil(), nil, &ApprovalRequiredError{ApprovalID: approvalID}
 	}
 
-	if err := validator.ValidateAccess(vectors, haveAccess); err != nil {
-		log.Error("err", err.Error())
+	return s.execQuery(ctx, prepared.execReq(queryID, user, query, fullRoundTripStartedAt, route))
+}
 
-		return uuid6.Nil(), nil, fmt.Errorf("preflight check: validate access: %w", err)
+// PreviewQuery runs UPDATE or DELETE inside a transaction and keeps the transaction open, so the user can check
+// affected rows before CommitQuery or RollbackQuery. Queries without RETURNING return all allowlisted columns of the
+// changed rows. Transactions that were not finished in time are rolled back.
+func (s *Service) PreviewQuery(
+	ctx context.Context,
+	user structs.User,
+	srvID config.TargetID,
+	queryID uuid6.UUID,
+	query string,
+) (*QueryPreview, error) {
+	fullRoundTripStartedAt := time.Now()
+	if queryID.IsNil() {
+		queryID = uuid6.New()
 	}
 
-	conn, err := s.getConnection(ctx, *srv)
+	prepared, err := s.prepareRewrittenQuery(ctx, user, srvID, query, previewRewriter)
 	if err != nil {
-		return uuid6.Nil(), nil, fmt.Errorf("get connection by id: %w", err)
+		return nil, err
 	}
 
-	queryStartedAt := time.Now()
-	res, err := conn.Query(ctx, query)
-	networkRoundTripDuration := time.Since(queryStartedAt)
-	if err != nil {
-		return uuid6.Nil(), nil, fmt.Errorf("query: %w", err)
+	switch {
+	case !isPreviewable(prepared.vectors):
+		return nil, fmt.Errorf("only update and delete can be previewed: %w", ErrPreviewNotSupported)
+	case prepared.target.RequireApproval:
+		return nil, fmt.Errorf("writes on target %s require approval: %w", srvID, ErrPreviewNotSupported)
+	case prepared.target.ReadOnly:
+		return nil, fmt.Errorf("target %s is read only: %w", srvID, ErrPreviewNotSupported)
 	}
 
-	rows, err := pgx.CollectRows(res, func(row pgx.CollectableRow) ([]any, error) {
-		return row.Values()
-	})
+	return s.previewQuery(ctx, prepared.execReq(queryID, user, query, fullRoundTripStartedAt, QueryRoutePrimary))
+}
+
+// CommitQuery commits a previewed query. The outcome is stored in history in both cases, even when commit fails.
+func (s *Service) CommitQuery(
+	ctx context.Context,
+	user structs.User,
+	queryID uuid6.UUID,
+) (uuid6.UUID, *structs.QTable, error) {
+	preview, err := s.previews.take(queryID, user.ID)
 	if err != nil {
-		return uuid6.Nil(), nil, fmt.Errorf("collect rowsL %w", err)
+		return uuid6.Nil(), nil, fmt.Errorf("take preview: %w", err)
 	}
 
-	cols := just.SliceMap(res.FieldDescriptions(), func(fd pgconn.FieldDescription) string {
-		return fd.Name
-	})
-
-	qTable := structs.QTable{
-		Headers: cols,
-		Rows: just.SliceMap(rows, func(row []any) []string {
-			return just.SliceMap(row, adaptPgType)
-		}),
+	if err := s.finishPreview(ctx, preview, previewOutcomeCommitted); err != nil {
+		return uuid6.Nil(), nil, err
 	}
 
-	meta := structs.QMeta{
-		ExecutionTimeMS:    time.Since(fullRoundTripStartedAt).Milliseconds(),
-		ParsingTimeMS:      parsingDuration.Milliseconds(),
-		NetworkRoundTripMS: networkRoundTripDuration.Milliseconds(),
-		RowsCount:          len(rows),
-		ColumnsCount:       len(cols),
-		VectorsCount:       len(vectors),
-	}
+	return queryID, &preview.table, nil
+}
 
-	buf, err := json.Marshal(storedQueryResultPayload{
-		Table: qTable,
-		Meta:  meta,
-	})
+// RollbackQuery discards changes of a previewed query. The rollback is stored in history.
+func (s *Service) RollbackQuery(ctx context.Context, user structs.User, queryID uuid6.UUID) error {
+	preview, err := s.previews.take(queryID, user.ID)
 	if err != nil {
-		return uuid6.Nil(), nil, fmt.Errorf("marshal qtable: %w", err)
+		return fmt.Errorf("take preview: %w", err)
 	}
 
-	req := storage.InsertQueryResultsReq{
-		ID:        uuid6.New(),
-		UserID:    user.ID,
-		TargetID:  srvID,
-		CreatedAt: queryStartedAt,
-		Query:     query,
-		Response:  buf,
-	}
-	if err := s.opts.storage.InsertQueryResults(s.opts.storage.Conn(ctx), req); err != nil {
-		return uuid6.Nil(), nil, fmt.Errorf("insert query results: %w", err)
+	return s.finishPreview(ctx, preview, previewOutcomeRolledBack)
+}
+
+// CancelQuery stops a running query. Users can cancel their own queries, admins can cancel any query.
+func (s *Service) CancelQuery(_ context.Context, user structs.User, queryID uuid6.UUID) error {
+	if err := s.running.cancel(queryID, user.ID, user.Role == config.RoleAdmin); err != nil {
+		return fmt.Errorf("cancel query: %w", err)
 	}
 
-	return req.ID, &qTable, nil
+	s.opts.logger.Info("query cancelled",
+		slog.String("query_id", queryID.S()),
+		slog.String("user", user.ID.S()))
+
+	return nil
 }
 
 func (s *Service) InitOIDC(_ context.Context) (string, string, error) { //nolint:gocritic
@@ -442,8 +607,8 @@ func (s *Service) GetQueryResults(ctx context.Context, user structs.User, qid uu
 		return nil, fmt.Errorf("user does not have access to this query result: %w", ErrNotFound)
 	}
 
-	var payload storedQueryResultPayload
-	if err := json.Unmarshal(res.Response, &payload); err != nil {
+	payload, err := parseStoredQueryResult(res.Response)
+	if err != nil {
 		return nil, fmt.Errorf("unmarshal query results: %w", err)
 	}
 
@@ -453,6 +618,8 @@ func (s *Service) GetQueryResults(ctx context.Context, user structs.User, qid uu
 		TargetID:  res.TargetID.S(),
 		CreatedAt: res.CreatedAt,
 		Query:     res.Query,
+		Status:    structs.QueryStatus(res.Status),
+		Error:     res.Error,
 		QTable:    payload.Table,
 		Meta:      payload.Meta,
 	}, nil
@@ -550,8 +717,7 @@ func (s *Service) ListRecentQueries(ctx context.Context, uid config.UserID, limi
 
 	out := make([]structs.Query, 0, len(items))
 	for _, item := range items {
-		var payload storedQueryResultPayload
-		if err := json.Unmarshal(item.Response, &payload); err != nil {
+		if _, err := parseStoredQueryResult(item.Response); err != nil {
 			continue
 		}
 
@@ -607,6 +773,243 @@ func (s *Service) ListAdminRequests(
 	return out, hasNext, nil
 }
 
+// ListPendingApprovals returns queries that wait for a review, oldest first.
+func (s *Service) ListPendingApprovals(ctx context.Context, user structs.User, limit int64) ([]structs.QueryApproval, error) {
+	if user.Role != config.RoleAdmin {
+		return nil, ErrForbidden
+	}
+
+	if limit <= 0 {
+		limit = 50
+	}
+
+	items, err := s.opts.storage.ListQueryApprovalsByStatus(s.opts.storage.Conn(ctx), structs.ApprovalStatusPending, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list pending approvals: %w", err)
+	}
+
+	return just.SliceMap(items, adaptQueryApproval), nil
+}
+
+// ListUserApprovals returns approval requests created by the user, newest first.
+func (s *Service) ListUserApprovals(ctx context.Context, uid config.UserID, limit int64) ([]structs.QueryApproval, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+
+	items, err := s.opts.storage.ListQueryApprovalsByUser(s.opts.storage.Conn(ctx), uid, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list user approvals: %w", err)
+	}
+
+	return just.SliceMap(items, adaptQueryApproval), nil
+}
+
+// ApproveQuery executes a pending query on behalf of its author. Results are stored as the author's query results.
+func (s *Service) ApproveQuery(
+	ctx context.Context,
+	user structs.User,
+	approvalID uuid6.UUID,
+	comment string,
+) (uuid6.UUID, *structs.QTable, error) {
+	startedAt := time.Now()
+
+	approval, target, err := s.getApprovalForReview(ctx, user, approvalID)
+	if err != nil {
+		return uuid6.Nil(), nil, err
+	}
+
+	// NOTE: the stored query was validated against the author's policy at submission. Config, policy and
+	//  break-glass grants may have changed since then, so the query is checked again with the author's current
+	//  subjects before it runs.
+	author := approvalAuthor(*approval)
+	prepared, err := s.prepareQuery(ctx, author, target.ID, approval.Query)
+	if err != nil {
+		return uuid6.Nil(), nil, fmt.Errorf("recheck author access: %w", err)
+	}
+
+	reviewReq := storage.ReviewQueryApprovalReq{
+		ID:         approval.ID,
+		Status:     structs.ApprovalStatusApproved,
+		ReviewerID: user.ID,
+		Comment:    strings.TrimSpace(comment),
+		ReviewedAt: startedAt,
+	}
+	if err := s.opts.storage.ReviewQueryApproval(s.opts.storage.Conn(ctx), reviewReq); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return uuid6.Nil(), nil, fmt.Errorf("approval was already reviewed: %w", ErrConflict)
+		}
+
+		return uuid6.Nil(), nil, fmt.Errorf("approve query: %w", err)
+	}
+
+	queryID, qTable, execErr := s.execQuery(ctx, prepared.execReq(uuid6.New(), author, approval.Query, startedAt, QueryRoutePrimary))
+
+	finishReq := storage.FinishQueryApprovalReq{
+		ID:            approval.ID,
+		Status:        just.If(execErr == nil, structs.ApprovalStatusExecuted, structs.ApprovalStatusFailed),
+		QueryResultID: queryID,
+		Error:         "",
+	}
+	if execErr != nil {
+		finishReq.Error = execErr.Error()
+	}
+	if err := s.opts.storage.FinishQueryApproval(s.opts.storage.Conn(ctx), finishReq); err != nil {
+		s.opts.logger.Error("finish query approval",
+			slog.String("approval_id", approval.ID.S()),
+			slog.String("error", err.Error()))
+	}
+
+	if execErr != nil {
+		return uuid6.Nil(), nil, fmt.Errorf("exec approved query: %w", execErr)
+	}
+
+	return queryID, qTable, nil
+}
+
+// RejectQuery closes a pending query without executing it.
+func (s *Service) RejectQuery(ctx context.Context, user structs.User, approvalID uuid6.UUID, comment string) error {
+	approval, _, err := s.getApprovalForReview(ctx, user, approvalID)
+	if err != nil {
+		return err
+	}
+
+	reviewReq := storage.ReviewQueryApprovalReq{
+		ID:         approval.ID,
+		Status:     structs.ApprovalStatusRejected,
+		ReviewerID: user.ID,
+		Comment:    strings.TrimSpace(comment),
+		ReviewedAt: time.Now(),
+	}
+	if err := s.opts.storage.ReviewQueryApproval(s.opts.storage.Conn(ctx), reviewReq); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return fmt.Errorf("approval was already reviewed: %w", ErrConflict)
+		}
+
+		return fmt.Errorf("reject query: %w", err)
+	}
+
+	return nil
+}
+
+// RequestBreakGlass grants the user an extra subject for the target until ttl passes. Policy decides what this
+// subject allows.
+func (s *Service) RequestBreakGlass(
+	ctx context.Context,
+	user structs.User,
+	targetID config.TargetID,
+	reason string,
+	ttl time.Duration,
+) (*structs.BreakGlassGrant, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, errors.New("reason is required") //nolint:err113
+	}
+
+	if ttl <= 0 {
+		return nil, errors.New("duration must be positive") //nolint:err113
+	}
+
+	if ttl > s.opts.breakGlassMaxTTL {
+		return nil, fmt.Errorf("duration must not exceed %s: %w", s.opts.breakGlassMaxTTL, ErrForbidden)
+	}
+
+	if !slices.ContainsFunc(s.opts.targets, func(target config.Target) bool { return target.ID == targetID }) {
+		return nil, fmt.Errorf("target not found: %w", ErrNotFound)
+	}
+
+	now := time.Now()
+	req := storage.InsertBreakGlassGrantReq{
+		ID:        uuid6.New(),
+		UserID:    user.ID,
+		TargetID:  targetID,
+		Reason:    reason,
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	}
+	err := s.opts.storage.DoInTx(ctx, func(conn qrm.DB) error {
+		if err := s.opts.storage.InsertBreakGlassGrant(conn, req); err != nil {
+			return fmt.Errorf("insert grant: %w", err)
+		}
+
+		details := map[string]string{
+			"grant_id":   req.ID.S(),
+			"reason":     reason,
+			"expires_at": req.ExpiresAt.Format(time.RFC3339),
+		}
+
+		return s.writeAudit(ctx, conn, user.ID, auditActionBreakGlassGrant, targetID, details)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("request break glass: %w", err)
+	}
+
+	s.opts.logger.Warn("break glass granted",
+		slog.String("grant_id", req.ID.S()),
+		slog.String("user", user.ID.S()),
+		slog.String("target", targetID.S()),
+		slog.Time("expires_at", req.ExpiresAt))
+
+	return &structs.BreakGlassGrant{
+		ID:        req.ID.S(),
+		UserID:    req.UserID,
+		TargetID:  req.TargetID,
+		Reason:    req.Reason,
+		CreatedAt: req.CreatedAt,
+		ExpiresAt: req.ExpiresAt,
+	}, nil
+}
+
+// ListBreakGlassGrants returns active grants. Admins see grants of all users, others see only their own.
+func (s *Service) ListBreakGlassGrants(ctx context.Context, user structs.User) ([]structs.BreakGlassGrant, error) {
+	uid := just.If(user.Role == config.RoleAdmin, config.UserID(""), user.ID)
+
+	items, err := s.activeGrants(ctx, uid, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("list active grants: %w", err)
+	}
+
+	return just.SliceMap(items, adaptBreakGlassGrant), nil
+}
+
+// RevokeBreakGlass ends an active grant before it expires.
+func (s *Service) RevokeBreakGlass(ctx context.Context, user structs.User, grantID uuid6.UUID) error {
+	if user.Role != config.RoleAdmin {
+		return ErrForbidden
+	}
+
+	grant, err := s.opts.storage.GetBreakGlassGrantByID(s.opts.storage.Conn(ctx), grantID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return fmt.Errorf("unknown grant id: %w", ErrNotFound)
+		}
+
+		return fmt.Errorf("get grant: %w", err)
+	}
+
+	err = s.opts.storage.DoInTx(ctx, func(conn qrm.DB) error {
+		if err := s.opts.storage.RevokeBreakGlassGrant(conn, grant.ID, user.ID, time.Now()); err != nil {
+			if errors.Is(err, storage.ErrNotFound) {
+				return fmt.Errorf("grant is not active: %w", ErrConflict)
+			}
+
+			return fmt.Errorf("revoke grant: %w", err)
+		}
+
+		details := map[string]string{
+			"grant_id": grant.ID.S(),
+			"user_id":  grant.UserID.S(),
+		}
+
+		return s.writeAudit(ctx, conn, user.ID, auditActionBreakGlassRevoke, grant.TargetID, details)
+	})
+	if err != nil {
+		return fmt.Errorf("revoke break glass: %w", err)
+	}
+
+	return nil
+}
+
 func resolveUserRole(claims map[string]json.RawMessage, roleClaim string, roleMapping map[string]config.Role) (config.Role, error) {
 	claimValues, err := getClaimValues(claims, roleClaim)
 	if err != nil {
@@ -643,6 +1046,19 @@ func userSubjects(user structs.User) []string {
 	}
 }
 
+// grantSubjects extends static subjects of the user with one subject per target of active break-glass grants.
+func grantSubjects(user structs.User, grants []storage.BreakGlassGrant) []string {
+	subjects := userSubjects(user)
+	for _, grant := range grants {
+		subject := opa.SubjectBreakGlass(grant.TargetID.S())
+		if !slices.Contains(subjects, subject) {
+			subjects = append(subjects, subject)
+		}
+	}
+
+	return subjects
+}
+
 func canReadQueryResults(user structs.User, ownerID config.UserID) bool {
 	return user.Role == config.RoleAdmin || user.ID == ownerID
 }
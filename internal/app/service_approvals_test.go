@@ -0,0 +1,200 @@
+// Database Gateway provides access to servers with ACL for safe and restricted database interactions.
+// Copyright (C) 2024  Kirill Zhuravlev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package app //nolint:testpackage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kazhuravlev/database-gateway/internal/config"
+	"github.com/kazhuravlev/database-gateway/internal/storage"
+	"github.com/kazhuravlev/database-gateway/internal/structs"
+	"github.com/kazhuravlev/database-gateway/internal/uuid6"
+	"github.com/kazhuravlev/database-gateway/internal/validator"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequiresApproval(t *testing.T) {
+	t.Parallel()
+
+	selectVec := validator.Vec{Op: config.OpSelect, Tbl: "clients", Cols: []string{"id"}}
+	updateVec := validator.Vec{Op: config.OpUpdate, Tbl: "clients", Cols: []string{"name"}}
+
+	testCases := []struct {
+		name    string
+		require bool
+		vectors []validator.Vec
+		want    bool
+	}{
+		{
+			name:    "write on target without approvals",
+			require: false,
+			vectors: []validator.Vec{updateVec},
+			want:    false,
+		},
+		{
+			name:    "read on target with approvals",
+			require: true,
+			vectors: []validator.Vec{selectVec},
+			want:    false,
+		},
+		{
+			name:    "write on target with approvals",
+			require: true,
+			vectors: []validator.Vec{updateVec},
+			want:    true,
+		},
+		{
+			name:    "read mixed with write",
+			require: true,
+			vectors: []validator.Vec{selectVec, updateVec},
+			want:    true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			target := config.Target{ID: "pg-prod", RequireApproval: tc.require} //nolint:exhaustruct
+			require.Equal(t, tc.want, requiresApproval(target, tc.vectors))
+		})
+	}
+}
+
+func TestCanReviewApproval(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		user     structs.User
+		authorID config.UserID
+		want     bool
+	}{
+		{
+			name: "admin reviews another users query",
+			user: structs.User{
+				ID:       config.UserID("admin@example.com"),
+				Username: "",
+				Role:     config.RoleAdmin,
+			},
+			authorID: config.UserID("alice@example.com"),
+			want:     true,
+		},
+		{
+			name: "admin cannot review own query",
+			user: structs.User{
+				ID:       config.UserID("admin@example.com"),
+				Username: "",
+				Role:     config.RoleAdmin,
+			},
+			authorID: config.UserID("admin@example.com"),
+			want:     false,
+		},
+		{
+			name: "user cannot review",
+			user: structs.User{
+				ID:       config.UserID("bob@example.com"),
+				Username: "",
+				Role:     config.RoleUser,
+			},
+			authorID: config.UserID("alice@example.com"),
+			want:     false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, tc.want, canReviewApproval(tc.user, tc.authorID))
+		})
+	}
+}
+
+const approvalPolicy = `
+package gateway
+
+default allow_target := false
+default allow_query := false
+
+allow_target if {
+	input.target == "pg-prod"
+}
+
+allow_query if {
+	"role:user" in input.subjects
+	input.op == "update"
+}
+`
+
+func TestApprovalRecheckUsesAuthor(t *testing.T) {
+	t.Parallel()
+
+	target := config.Target{ //nolint:exhaustruct
+		ID:              "pg-prod",
+		DefaultSchema:   "public",
+		RequireApproval: true,
+		Tables:          []config.TargetTable{{Table: "public.clients", Fields: []string{"id", "name"}}},
+		RoleLimits: map[config.Role]config.ResultLimits{
+			config.RoleUser:  {MaxRows: 10, MaxBytes: 0},
+			config.RoleAdmin: {MaxRows: 1000, MaxBytes: 0},
+		},
+	}
+
+	svc := &Service{ //nolint:exhaustruct
+		opts: Options{ //nolint:exhaustruct
+			targets:    []config.Target{target},
+			authorizer: mustAuthorizer(t, approvalPolicy),
+		},
+		activeGrants: noGrants,
+	}
+
+	query := `update clients set name = 'x' where id = 1`
+
+	t.Run("author policy is applied", func(t *testing.T) {
+		t.Parallel()
+
+		approval := storage.QueryApproval{ //nolint:exhaustruct
+			UserID:   "bob@example.com",
+			UserName: "bob",
+			UserRole: config.RoleAdmin,
+		}
+
+		_, err := svc.prepareQuery(context.Background(), approvalAuthor(approval), target.ID, query)
+		require.ErrorIs(t, err, validator.ErrAccessDenied)
+	})
+
+	t.Run("author limits are applied", func(t *testing.T) {
+		t.Parallel()
+
+		author := approvalAuthor(storage.QueryApproval{ //nolint:exhaustruct
+			UserID:   "alice@example.com",
+			UserName: "alice",
+			UserRole: config.RoleUser,
+		})
+
+		prepared, err := svc.prepareQuery(context.Background(), author, target.ID, query)
+		require.NoError(t, err)
+
+		req := prepared.execReq(uuid6.New(), author, query, time.Now(), QueryRoutePrimary)
+		require.Equal(t, author.ID, req.UserID)
+		require.Equal(t, config.ResultLimits{MaxRows: 10, MaxBytes: 0}, req.Limits)
+		require.False(t, req.UseReplica)
+	})
+}
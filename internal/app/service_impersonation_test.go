@@ -0,0 +1,156 @@
+// Database Gateway provides access to servers with ACL for safe and restricted database interactions.
+// Copyright (C) 2024  Kirill Zhuravlev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package app //nolint:testpackage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/kazhuravlev/database-gateway/internal/config"
+	"github.com/kazhuravlev/database-gateway/internal/storage"
+	"github.com/kazhuravlev/database-gateway/internal/structs"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingExecer keeps statements instead of sending them to the target.
+type recordingExecer struct {
+	statements []string
+}
+
+func (e *recordingExecer) Exec(_ context.Context, sql string, _ ...any) (pgconn.CommandTag, error) {
+	e.statements = append(e.statements, sql)
+
+	return pgconn.CommandTag{}, nil
+}
+
+func TestImpersonatedRole(t *testing.T) {
+	t.Parallel()
+
+	impersonation := &config.Impersonation{
+		Users: map[config.UserID]string{"alice@example.com": "alice"},
+		Roles: map[config.Role]string{config.RoleUser: "dbgw_reader"},
+	}
+
+	testCases := []struct {
+		name          string
+		impersonation *config.Impersonation
+		user          structs.User
+		want          string
+		wantErr       error
+	}{
+		{
+			name:          "impersonation disabled",
+			impersonation: nil,
+			user:          structs.User{ID: "bob@example.com", Username: "", Role: config.RoleUser},
+			want:          "",
+			wantErr:       nil,
+		},
+		{
+			name:          "user mapping wins",
+			impersonation: impersonation,
+			user:          structs.User{ID: "alice@example.com", Username: "", Role: config.RoleUser},
+			want:          "alice",
+			wantErr:       nil,
+		},
+		{
+			name:          "role mapping",
+			impersonation: impersonation,
+			user:          structs.User{ID: "bob@example.com", Username: "", Role: config.RoleUser},
+			want:          "dbgw_reader",
+			wantErr:       nil,
+		},
+		{
+			name:          "unmapped user is forbidden",
+			impersonation: impersonation,
+			user:          structs.User{ID: "root@example.com", Username: "", Role: config.RoleAdmin},
+			want:          "",
+			wantErr:       ErrForbidden,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			target := config.Target{ID: "pg-prod", Impersonation: tc.impersonation} //nolint:exhaustruct
+
+			got, err := impersonatedRole(target, tc.user)
+			require.ErrorIs(t, err, tc.wantErr)
+			require.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestSetLocalRole(t *testing.T) {
+	t.Parallel()
+
+	t.Run("empty role keeps connection role", func(t *testing.T) {
+		t.Parallel()
+
+		var tx recordingExecer
+		require.NoError(t, setLocalRole(context.Background(), &tx, ""))
+		require.Empty(t, tx.statements)
+	})
+
+	t.Run("role is quoted", func(t *testing.T) {
+		t.Parallel()
+
+		var tx recordingExecer
+		require.NoError(t, setLocalRole(context.Background(), &tx, `alice"; drop table clients; --`))
+		require.Equal(t, []string{`set local role "alice""; drop table clients; --"`}, tx.statements)
+	})
+}
+
+func TestApprovalAuthorRole(t *testing.T) {
+	t.Parallel()
+
+	target := config.Target{ //nolint:exhaustruct
+		ID: "pg-prod",
+		Impersonation: &config.Impersonation{
+			Users: map[config.UserID]string{"alice@example.com": "alice"},
+			Roles: map[config.Role]string{config.RoleAdmin: "dbgw_admin"},
+		},
+	}
+
+	t.Run("author role is used", func(t *testing.T) {
+		t.Parallel()
+
+		approval := storage.QueryApproval{ //nolint:exhaustruct
+			UserID:   "bob@example.com",
+			UserName: "bob",
+			UserRole: config.RoleAdmin,
+		}
+
+		got, err := impersonatedRole(target, approvalAuthor(approval))
+		require.NoError(t, err)
+		require.Equal(t, "dbgw_admin", got)
+	})
+
+	t.Run("unmapped author is forbidden", func(t *testing.T) {
+		t.Parallel()
+
+		approval := storage.QueryApproval{ //nolint:exhaustruct
+			UserID:   "bob@example.com",
+			UserName: "bob",
+			UserRole: config.RoleUser,
+		}
+
+		_, err := impersonatedRole(target, approvalAuthor(approval))
+		require.ErrorIs(t, err, ErrForbidden)
+	})
+}
@@ -0,0 +1,175 @@
+// Database Gateway provides access to servers with ACL for safe and restricted database interactions.
+// Copyright (C) 2024  Kirill Zhuravlev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package app //nolint:testpackage
+
+import (
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/kazhuravlev/database-gateway/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResultLimits(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name       string
+		limits     config.ResultLimits
+		roleLimits map[config.Role]config.ResultLimits
+		role       config.Role
+		want       config.ResultLimits
+	}{
+		{
+			name:       "no limits",
+			limits:     config.ResultLimits{MaxRows: 0, MaxBytes: 0},
+			roleLimits: nil,
+			role:       config.RoleUser,
+			want:       config.ResultLimits{MaxRows: 0, MaxBytes: 0},
+		},
+		{
+			name:       "target limits only",
+			limits:     config.ResultLimits{MaxRows: 100, MaxBytes: 1024},
+			roleLimits: nil,
+			role:       config.RoleUser,
+			want:       config.ResultLimits{MaxRows: 100, MaxBytes: 1024},
+		},
+		{
+			name:   "role limits only",
+			limits: config.ResultLimits{MaxRows: 0, MaxBytes: 0},
+			roleLimits: map[config.Role]config.ResultLimits{
+				config.RoleUser: {MaxRows: 10, MaxBytes: 0},
+			},
+			role: config.RoleUser,
+			want: config.ResultLimits{MaxRows: 10, MaxBytes: 0},
+		},
+		{
+			name:   "stricter value wins",
+			limits: config.ResultLimits{MaxRows: 100, MaxBytes: 1024},
+			roleLimits: map[config.Role]config.ResultLimits{
+				config.RoleUser: {MaxRows: 500, MaxBytes: 512},
+			},
+			role: config.RoleUser,
+			want: config.ResultLimits{MaxRows: 100, MaxBytes: 512},
+		},
+		{
+			name:   "limits of other role are ignored",
+			limits: config.ResultLimits{MaxRows: 100, MaxBytes: 0},
+			roleLimits: map[config.Role]config.ResultLimits{
+				config.RoleAdmin: {MaxRows: 10, MaxBytes: 0},
+			},
+			role: config.RoleUser,
+			want: config.ResultLimits{MaxRows: 100, MaxBytes: 0},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			target := config.Target{ID: "pg-prod", Limits: tc.limits, RoleLimits: tc.roleLimits} //nolint:exhaustruct
+			require.Equal(t, tc.want, resultLimits(target, tc.role))
+		})
+	}
+}
+
+// stubRows is a pgx.Rows over a fixed set of text rows. It counts rows that were read.
+type stubRows struct {
+	values []string
+	pos    int
+}
+
+func (r *stubRows) Close()                                       {}
+func (r *stubRows) Err() error                                   { return nil }
+func (r *stubRows) CommandTag() pgconn.CommandTag                { return pgconn.CommandTag{} }
+func (r *stubRows) FieldDescriptions() []pgconn.FieldDescription { return nil }
+func (r *stubRows) Scan(...any) error                            { return nil }
+func (r *stubRows) RawValues() [][]byte                          { return nil }
+func (r *stubRows) Conn() *pgx.Conn                              { return nil }
+
+func (r *stubRows) Next() bool {
+	if r.pos >= len(r.values) {
+		return false
+	}
+	r.pos++
+
+	return true
+}
+
+func (r *stubRows) Values() ([]any, error) {
+	return []any{r.values[r.pos-1]}, nil
+}
+
+func TestResultCollector(t *testing.T) {
+	t.Parallel()
+
+	fields := []pgconn.FieldDescription{{Name: "name", DataTypeOID: pgtype.TextOID}} //nolint:exhaustruct
+
+	testCases := []struct {
+		name          string
+		limits        config.ResultLimits
+		wantRows      int
+		wantRead      int
+		wantTruncated bool
+	}{
+		{
+			name:          "no limits",
+			limits:        config.ResultLimits{MaxRows: 0, MaxBytes: 0},
+			wantRows:      5,
+			wantRead:      5,
+			wantTruncated: false,
+		},
+		{
+			name:          "row limit stops reading",
+			limits:        config.ResultLimits{MaxRows: 2, MaxBytes: 0},
+			wantRows:      2,
+			wantRead:      3,
+			wantTruncated: true,
+		},
+		{
+			name:          "byte limit stops reading",
+			limits:        config.ResultLimits{MaxRows: 0, MaxBytes: 10},
+			wantRows:      2,
+			wantRead:      3,
+			wantTruncated: true,
+		},
+		{
+			name:          "exact row limit",
+			limits:        config.ResultLimits{MaxRows: 5, MaxBytes: 0},
+			wantRows:      5,
+			wantRead:      5,
+			wantTruncated: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			rows := &stubRows{values: []string{"alice", "bobby", "carol", "dave1", "emma1"}, pos: 0}
+			collector := resultCollector{limits: tc.limits, fields: fields} //nolint:exhaustruct
+
+			read, err := collector.read(rows)
+			require.NoError(t, err)
+			require.Equal(t, tc.wantRead, read)
+			require.Len(t, collector.rows, tc.wantRows)
+			require.Equal(t, tc.wantTruncated, collector.truncated)
+		})
+	}
+}
@@ -0,0 +1,135 @@
+// Database Gateway provides access to servers with ACL for safe and restricted database interactions.
+// Copyright (C) 2024  Kirill Zhuravlev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package app //nolint:testpackage
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/kazhuravlev/database-gateway/internal/config"
+	"github.com/kazhuravlev/database-gateway/internal/structs"
+	"github.com/kazhuravlev/database-gateway/internal/validator"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSupportLookupByKeyPolicy(t *testing.T) {
+	t.Parallel()
+
+	module, err := os.ReadFile("../../example/opa/10_support_lookup_by_key.rego")
+	require.NoError(t, err)
+
+	target := config.Target{ //nolint:exhaustruct
+		ID:            "taxi-prod",
+		DefaultSchema: "public",
+		Tables: []config.TargetTable{{
+			Table:  "public.clients",
+			Fields: []string{"id", "name", "phone", "email", "referrer_id"},
+		}},
+	}
+
+	svc := &Service{ //nolint:exhaustruct
+		opts: Options{ //nolint:exhaustruct
+			targets:    []config.Target{target},
+			authorizer: mustAuthorizer(t, string(module)),
+		},
+		activeGrants: noGrants,
+	}
+
+	user := structs.User{
+		ID:       config.UserID("support@example.com"),
+		Username: "support",
+		Role:     config.Role("support"),
+	}
+
+	testCases := []struct {
+		name    string
+		query   string
+		allowed bool
+	}{
+		{
+			name:    "lookup by id",
+			query:   `select phone from clients where id = 1`,
+			allowed: true,
+		},
+		{
+			name:    "lookup without id",
+			query:   `select phone from clients where name = 'x'`,
+			allowed: false,
+		},
+		{
+			name:    "union branch without id",
+			query:   `select phone from clients where id = 1 union select phone from clients`,
+			allowed: false,
+		},
+		{
+			name:    "union with id in every branch",
+			query:   `select phone from clients where id = 1 union select phone from clients where id = 2`,
+			allowed: true,
+		},
+		{
+			name:    "union sorted by phone",
+			query:   `select phone from clients where id = 1 union select phone from clients where id = 2 order by phone`,
+			allowed: false,
+		},
+		{
+			name:    "subquery in select list without id",
+			query:   `select (select max(phone) from clients), id from clients where id = 1`,
+			allowed: false,
+		},
+		{
+			name:    "correlated subquery filters by id",
+			query:   `select c.phone, (select r.phone from clients r where r.id = c.referrer_id) from clients c where c.id = 1`,
+			allowed: true,
+		},
+		{
+			name:    "cte filtered by id from outside",
+			query:   `with c as (select id, phone from clients) select phone from c where id = 1`,
+			allowed: true,
+		},
+		{
+			name:    "cte sorted by phone from outside",
+			query:   `with c as (select id, phone from clients where id = 1) select phone from c order by phone`,
+			allowed: false,
+		},
+		{
+			name:    "derived table sorted by email from outside",
+			query:   `select email from (select id, email from clients where id = 1) c order by email`,
+			allowed: false,
+		},
+		{
+			name:    "derived table filtered by id from outside",
+			query:   `select email from (select id, email from clients) c where c.id = 1`,
+			allowed: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := svc.prepareQuery(context.Background(), user, target.ID, tc.query)
+			if tc.allowed {
+				require.NoError(t, err)
+
+				return
+			}
+
+			require.ErrorIs(t, err, validator.ErrAccessDenied)
+		})
+	}
+}
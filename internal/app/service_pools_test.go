@@ -0,0 +1,136 @@
+// Database Gateway provides access to servers with ACL for safe and restricted database interactions.
+// Copyright (C) 2024  Kirill Zhuravlev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package app //nolint:testpackage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kazhuravlev/database-gateway/internal/config"
+	"github.com/kazhuravlev/database-gateway/internal/structs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildPoolConfig(t *testing.T) {
+	t.Parallel()
+
+	connection := config.Connection{
+		Host:              "localhost",
+		Port:              5432,
+		User:              "pg01",
+		Password:          "pg01",
+		DB:                "pg01",
+		TLS:               config.TLSConfig{Mode: "", RootCert: "", ClientCert: "", ClientKey: "", ServerName: ""},
+		MaxPoolSize:       0,
+		MinPoolSize:       0,
+		MaxConnLifetime:   0,
+		MaxConnIdleTime:   0,
+		HealthCheckPeriod: 0,
+		ApplicationName:   "",
+		SSHTunnel:         nil,
+	}
+
+	t.Run("defaults", func(t *testing.T) {
+		t.Parallel()
+
+		defaults, err := buildPoolConfig(config.Target{Connection: connection}, nil) //nolint:exhaustruct
+		require.NoError(t, err)
+		require.Equal(t, "pg01", defaults.ConnConfig.Database)
+		require.Equal(t, defaultApplicationName, defaults.ConnConfig.RuntimeParams["application_name"])
+		require.NotContains(t, defaults.ConnConfig.RuntimeParams, "statement_timeout")
+		require.Positive(t, defaults.MaxConns)
+		require.Zero(t, defaults.MinConns)
+	})
+
+	t.Run("settings from config", func(t *testing.T) {
+		t.Parallel()
+
+		conn := connection
+		conn.MaxPoolSize = 8
+		conn.MinPoolSize = 2
+		conn.MaxConnLifetime = config.Duration(time.Hour)
+		conn.MaxConnIdleTime = config.Duration(5 * time.Minute)
+		conn.HealthCheckPeriod = config.Duration(30 * time.Second)
+		conn.ApplicationName = "dbgw-prod"
+
+		target := config.Target{ //nolint:exhaustruct
+			Connection:       conn,
+			StatementTimeout: config.Duration(15 * time.Second),
+		}
+
+		poolCfg, err := buildPoolConfig(target, nil)
+		require.NoError(t, err)
+		require.EqualValues(t, 8, poolCfg.MaxConns)
+		require.EqualValues(t, 2, poolCfg.MinConns)
+		require.Equal(t, time.Hour, poolCfg.MaxConnLifetime)
+		require.Equal(t, 5*time.Minute, poolCfg.MaxConnIdleTime)
+		require.Equal(t, 30*time.Second, poolCfg.HealthCheckPeriod)
+		require.Equal(t, "dbgw-prod", poolCfg.ConnConfig.RuntimeParams["application_name"])
+		require.Equal(t, "15000", poolCfg.ConnConfig.RuntimeParams["statement_timeout"])
+		require.Equal(t, "pg01", poolCfg.ConnConfig.Password)
+		require.Nil(t, poolCfg.BeforeConnect)
+	})
+
+	t.Run("password reference is resolved on connect", func(t *testing.T) {
+		t.Parallel()
+
+		filename := filepath.Join(t.TempDir(), "password")
+		require.NoError(t, os.WriteFile(filename, []byte("first\n"), 0o600))
+
+		conn := connection
+		conn.Password = "file:" + filename
+
+		poolCfg, err := buildPoolConfig(config.Target{Connection: conn}, config.NewSecrets()) //nolint:exhaustruct
+		require.NoError(t, err)
+		require.Empty(t, poolCfg.ConnConfig.Password)
+
+		connCfg := poolCfg.ConnConfig.Copy()
+		require.NoError(t, poolCfg.BeforeConnect(context.Background(), connCfg))
+		require.Equal(t, "first", connCfg.Password)
+
+		require.NoError(t, os.WriteFile(filename, []byte("rotated\n"), 0o600))
+		require.NoError(t, poolCfg.BeforeConnect(context.Background(), connCfg))
+		require.Equal(t, "rotated", connCfg.Password)
+	})
+}
+
+func TestHealthRegistry(t *testing.T) {
+	t.Parallel()
+
+	registry := newHealthRegistry()
+	require.Equal(t, structs.TargetStatusUnknown, registry.get("pg-1").Status)
+
+	health := unknownHealth()
+	health.Status = structs.TargetStatusDown
+	health.Error = "ping: connection refused"
+	registry.set("pg-1", health)
+
+	require.Equal(t, health, registry.get("pg-1"))
+	require.Equal(t, structs.TargetStatusUnknown, registry.get("pg-2").Status)
+}
+
+func TestAdaptPoolStatsWithoutPool(t *testing.T) {
+	t.Parallel()
+
+	stats := adaptPoolStats("pg-1", nil)
+	require.Equal(t, config.TargetID("pg-1"), stats.TargetID)
+	require.False(t, stats.Connected)
+	require.Zero(t, stats.TotalConns)
+}
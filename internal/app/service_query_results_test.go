@@ -17,6 +17,7 @@
 package app //nolint:testpackage
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/kazhuravlev/database-gateway/internal/config"
@@ -73,3 +74,32 @@ func TestCanReadQueryResults(t *testing.T) {
 		})
 	}
 }
+
+func TestParseStoredQueryResult(t *testing.T) {
+	t.Parallel()
+
+	t.Run("typed cells keep precision", func(t *testing.T) {
+		t.Parallel()
+
+		payload, err := parseStoredQueryResult([]byte(`{
+			"table": {
+				"headers": ["id", "name"],
+				"columns": [{"name": "id", "type_oid": 20, "type_name": "bigint", "nullable": false}],
+				"rows": [[9223372036854775807, null]]
+			},
+			"meta": {"rows_count": 1}
+		}`))
+		require.NoError(t, err)
+		require.Equal(t, [][]any{{json.Number("9223372036854775807"), nil}}, payload.Table.Rows)
+		require.Equal(t, "bigint", payload.Table.Columns[0].TypeName)
+	})
+
+	t.Run("results stored as strings", func(t *testing.T) {
+		t.Parallel()
+
+		payload, err := parseStoredQueryResult([]byte(`{"table": {"headers": ["id"], "rows": [["1"]]}, "meta": {}}`))
+		require.NoError(t, err)
+		require.Equal(t, [][]any{{"1"}}, payload.Table.Rows)
+		require.Empty(t, payload.Table.Columns)
+	})
+}
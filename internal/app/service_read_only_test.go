@@ -0,0 +1,73 @@
+// Database Gateway provides access to servers with ACL for safe and restricted database interactions.
+// Copyright (C) 2024  Kirill Zhuravlev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package app //nolint:testpackage
+
+import (
+	"testing"
+
+	"github.com/kazhuravlev/database-gateway/internal/config"
+	"github.com/kazhuravlev/database-gateway/internal/validator"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadOnlyQuery(t *testing.T) {
+	t.Parallel()
+
+	selectVec := validator.Vec{Op: config.OpSelect, Tbl: "clients", Cols: []string{"id"}}
+	updateVec := validator.Vec{Op: config.OpUpdate, Tbl: "clients", Cols: []string{"name"}}
+
+	testCases := []struct {
+		name     string
+		readOnly bool
+		vectors  []validator.Vec
+		want     bool
+	}{
+		{
+			name:     "select",
+			readOnly: false,
+			vectors:  []validator.Vec{selectVec},
+			want:     true,
+		},
+		{
+			name:     "write",
+			readOnly: false,
+			vectors:  []validator.Vec{updateVec},
+			want:     false,
+		},
+		{
+			name:     "select mixed with write",
+			readOnly: false,
+			vectors:  []validator.Vec{selectVec, updateVec},
+			want:     false,
+		},
+		{
+			name:     "write on read only target",
+			readOnly: true,
+			vectors:  []validator.Vec{updateVec},
+			want:     true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			target := config.Target{ID: "pg-prod", ReadOnly: tc.readOnly} //nolint:exhaustruct
+			require.Equal(t, tc.want, readOnlyQuery(target, tc.vectors))
+		})
+	}
+}
@@ -24,6 +24,7 @@ import (
 	"github.com/kazhuravlev/database-gateway/internal/config"
 	"github.com/kazhuravlev/database-gateway/internal/storage"
 	"github.com/kazhuravlev/database-gateway/internal/structs"
+	"github.com/kazhuravlev/database-gateway/internal/uuid6"
 	"github.com/stretchr/testify/require"
 )
 
@@ -131,6 +132,29 @@ func TestUserSubjectsIncludesRolePrincipal(t *testing.T) {
 	}, subjects)
 }
 
+func TestGrantSubjectsAddsBreakGlassPerTarget(t *testing.T) {
+	t.Parallel()
+
+	user := structs.User{
+		ID:       "user@example.com",
+		Username: "user",
+		Role:     config.RoleUser,
+	}
+	grants := []storage.BreakGlassGrant{
+		{ID: uuid6.New(), UserID: user.ID, TargetID: "taxi-prod"}, //nolint:exhaustruct
+		{ID: uuid6.New(), UserID: user.ID, TargetID: "taxi-prod"}, //nolint:exhaustruct
+		{ID: uuid6.New(), UserID: user.ID, TargetID: "billing"},   //nolint:exhaustruct
+	}
+
+	require.Equal(t, []string{
+		"user:user@example.com",
+		"role:user",
+		"breakglass:taxi-prod",
+		"breakglass:billing",
+	}, grantSubjects(user, grants))
+	require.Equal(t, userSubjects(user), grantSubjects(user, nil))
+}
+
 func TestGetClaimValues(t *testing.T) {
 	t.Parallel()
 
@@ -177,16 +201,30 @@ func TestNewRequiresRoleMapping(t *testing.T) {
 				Tags:        nil,
 				Type:        "",
 				Connection: config.Connection{
-					Host:        "",
-					Port:        0,
-					User:        "",
-					Password:    "",
-					DB:          "",
-					UseSSL:      false,
-					MaxPoolSize: 0,
+					Host:              "",
+					Port:              0,
+					User:              "",
+					Password:          "",
+					DB:                "",
+					TLS:               config.TLSConfig{Mode: "", RootCert: "", ClientCert: "", ClientKey: "", ServerName: ""},
+					MaxPoolSize:       0,
+					MinPoolSize:       0,
+					MaxConnLifetime:   0,
+					MaxConnIdleTime:   0,
+					HealthCheckPeriod: 0,
+					ApplicationName:   "",
+					SSHTunnel:         nil,
 				},
-				DefaultSchema: "",
-				Tables:        nil,
+				DefaultSchema:    "",
+				Tables:           nil,
+				RequireApproval:  false,
+				ReadOnly:         false,
+				StatementTimeout: 0,
+				Limits:           config.ResultLimits{MaxRows: 0, MaxBytes: 0},
+				RoleLimits:       nil,
+				Impersonation:    nil,
+				Replicas:         nil,
+				MaxReplicaLag:    0,
 			},
 		},
 		config.UsersProviderOIDC{
@@ -59,16 +59,30 @@ func TestServiceGetTargets(t *testing.T) {
 			Tags:        []string{"prod"},
 			Type:        "postgres",
 			Connection: config.Connection{
-				Host:        "",
-				Port:        0,
-				User:        "",
-				Password:    "",
-				DB:          "",
-				UseSSL:      false,
-				MaxPoolSize: 0,
+				Host:              "",
+				Port:              0,
+				User:              "",
+				Password:          "",
+				DB:                "",
+				TLS:               config.TLSConfig{Mode: "", RootCert: "", ClientCert: "", ClientKey: "", ServerName: ""},
+				MaxPoolSize:       0,
+				MinPoolSize:       0,
+				MaxConnLifetime:   0,
+				MaxConnIdleTime:   0,
+				HealthCheckPeriod: 0,
+				ApplicationName:   "",
+				SSHTunnel:         nil,
 			},
-			DefaultSchema: "public",
-			Tables:        []config.TargetTable{{Table: "public.clients", Fields: nil}},
+			DefaultSchema:    "public",
+			Tables:           []config.TargetTable{{Table: "public.clients", Fields: nil}},
+			RequireApproval:  false,
+			ReadOnly:         false,
+			StatementTimeout: 0,
+			Limits:           config.ResultLimits{MaxRows: 0, MaxBytes: 0},
+			RoleLimits:       nil,
+			Impersonation:    nil,
+			Replicas:         nil,
+			MaxReplicaLag:    0,
 		},
 		{
 			ID:          "pg-2",
@@ -76,16 +90,30 @@ func TestServiceGetTargets(t *testing.T) {
 			Tags:        []string{"analytics"},
 			Type:        "postgres",
 			Connection: config.Connection{
-				Host:        "",
-				Port:        0,
-				User:        "",
-				Password:    "",
-				DB:          "",
-				UseSSL:      false,
-				MaxPoolSize: 0,
+				Host:              "",
+				Port:              0,
+				User:              "",
+				Password:          "",
+				DB:                "",
+				TLS:               config.TLSConfig{Mode: "", RootCert: "", ClientCert: "", ClientKey: "", ServerName: ""},
+				MaxPoolSize:       0,
+				MinPoolSize:       0,
+				MaxConnLifetime:   0,
+				MaxConnIdleTime:   0,
+				HealthCheckPeriod: 0,
+				ApplicationName:   "",
+				SSHTunnel:         nil,
 			},
-			DefaultSchema: "public",
-			Tables:        []config.TargetTable{{Table: "public.events", Fields: nil}},
+			DefaultSchema:    "public",
+			Tables:           []config.TargetTable{{Table: "public.events", Fields: nil}},
+			RequireApproval:  false,
+			ReadOnly:         false,
+			StatementTimeout: 0,
+			Limits:           config.ResultLimits{MaxRows: 0, MaxBytes: 0},
+			RoleLimits:       nil,
+			Impersonation:    nil,
+			Replicas:         nil,
+			MaxReplicaLag:    0,
 		},
 	}
 
@@ -131,6 +159,8 @@ func TestServiceGetTargets(t *testing.T) {
 					},
 					authorizer: mustAuthorizer(t, targetPolicy),
 					storage:    nil,
+
+					breakGlassMaxTTL: 0,
 				},
 				connsMu:       new(sync.RWMutex),
 				conns:         nil,
@@ -139,6 +169,10 @@ func TestServiceGetTargets(t *testing.T) {
 				tokenVerifier: nil,
 				oidcLogoutEP:  "",
 				oidcRevokeEP:  "",
+				activeGrants:  noGrants,
+				running:       newQueryRegistry(),
+				previews:      newPreviewRegistry(),
+				health:        newHealthRegistry(),
 			}
 
 			got, err := svc.GetTargets(context.Background(), tc.user)
@@ -163,20 +197,40 @@ func TestServiceGetTargetByID(t *testing.T) {
 		Tags:        []string{"prod"},
 		Type:        "postgres",
 		Connection: config.Connection{
-			Host:        "",
-			Port:        0,
-			User:        "",
-			Password:    "",
-			DB:          "",
-			UseSSL:      false,
-			MaxPoolSize: 0,
+			Host:              "",
+			Port:              0,
+			User:              "",
+			Password:          "",
+			DB:                "",
+			TLS:               config.TLSConfig{Mode: "", RootCert: "", ClientCert: "", ClientKey: "", ServerName: ""},
+			MaxPoolSize:       0,
+			MinPoolSize:       0,
+			MaxConnLifetime:   0,
+			MaxConnIdleTime:   0,
+			HealthCheckPeriod: 0,
+			ApplicationName:   "",
+			SSHTunnel:         nil,
 		},
-		DefaultSchema: "public",
-		Tables:        []config.TargetTable{{Table: "public.clients", Fields: nil}},
+		DefaultSchema:    "public",
+		Tables:           []config.TargetTable{{Table: "public.clients", Fields: nil}},
+		RequireApproval:  false,
+		ReadOnly:         false,
+		StatementTimeout: 0,
+		Limits:           config.ResultLimits{MaxRows: 0, MaxBytes: 0},
+		RoleLimits:       nil,
+		Impersonation:    nil,
+		Replicas:         nil,
+		MaxReplicaLag:    0,
 	}
 
 	user := structs.User{ID: "alice@example.com", Username: "", Role: config.RoleUser}
 
+	health := newHealthRegistry()
+	targetHealth := unknownHealth()
+	targetHealth.Status = structs.TargetStatusUp
+	targetHealth.Version = "16.4"
+	health.set("pg-1", targetHealth)
+
 	testCases := []struct {
 		name       string
 		authorizer string
@@ -195,6 +249,7 @@ func TestServiceGetTargetByID(t *testing.T) {
 				Tags:        []structs.Tag{{Name: "prod"}},
 				Type:        "postgres",
 				Tables:      []config.TargetTable{{Table: "public.clients", Fields: nil}},
+				Health:      targetHealth,
 			},
 		},
 		{
@@ -237,6 +292,8 @@ default allow_query := false
 					},
 					authorizer: mustAuthorizer(t, tc.authorizer),
 					storage:    nil,
+
+					breakGlassMaxTTL: 0,
 				},
 				connsMu:       new(sync.RWMutex),
 				conns:         nil,
@@ -245,6 +302,10 @@ default allow_query := false
 				tokenVerifier: nil,
 				oidcLogoutEP:  "",
 				oidcRevokeEP:  "",
+				activeGrants:  noGrants,
+				running:       newQueryRegistry(),
+				previews:      newPreviewRegistry(),
+				health:        health,
 			}
 
 			got, err := svc.GetTargetByID(context.Background(), user, tc.targetID)
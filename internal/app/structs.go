@@ -19,7 +19,9 @@ package app
 import (
 	"time"
 
+	"github.com/kazhuravlev/database-gateway/internal/config"
 	"github.com/kazhuravlev/database-gateway/internal/structs"
+	"github.com/kazhuravlev/database-gateway/internal/uuid6"
 )
 
 type QueryResults struct {
@@ -28,6 +30,25 @@ type QueryResults struct {
 	TargetID  string
 	CreatedAt time.Time
 	Query     string
+	Status    structs.QueryStatus
+	Error     string
 	QTable    structs.QTable
 	Meta      structs.QMeta
 }
+
+// QueryState is the progress of a submitted query.
+type QueryState struct {
+	ID        uuid6.UUID
+	TargetID  config.TargetID
+	Status    structs.QueryStatus
+	Error     string
+	CreatedAt time.Time
+}
+
+// QueryPreview is a write that was executed inside an open transaction and waits for CommitQuery or RollbackQuery.
+type QueryPreview struct {
+	ID           uuid6.UUID
+	Table        structs.QTable
+	AffectedRows int64
+	ExpiresAt    time.Time
+}
@@ -19,8 +19,11 @@ package app //nolint:testpackage
 import (
 	"context"
 	"testing"
+	"time"
 
+	"github.com/kazhuravlev/database-gateway/internal/config"
 	"github.com/kazhuravlev/database-gateway/internal/policy/opa"
+	"github.com/kazhuravlev/database-gateway/internal/storage"
 	"github.com/stretchr/testify/require"
 )
 
@@ -34,3 +37,7 @@ func mustAuthorizer(t *testing.T, module string) *opa.Authorizer {
 
 	return authz
 }
+
+func noGrants(context.Context, config.UserID, time.Time) ([]storage.BreakGlassGrant, error) {
+	return nil, nil
+}
@@ -0,0 +1,216 @@
+// Database Gateway provides access to servers with ACL for safe and restricted database interactions.
+// Copyright (C) 2024  Kirill Zhuravlev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/kazhuravlev/database-gateway/internal/config"
+	"github.com/kazhuravlev/just"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+const (
+	sshDefaultPort      = "22"
+	sshHandshakeTimeout = 10 * time.Second
+)
+
+// sshTunnel keeps one ssh client to the jump host and dials target connections through it. A broken client is
+// dropped and the next dial connects again.
+type sshTunnel struct {
+	addr   string
+	config *ssh.ClientConfig
+
+	mu     *sync.Mutex
+	client *ssh.Client
+	closed bool
+}
+
+func newSSHTunnel(cfg config.SSHTunnel) (*sshTunnel, error) {
+	keyBuf, err := os.ReadFile(cfg.PrivateKeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("read private key: %w", err)
+	}
+
+	signer, err := ssh.ParsePrivateKey(keyBuf)
+	if err != nil {
+		return nil, fmt.Errorf("parse private key: %w", err)
+	}
+
+	hostKeyCallback, err := knownhosts.New(cfg.KnownHostsPath)
+	if err != nil {
+		return nil, fmt.Errorf("read known hosts: %w", err)
+	}
+
+	addr := cfg.Host
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, sshDefaultPort)
+	}
+
+	return &sshTunnel{
+		addr: addr,
+		config: &ssh.ClientConfig{ //nolint:exhaustruct
+			User:            cfg.User,
+			Auth:            []ssh.AuthMethod{ssh.PublicKeys(signer)},
+			HostKeyCallback: hostKeyCallback,
+			Timeout:         sshHandshakeTimeout,
+		},
+		mu:     new(sync.Mutex),
+		client: nil,
+		closed: false,
+	}, nil
+}
+
+// apply makes the pool dial through the tunnel. Target host is resolved by the jump host, because it is usually not
+// resolvable from the gateway.
+func (t *sshTunnel) apply(poolCfg *pgxpool.Config) {
+	poolCfg.ConnConfig.DialFunc = t.DialContext
+	poolCfg.ConnConfig.LookupFunc = func(_ context.Context, host string) ([]string, error) {
+		return []string{host}, nil
+	}
+}
+
+// DialContext opens a connection to addr from the jump host. The dial is retried once with a new client, because the
+// current one could be broken without being noticed yet.
+func (t *sshTunnel) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	client, err := t.getClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := client.DialContext(ctx, network, addr)
+	if err == nil {
+		return conn, nil
+	}
+
+	// NOTE: the jump host has answered, so the client works and the target itself is unreachable.
+	if _, ok := just.ErrAs[*ssh.OpenChannelError](err); ok {
+		return nil, fmt.Errorf("dial %s through ssh tunnel: %w", addr, err)
+	}
+
+	t.drop(client)
+
+	client, err = t.getClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err = client.DialContext(ctx, network, addr)
+	if err != nil {
+		return nil, fmt.Errorf("dial %s through ssh tunnel: %w", addr, err)
+	}
+
+	return conn, nil
+}
+
+func (t *sshTunnel) getClient(ctx context.Context) (*ssh.Client, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.closed {
+		return nil, fmt.Errorf("ssh tunnel is closed: %w", ErrClosed)
+	}
+
+	if t.client != nil {
+		return t.client, nil
+	}
+
+	dialer := net.Dialer{Timeout: sshHandshakeTimeout} //nolint:exhaustruct
+	netConn, err := dialer.DialContext(ctx, "tcp", t.addr)
+	if err != nil {
+		return nil, fmt.Errorf("dial ssh host: %w", err)
+	}
+
+	// NOTE: ClientConfig.Timeout covers ssh.Dial only, so the handshake gets its own deadline. Otherwise a stalled
+	//  jump host would hold the lock and block every dial through the tunnel.
+	deadline := time.Now().Add(sshHandshakeTimeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+
+	if err := netConn.SetDeadline(deadline); err != nil {
+		_ = netConn.Close()
+
+		return nil, fmt.Errorf("set handshake deadline: %w", err)
+	}
+
+	sshConn, chans, reqs, err := ssh.NewClientConn(netConn, t.addr, t.config)
+	if err != nil {
+		_ = netConn.Close()
+
+		return nil, fmt.Errorf("ssh handshake: %w", err)
+	}
+
+	if err := netConn.SetDeadline(time.Time{}); err != nil {
+		_ = sshConn.Close()
+
+		return nil, fmt.Errorf("clear handshake deadline: %w", err)
+	}
+
+	client := ssh.NewClient(sshConn, chans, reqs)
+	t.client = client
+
+	// NOTE: drop the client as soon as the jump host goes away, so the next dial reconnects.
+	go func() {
+		_ = client.Wait()
+		t.drop(client)
+	}()
+
+	return client, nil
+}
+
+// drop forgets the client unless it was already replaced.
+func (t *sshTunnel) drop(client *ssh.Client) {
+	t.mu.Lock()
+	if t.client == client {
+		t.client = nil
+	}
+	t.mu.Unlock()
+
+	_ = client.Close()
+}
+
+// Close closes the ssh client. Call it after the pool that uses the tunnel is closed. Close is safe on nil tunnel.
+func (t *sshTunnel) Close() error {
+	if t == nil {
+		return nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.closed = true
+	if t.client == nil {
+		return nil
+	}
+
+	err := t.client.Close()
+	t.client = nil
+	if err != nil && !errors.Is(err, net.ErrClosed) {
+		return fmt.Errorf("close ssh client: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,358 @@
+// Database Gateway provides access to servers with ACL for safe and restricted database interactions.
+// Copyright (C) 2024  Kirill Zhuravlev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package app //nolint:testpackage
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kazhuravlev/database-gateway/internal/config"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// sshServerStandIn is a minimal jump host. It accepts one user key and forwards direct-tcpip channels.
+type sshServerStandIn struct {
+	listener net.Listener
+	config   *ssh.ServerConfig
+	hostKey  ssh.PublicKey
+
+	mu    sync.Mutex
+	conns []*ssh.ServerConn
+}
+
+func newSSHServerStandIn(t *testing.T, userKey ssh.PublicKey) *sshServerStandIn {
+	t.Helper()
+
+	_, hostPriv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	hostSigner, err := ssh.NewSignerFromKey(hostPriv)
+	require.NoError(t, err)
+
+	serverCfg := &ssh.ServerConfig{ //nolint:exhaustruct
+		PublicKeyCallback: func(_ ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if string(key.Marshal()) != string(userKey.Marshal()) {
+				return nil, io.EOF
+			}
+
+			return nil, nil //nolint:nilnil
+		},
+	}
+	serverCfg.AddHostKey(hostSigner)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+
+	srv := &sshServerStandIn{
+		listener: listener,
+		config:   serverCfg,
+		hostKey:  hostSigner.PublicKey(),
+		mu:       sync.Mutex{},
+		conns:    nil,
+	}
+	go srv.serve()
+
+	return srv
+}
+
+func (s *sshServerStandIn) serve() {
+	for {
+		netConn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		go s.handle(netConn)
+	}
+}
+
+func (s *sshServerStandIn) handle(netConn net.Conn) {
+	sshConn, chans, reqs, err := ssh.NewServerConn(netConn, s.config)
+	if err != nil {
+		_ = netConn.Close()
+
+		return
+	}
+
+	s.mu.Lock()
+	s.conns = append(s.conns, sshConn)
+	s.mu.Unlock()
+
+	go ssh.DiscardRequests(reqs)
+
+	for newCh := range chans {
+		if newCh.ChannelType() != "direct-tcpip" {
+			_ = newCh.Reject(ssh.UnknownChannelType, "unsupported")
+
+			continue
+		}
+
+		var payload struct {
+			DestAddr string
+			DestPort uint32
+			OrigAddr string
+			OrigPort uint32
+		}
+		if err := ssh.Unmarshal(newCh.ExtraData(), &payload); err != nil {
+			_ = newCh.Reject(ssh.ConnectionFailed, err.Error())
+
+			continue
+		}
+
+		target, err := net.Dial("tcp", net.JoinHostPort(payload.DestAddr, strconv.Itoa(int(payload.DestPort))))
+		if err != nil {
+			_ = newCh.Reject(ssh.ConnectionFailed, err.Error())
+
+			continue
+		}
+
+		channel, chReqs, err := newCh.Accept()
+		if err != nil {
+			_ = target.Close()
+
+			continue
+		}
+		go ssh.DiscardRequests(chReqs)
+
+		go func() {
+			defer channel.Close()
+			defer target.Close()
+
+			go func() { _, _ = io.Copy(target, channel) }()
+			_, _ = io.Copy(channel, target)
+		}()
+	}
+}
+
+// dropClients closes all ssh connections like a restarted jump host does.
+func (s *sshServerStandIn) dropClients() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, conn := range s.conns {
+		_ = conn.Close()
+	}
+	s.conns = nil
+}
+
+// startEchoServer stands in for a target database.
+func startEchoServer(t *testing.T) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer conn.Close()
+				_, _ = io.Copy(conn, conn)
+			}()
+		}
+	}()
+
+	return listener.Addr().String()
+}
+
+// tunnelConfig writes the user key and known_hosts with the given host key.
+func tunnelConfig(
+	t *testing.T,
+	srv *sshServerStandIn,
+	userKey ed25519.PrivateKey,
+	hostKey ssh.PublicKey,
+) config.SSHTunnel {
+	t.Helper()
+
+	block, err := ssh.MarshalPrivateKey(userKey, "")
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	keyPath := filepath.Join(dir, "id_ed25519")
+	require.NoError(t, os.WriteFile(keyPath, pem.EncodeToMemory(block), 0o600))
+
+	hostAddr := srv.listener.Addr().String()
+	knownHostsPath := filepath.Join(dir, "known_hosts")
+	line := knownhosts.Line([]string{hostAddr}, hostKey) + "\n"
+	require.NoError(t, os.WriteFile(knownHostsPath, []byte(line), 0o600))
+
+	return config.SSHTunnel{
+		Host:           hostAddr,
+		User:           "gateway",
+		PrivateKeyPath: keyPath,
+		KnownHostsPath: knownHostsPath,
+	}
+}
+
+// newUserKey returns a private key of the gateway and its public part for the jump host.
+func newUserKey(t *testing.T) (ed25519.PrivateKey, ssh.PublicKey) {
+	t.Helper()
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	sshPub, err := ssh.NewPublicKey(pub)
+	require.NoError(t, err)
+
+	return priv, sshPub
+}
+
+func requireEcho(t *testing.T, conn net.Conn) {
+	t.Helper()
+
+	_, err := conn.Write([]byte("ping"))
+	require.NoError(t, err)
+
+	buf := make([]byte, 4)
+	_, err = io.ReadFull(conn, buf)
+	require.NoError(t, err)
+	require.Equal(t, "ping", string(buf))
+}
+
+func TestSSHTunnel(t *testing.T) {
+	t.Parallel()
+
+	t.Run("dial and reconnect", func(t *testing.T) {
+		t.Parallel()
+
+		echoAddr := startEchoServer(t)
+		userKey, userPub := newUserKey(t)
+		srv := newSSHServerStandIn(t, userPub)
+
+		tunnel, err := newSSHTunnel(tunnelConfig(t, srv, userKey, srv.hostKey))
+		require.NoError(t, err)
+		t.Cleanup(func() { require.NoError(t, tunnel.Close()) })
+
+		conn, err := tunnel.DialContext(context.Background(), "tcp", echoAddr)
+		require.NoError(t, err)
+		requireEcho(t, conn)
+		_ = conn.Close()
+
+		srv.dropClients()
+
+		conn, err = tunnel.DialContext(context.Background(), "tcp", echoAddr)
+		require.NoError(t, err)
+		requireEcho(t, conn)
+		_ = conn.Close()
+	})
+
+	t.Run("unreachable target keeps the client", func(t *testing.T) {
+		t.Parallel()
+
+		echoAddr := startEchoServer(t)
+		userKey, userPub := newUserKey(t)
+		srv := newSSHServerStandIn(t, userPub)
+
+		tunnel, err := newSSHTunnel(tunnelConfig(t, srv, userKey, srv.hostKey))
+		require.NoError(t, err)
+		t.Cleanup(func() { require.NoError(t, tunnel.Close()) })
+
+		_, err = tunnel.DialContext(context.Background(), "tcp", "127.0.0.1:1")
+		require.ErrorContains(t, err, "through ssh tunnel")
+
+		client := tunnel.client
+		conn, err := tunnel.DialContext(context.Background(), "tcp", echoAddr)
+		require.NoError(t, err)
+		requireEcho(t, conn)
+		_ = conn.Close()
+		require.Same(t, client, tunnel.client)
+	})
+
+	t.Run("unknown host key is rejected", func(t *testing.T) {
+		t.Parallel()
+
+		echoAddr := startEchoServer(t)
+		userKey, userPub := newUserKey(t)
+		srv := newSSHServerStandIn(t, userPub)
+		_, otherHostKey := newUserKey(t)
+
+		tunnel, err := newSSHTunnel(tunnelConfig(t, srv, userKey, otherHostKey))
+		require.NoError(t, err)
+		t.Cleanup(func() { require.NoError(t, tunnel.Close()) })
+
+		_, err = tunnel.DialContext(context.Background(), "tcp", echoAddr)
+		require.ErrorContains(t, err, "ssh handshake")
+	})
+
+	t.Run("stalled handshake is bounded by context deadline", func(t *testing.T) {
+		t.Parallel()
+
+		userKey, userPub := newUserKey(t)
+		srv := newSSHServerStandIn(t, userPub)
+		require.NoError(t, srv.listener.Close())
+
+		// NOTE: the stalled host accepts connections and never answers.
+		stalled, err := net.Listen("tcp", srv.listener.Addr().String())
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = stalled.Close() })
+
+		go func() {
+			for {
+				conn, err := stalled.Accept()
+				if err != nil {
+					return
+				}
+				t.Cleanup(func() { _ = conn.Close() })
+			}
+		}()
+
+		tunnel, err := newSSHTunnel(tunnelConfig(t, srv, userKey, srv.hostKey))
+		require.NoError(t, err)
+		t.Cleanup(func() { require.NoError(t, tunnel.Close()) })
+
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+
+		startedAt := time.Now()
+		_, err = tunnel.DialContext(ctx, "tcp", "127.0.0.1:1")
+		require.ErrorContains(t, err, "ssh handshake")
+		require.Less(t, time.Since(startedAt), sshHandshakeTimeout)
+	})
+
+	t.Run("closed tunnel does not dial", func(t *testing.T) {
+		t.Parallel()
+
+		echoAddr := startEchoServer(t)
+		userKey, userPub := newUserKey(t)
+		srv := newSSHServerStandIn(t, userPub)
+
+		tunnel, err := newSSHTunnel(tunnelConfig(t, srv, userKey, srv.hostKey))
+		require.NoError(t, err)
+		require.NoError(t, tunnel.Close())
+
+		_, err = tunnel.DialContext(context.Background(), "tcp", echoAddr)
+		require.ErrorIs(t, err, ErrClosed)
+	})
+}
@@ -17,9 +17,12 @@
 package config
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"net/url"
 	"strings"
+	"time"
 )
 
 type UserID string
@@ -62,14 +65,121 @@ func (op Op) S() string {
 	return string(op)
 }
 
+// Duration is a time.Duration that is written in config as a string like "300ms" or "1m30s".
+type Duration time.Duration
+
+func (d Duration) D() time.Duration {
+	return time.Duration(d)
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String()) //nolint:wrapcheck
+}
+
+func (d *Duration) UnmarshalJSON(buf []byte) error {
+	var str string
+	if err := json.Unmarshal(buf, &str); err != nil {
+		return fmt.Errorf("duration should be a string like \"1s\": %w", err)
+	}
+
+	value, err := time.ParseDuration(str)
+	if err != nil {
+		return fmt.Errorf("parse duration: %w", err)
+	}
+
+	*d = Duration(value)
+
+	return nil
+}
+
 type PostgresConfig struct {
-	Host        string `json:"host"`
-	Port        int    `json:"port"`
-	Database    string `json:"database"`
-	Username    string `json:"username"`
-	Password    string `json:"password"`
-	UseSSL      bool   `json:"use_ssl"`
-	MaxPoolSize int    `json:"max_pool_size"`
+	Host        string    `json:"host"`
+	Port        int       `json:"port"`
+	Database    string    `json:"database"`
+	Username    string    `json:"username"`
+	Password    string    `json:"password"`
+	TLS         TLSConfig `json:"tls"`
+	MaxPoolSize int       `json:"max_pool_size"`
+}
+
+func (c *PostgresConfig) UnmarshalJSON(buf []byte) error {
+	type plain PostgresConfig
+	if err := json.Unmarshal(buf, (*plain)(c)); err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	return applyLegacyUseSSL(buf, &c.TLS)
+}
+
+// applyLegacyUseSSL maps the former use_ssl flag to the tls block, so old configs keep encrypting connections.
+// "use_ssl": true means tls.mode "require" unless a stricter mode is set.
+func applyLegacyUseSSL(buf []byte, tls *TLSConfig) error {
+	var legacy struct {
+		UseSSL *bool `json:"use_ssl"`
+	}
+	if err := json.Unmarshal(buf, &legacy); err != nil {
+		return fmt.Errorf("read use_ssl: %w", err)
+	}
+
+	if legacy.UseSSL == nil || !*legacy.UseSSL {
+		return nil
+	}
+
+	switch tls.Mode {
+	case "":
+		tls.Mode = TLSModeRequire
+	case TLSModeDisable:
+		return errors.New("use_ssl is replaced by tls.mode and conflicts with tls.mode \"disable\"") //nolint:err113
+	default:
+	}
+
+	return nil
+}
+
+type TLSMode string
+
+const (
+	TLSModeDisable    TLSMode = "disable"
+	TLSModeRequire    TLSMode = "require"
+	TLSModeVerifyCA   TLSMode = "verify-ca"
+	TLSModeVerifyFull TLSMode = "verify-full"
+)
+
+// TLSConfig follows libpq ssl settings. Only modes that always use TLS or never use it are supported.
+type TLSConfig struct {
+	Mode       TLSMode `json:"mode"`        // one of disable, require, verify-ca, verify-full; disable by default
+	RootCert   string  `json:"root_cert"`   // path to CA bundle for verify-ca and verify-full; system roots when empty
+	ClientCert string  `json:"client_cert"` // path to client certificate; requires client_key
+	ClientKey  string  `json:"client_key"`  // path to key of client certificate
+	ServerName string  `json:"server_name"` // name expected in the server certificate; host by default
+}
+
+// EffectiveMode returns the mode with the default applied.
+func (t *TLSConfig) EffectiveMode() TLSMode {
+	if t.Mode == "" {
+		return TLSModeDisable
+	}
+
+	return t.Mode
+}
+
+func (t *TLSConfig) Validate() error {
+	mode := t.EffectiveMode()
+	switch mode {
+	case TLSModeDisable, TLSModeRequire, TLSModeVerifyCA, TLSModeVerifyFull:
+	default:
+		return fmt.Errorf("unsupported tls.mode %q", t.Mode) //nolint:err113
+	}
+
+	if (t.ClientCert == "") != (t.ClientKey == "") {
+		return errors.New("tls.client_cert and tls.client_key should be set together") //nolint:err113
+	}
+
+	if mode == TLSModeDisable && (t.RootCert != "" || t.ClientCert != "" || t.ServerName != "") {
+		return errors.New("tls.mode should not be disable when certificates or server_name are set") //nolint:err113
+	}
+
+	return nil
 }
 
 type TargetTable struct {
@@ -78,23 +188,161 @@ type TargetTable struct {
 }
 
 type Connection struct {
-	Host        string `json:"host"`
-	Port        int    `json:"port"`
-	User        string `json:"user"`
-	Password    string `json:"password"`
-	DB          string `json:"db"`
-	UseSSL      bool   `json:"use_ssl"`
-	MaxPoolSize int    `json:"max_pool_size"`
+	Host              string     `json:"host"`
+	Port              int        `json:"port"`
+	User              string     `json:"user"`
+	Password          string     `json:"password"`
+	DB                string     `json:"db"`
+	TLS               TLSConfig  `json:"tls"`
+	MaxPoolSize       int        `json:"max_pool_size"`        // pgxpool default when 0
+	MinPoolSize       int        `json:"min_pool_size"`        // connections kept open even when idle; 0 by default
+	MaxConnLifetime   Duration   `json:"max_conn_lifetime"`    // connection is closed after this time; pgxpool default when 0
+	MaxConnIdleTime   Duration   `json:"max_conn_idle_time"`   // idle connection is closed after this time; pgxpool default when 0
+	HealthCheckPeriod Duration   `json:"health_check_period"`  // how often idle connections are checked; pgxpool default when 0
+	ApplicationName   string     `json:"application_name"`     // application_name of target sessions; database-gateway by default
+	SSHTunnel         *SSHTunnel `json:"ssh_tunnel,omitempty"` // connect through a jump host; direct connection when omitted
+}
+
+// SSHTunnel describes a jump host. Host and Port of the connection are resolved and dialed from the jump host.
+type SSHTunnel struct {
+	Host           string `json:"host"`             // address of the jump host like bastion:22; port 22 is used when omitted
+	User           string `json:"user"`             // ssh user
+	PrivateKeyPath string `json:"private_key_path"` // path to unencrypted private key of the user
+	KnownHostsPath string `json:"known_hosts_path"` // path to known_hosts file with the key of the jump host
+}
+
+func (t *SSHTunnel) Validate() error {
+	if strings.TrimSpace(t.Host) == "" || strings.TrimSpace(t.User) == "" {
+		return errors.New("ssh_tunnel.host and ssh_tunnel.user are required") //nolint:err113
+	}
+
+	if strings.TrimSpace(t.PrivateKeyPath) == "" || strings.TrimSpace(t.KnownHostsPath) == "" {
+		return errors.New("ssh_tunnel.private_key_path and ssh_tunnel.known_hosts_path are required") //nolint:err113
+	}
+
+	return nil
+}
+
+func (c *Connection) UnmarshalJSON(buf []byte) error {
+	type plain Connection
+	if err := json.Unmarshal(buf, (*plain)(c)); err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	return applyLegacyUseSSL(buf, &c.TLS)
+}
+
+func (c *Connection) Validate() error {
+	if c.MaxPoolSize < 0 || c.MinPoolSize < 0 {
+		return errors.New("max_pool_size and min_pool_size should not be negative") //nolint:err113
+	}
+
+	if c.MaxPoolSize > 0 && c.MinPoolSize > c.MaxPoolSize {
+		return errors.New("min_pool_size should not exceed max_pool_size") //nolint:err113
+	}
+
+	if c.MaxConnLifetime < 0 || c.MaxConnIdleTime < 0 || c.HealthCheckPeriod < 0 {
+		return errors.New("max_conn_lifetime, max_conn_idle_time and health_check_period should not be negative") //nolint:err113
+	}
+
+	if err := c.TLS.Validate(); err != nil {
+		return err
+	}
+
+	if c.SSHTunnel != nil {
+		if err := c.SSHTunnel.Validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
 type Target struct {
-	ID            TargetID      `json:"id"`
-	Description   string        `json:"description"`
-	Tags          []string      `json:"tags"`
-	Type          string        `json:"type"`
-	Connection    Connection    `json:"connection"`
-	DefaultSchema string        `json:"default_schema"`
-	Tables        []TargetTable `json:"tables"`
+	ID               TargetID              `json:"id"`
+	Description      string                `json:"description"`
+	Tags             []string              `json:"tags"`
+	Type             string                `json:"type"`
+	Connection       Connection            `json:"connection"`
+	DefaultSchema    string                `json:"default_schema"`
+	Tables           []TargetTable         `json:"tables"`
+	RequireApproval  bool                  `json:"require_approval"`
+	ReadOnly         bool                  `json:"read_only"`         // run every query in a read-only transaction
+	StatementTimeout Duration              `json:"statement_timeout"` // statement_timeout of target connections; no limit by default
+	Limits           ResultLimits          `json:"limits"`
+	RoleLimits       map[Role]ResultLimits `json:"role_limits"`             // per-role limits; the stricter of both is applied
+	Impersonation    *Impersonation        `json:"impersonation,omitempty"` // run queries as a database role of the user
+	Replicas         []Replica             `json:"replicas,omitempty"`      // read replicas for select-only queries
+	MaxReplicaLag    Duration              `json:"max_replica_lag"`         // replicas that lag more are skipped; no limit by default
+}
+
+// Replica is a read-only copy of the target. Queries whose vectors are all selects run on a healthy replica.
+type Replica struct {
+	ID         string     `json:"id"` // unique within the target
+	Connection Connection `json:"connection"`
+}
+
+func validateReplicas(target Target) error { //nolint:gocritic
+	if target.MaxReplicaLag < 0 {
+		return errors.New("max_replica_lag should not be negative") //nolint:err113
+	}
+
+	seen := make(map[string]struct{}, len(target.Replicas))
+	for i := range target.Replicas {
+		replica := target.Replicas[i]
+		if strings.TrimSpace(replica.ID) == "" {
+			return errors.New("replica id is required") //nolint:err113
+		}
+
+		if _, ok := seen[replica.ID]; ok {
+			return fmt.Errorf("replica %q is defined twice", replica.ID) //nolint:err113
+		}
+		seen[replica.ID] = struct{}{}
+
+		if err := replica.Connection.Validate(); err != nil {
+			return fmt.Errorf("bad connection of replica %q: %w", replica.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// Impersonation maps gateway users to database roles. Every query runs in a transaction after SET LOCAL ROLE, so
+// grants and row level security of the database apply on top of the policy. Connection.User must be a member of
+// every mapped role. Users without a mapping cannot query the target.
+type Impersonation struct {
+	Users map[UserID]string `json:"users"` // database role per user id; checked first
+	Roles map[Role]string   `json:"roles"` // database role per gateway role
+}
+
+func (i *Impersonation) Validate() error {
+	if len(i.Users) == 0 && len(i.Roles) == 0 {
+		return errors.New("impersonation should map at least one user or role") //nolint:err113
+	}
+
+	for userID, dbRole := range i.Users {
+		if strings.TrimSpace(dbRole) == "" {
+			return fmt.Errorf("impersonation.users[%q] should not be empty", userID) //nolint:err113
+		}
+	}
+
+	for role, dbRole := range i.Roles {
+		if !role.IsValid() {
+			return fmt.Errorf("unsupported role %q in impersonation.roles", role) //nolint:err113
+		}
+
+		if strings.TrimSpace(dbRole) == "" {
+			return fmt.Errorf("impersonation.roles[%q] should not be empty", role) //nolint:err113
+		}
+	}
+
+	return nil
+}
+
+// ResultLimits bounds how much of a query result the gateway reads from the target. Zero means no limit.
+type ResultLimits struct {
+	MaxRows  int   `json:"max_rows"`
+	MaxBytes int64 `json:"max_bytes"` // total size of all cells of the result
 }
 
 type UsersProviderOIDC struct {
@@ -116,14 +364,44 @@ type FacadeConfig struct {
 
 type PolicyConfig struct {
 	Path string `json:"path"` // directory with .rego modules; relative paths are resolved from the config file location
+	// Remote switches the gateway from embedded OPA to a remote OPA server. Path is not used in this case.
+	Remote *RemotePolicyConfig `json:"remote"`
+}
+
+type RemotePolicyConfig struct {
+	URL        string   `json:"url"`         // base url of OPA server, like http://opa:8181
+	Timeout    Duration `json:"timeout"`     // timeout of one decision request; 2s by default
+	AuthHeader string   `json:"auth_header"` // value of Authorization header, like "Bearer <token>"
+}
+
+type BreakGlassConfig struct {
+	MaxTTL Duration `json:"max_ttl"` // longest elevation a user may request; 4h by default
+}
+
+type PreviewConfig struct {
+	TTL Duration `json:"ttl"` // how long a previewed write waits for commit or rollback; 1m by default
+}
+
+type AsyncConfig struct {
+	Workers   int `json:"workers"`    // how many submitted queries run at the same time; 4 by default
+	QueueSize int `json:"queue_size"` // how many submitted queries may wait for a worker; 64 by default
+}
+
+type HealthCheckConfig struct {
+	Interval Duration `json:"interval"` // how often every target is probed; 30s by default
+	Timeout  Duration `json:"timeout"`  // timeout of one probe; 5s by default
 }
 
 type Config struct {
-	Targets []Target          `json:"targets"`
-	Users   UsersProviderOIDC `json:"users"`
-	Policy  PolicyConfig      `json:"policy"`
-	Facade  FacadeConfig      `json:"facade"`
-	Storage PostgresConfig    `json:"storage"`
+	Targets     []Target          `json:"targets"`
+	Users       UsersProviderOIDC `json:"users"`
+	Policy      PolicyConfig      `json:"policy"`
+	Facade      FacadeConfig      `json:"facade"`
+	Storage     PostgresConfig    `json:"storage"`
+	BreakGlass  BreakGlassConfig  `json:"break_glass"`
+	Preview     PreviewConfig     `json:"preview"`
+	Async       AsyncConfig       `json:"async"`
+	HealthCheck HealthCheckConfig `json:"health_check"`
 }
 
 func (c *Config) Validate() error {
@@ -135,6 +413,34 @@ func (c *Config) Validate() error {
 				return fmt.Errorf("use table notation with leading schema. Like 'public.%s'", table.Table) //nolint:err113
 			}
 		}
+
+		if err := target.Connection.Validate(); err != nil {
+			return fmt.Errorf("bad connection of target %q: %w", target.ID, err)
+		}
+
+		if target.Limits.MaxRows < 0 || target.Limits.MaxBytes < 0 {
+			return fmt.Errorf("limits of target %q should not be negative", target.ID) //nolint:err113
+		}
+
+		for role, limits := range target.RoleLimits {
+			if !role.IsValid() {
+				return fmt.Errorf("unsupported role %q in role_limits of target %q", role, target.ID) //nolint:err113
+			}
+
+			if limits.MaxRows < 0 || limits.MaxBytes < 0 {
+				return fmt.Errorf("role_limits[%q] of target %q should not be negative", role, target.ID) //nolint:err113
+			}
+		}
+
+		if target.Impersonation != nil {
+			if err := target.Impersonation.Validate(); err != nil {
+				return fmt.Errorf("bad impersonation of target %q: %w", target.ID, err)
+			}
+		}
+
+		if err := validateReplicas(target); err != nil {
+			return fmt.Errorf("bad replicas of target %q: %w", target.ID, err)
+		}
 	}
 
 	for attrValue, role := range c.Users.RoleMapping {
@@ -143,9 +449,34 @@ func (c *Config) Validate() error {
 		}
 	}
 
-	if strings.TrimSpace(c.Policy.Path) == "" {
+	if remote := c.Policy.Remote; remote != nil {
+		remoteURL, err := url.Parse(remote.URL)
+		if err != nil || (remoteURL.Scheme != "http" && remoteURL.Scheme != "https") || remoteURL.Host == "" {
+			return errors.New("policy.remote.url should be a valid http(s) url") //nolint:err113
+		}
+
+		if remote.Timeout < 0 {
+			return errors.New("policy.remote.timeout should not be negative") //nolint:err113
+		}
+	} else if strings.TrimSpace(c.Policy.Path) == "" {
 		return errors.New("policy.path is required") //nolint:err113
 	}
 
+	if err := c.Storage.TLS.Validate(); err != nil {
+		return fmt.Errorf("bad storage config: %w", err)
+	}
+
+	if c.Preview.TTL < 0 {
+		return errors.New("preview.ttl should not be negative") //nolint:err113
+	}
+
+	if c.Async.Workers < 0 || c.Async.QueueSize < 0 {
+		return errors.New("async.workers and async.queue_size should not be negative") //nolint:err113
+	}
+
+	if c.HealthCheck.Interval < 0 || c.HealthCheck.Timeout < 0 {
+		return errors.New("health_check.interval and health_check.timeout should not be negative") //nolint:err113
+	}
+
 	return nil
 }
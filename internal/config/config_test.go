@@ -17,7 +17,9 @@
 package config_test
 
 import (
+	"encoding/json"
 	"testing"
+	"time"
 
 	"github.com/kazhuravlev/database-gateway/internal/config"
 	"github.com/stretchr/testify/require"
@@ -50,6 +52,169 @@ func TestConfigValidate(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "remote policy without path",
+			prepare: func(cfg *config.Config) {
+				cfg.Policy.Path = ""
+				cfg.Policy.Remote = &config.RemotePolicyConfig{
+					URL:        "http://opa:8181",
+					Timeout:    config.Duration(time.Second),
+					AuthHeader: "",
+				}
+			},
+			wantErr: false,
+		},
+		{
+			name: "remote policy with bad url",
+			prepare: func(cfg *config.Config) {
+				cfg.Policy.Remote = &config.RemotePolicyConfig{
+					URL:        "opa:8181",
+					Timeout:    0,
+					AuthHeader: "",
+				}
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative target limits",
+			prepare: func(cfg *config.Config) {
+				cfg.Targets[0].Limits = config.ResultLimits{MaxRows: -1, MaxBytes: 0}
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative preview ttl",
+			prepare: func(cfg *config.Config) {
+				cfg.Preview.TTL = config.Duration(-time.Second)
+			},
+			wantErr: true,
+		},
+		{
+			name: "min pool size above max pool size",
+			prepare: func(cfg *config.Config) {
+				cfg.Targets[0].Connection.MaxPoolSize = 2
+				cfg.Targets[0].Connection.MinPoolSize = 4
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative max conn lifetime",
+			prepare: func(cfg *config.Config) {
+				cfg.Targets[0].Connection.MaxConnLifetime = config.Duration(-time.Minute)
+			},
+			wantErr: true,
+		},
+		{
+			name: "ssh tunnel without known hosts",
+			prepare: func(cfg *config.Config) {
+				cfg.Targets[0].Connection.SSHTunnel = &config.SSHTunnel{
+					Host:           "bastion:22",
+					User:           "dbgw",
+					PrivateKeyPath: "/etc/dbgw/id_ed25519",
+					KnownHostsPath: "",
+				}
+			},
+			wantErr: true,
+		},
+		{
+			name: "impersonation without mappings",
+			prepare: func(cfg *config.Config) {
+				cfg.Targets[0].Impersonation = &config.Impersonation{Users: nil, Roles: nil}
+			},
+			wantErr: true,
+		},
+		{
+			name: "impersonation of unknown role",
+			prepare: func(cfg *config.Config) {
+				cfg.Targets[0].Impersonation = &config.Impersonation{
+					Users: nil,
+					Roles: map[config.Role]string{"owner": "dbgw_owner"},
+				}
+			},
+			wantErr: true,
+		},
+		{
+			name: "replicas",
+			prepare: func(cfg *config.Config) {
+				cfg.Targets[0].Replicas = []config.Replica{{ID: "replica-1", Connection: cfg.Targets[0].Connection}}
+				cfg.Targets[0].MaxReplicaLag = config.Duration(30 * time.Second)
+			},
+			wantErr: false,
+		},
+		{
+			name: "replica without id",
+			prepare: func(cfg *config.Config) {
+				cfg.Targets[0].Replicas = []config.Replica{{ID: " ", Connection: cfg.Targets[0].Connection}}
+			},
+			wantErr: true,
+		},
+		{
+			name: "duplicated replica",
+			prepare: func(cfg *config.Config) {
+				replica := config.Replica{ID: "replica-1", Connection: cfg.Targets[0].Connection}
+				cfg.Targets[0].Replicas = []config.Replica{replica, replica}
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative replica lag",
+			prepare: func(cfg *config.Config) {
+				cfg.Targets[0].MaxReplicaLag = config.Duration(-time.Second)
+			},
+			wantErr: true,
+		},
+		{
+			name: "unsupported tls mode",
+			prepare: func(cfg *config.Config) {
+				cfg.Targets[0].Connection.TLS.Mode = "prefer"
+			},
+			wantErr: true,
+		},
+		{
+			name: "client cert without key",
+			prepare: func(cfg *config.Config) {
+				cfg.Storage.TLS = config.TLSConfig{
+					Mode:       config.TLSModeVerifyFull,
+					RootCert:   "",
+					ClientCert: "/etc/dbgw/client.crt",
+					ClientKey:  "",
+					ServerName: "",
+				}
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative health check interval",
+			prepare: func(cfg *config.Config) {
+				cfg.HealthCheck.Interval = config.Duration(-time.Second)
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative async workers",
+			prepare: func(cfg *config.Config) {
+				cfg.Async.Workers = -1
+			},
+			wantErr: true,
+		},
+		{
+			name: "role limits for unknown role",
+			prepare: func(cfg *config.Config) {
+				cfg.Targets[0].RoleLimits = map[config.Role]config.ResultLimits{
+					"owner": {MaxRows: 10, MaxBytes: 0},
+				}
+			},
+			wantErr: true,
+		},
+		{
+			name: "role limits",
+			prepare: func(cfg *config.Config) {
+				cfg.Targets[0].RoleLimits = map[config.Role]config.ResultLimits{
+					config.RoleUser: {MaxRows: 1000, MaxBytes: 1 << 20},
+				}
+			},
+			wantErr: false,
+		},
 		{
 			name: "invalid role mapping",
 			prepare: func(cfg *config.Config) {
@@ -85,18 +250,32 @@ func validConfigForTest() config.Config {
 				Tags:        nil,
 				Type:        "",
 				Connection: config.Connection{
-					Host:        "",
-					Port:        0,
-					User:        "",
-					Password:    "",
-					DB:          "",
-					UseSSL:      false,
-					MaxPoolSize: 0,
+					Host:              "",
+					Port:              0,
+					User:              "",
+					Password:          "",
+					DB:                "",
+					TLS:               config.TLSConfig{Mode: "", RootCert: "", ClientCert: "", ClientKey: "", ServerName: ""},
+					MaxPoolSize:       0,
+					MinPoolSize:       0,
+					MaxConnLifetime:   0,
+					MaxConnIdleTime:   0,
+					HealthCheckPeriod: 0,
+					ApplicationName:   "",
+					SSHTunnel:         nil,
 				},
 				DefaultSchema: "",
 				Tables: []config.TargetTable{
 					{Table: "public.known", Fields: nil},
 				},
+				RequireApproval:  false,
+				ReadOnly:         false,
+				StatementTimeout: 0,
+				Limits:           config.ResultLimits{MaxRows: 0, MaxBytes: 0},
+				RoleLimits:       nil,
+				Impersonation:    nil,
+				Replicas:         nil,
+				MaxReplicaLag:    0,
 			},
 		},
 		Users: config.UsersProviderOIDC{
@@ -113,7 +292,8 @@ func validConfigForTest() config.Config {
 			},
 		},
 		Policy: config.PolicyConfig{
-			Path: "./opa",
+			Path:   "./opa",
+			Remote: nil,
 		},
 		Facade: config.FacadeConfig{
 			Port:               0,
@@ -126,8 +306,72 @@ func validConfigForTest() config.Config {
 			Database:    "",
 			Username:    "",
 			Password:    "",
-			UseSSL:      false,
+			TLS:         config.TLSConfig{Mode: "", RootCert: "", ClientCert: "", ClientKey: "", ServerName: ""},
 			MaxPoolSize: 0,
 		},
+		BreakGlass:  config.BreakGlassConfig{MaxTTL: 0},
+		Preview:     config.PreviewConfig{TTL: 0},
+		Async:       config.AsyncConfig{Workers: 0, QueueSize: 0},
+		HealthCheck: config.HealthCheckConfig{Interval: 0, Timeout: 0},
+	}
+}
+
+func TestDurationJSON(t *testing.T) {
+	t.Parallel()
+
+	var cfg config.RemotePolicyConfig
+	require.NoError(t, json.Unmarshal([]byte(`{"url": "http://opa:8181", "timeout": "1m30s"}`), &cfg))
+	require.Equal(t, 90*time.Second, cfg.Timeout.D())
+
+	buf, err := json.Marshal(cfg.Timeout)
+	require.NoError(t, err)
+	require.JSONEq(t, `"1m30s"`, string(buf))
+
+	require.Error(t, json.Unmarshal([]byte(`{"timeout": 5}`), &cfg))
+	require.Error(t, json.Unmarshal([]byte(`{"timeout": "5 seconds"}`), &cfg))
+}
+
+func TestLegacyUseSSL(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name    string
+		json    string
+		want    config.TLSMode
+		wantErr bool
+	}{
+		{name: "not set", json: `{}`, want: "", wantErr: false},
+		{name: "false", json: `{"use_ssl": false}`, want: "", wantErr: false},
+		{name: "true means require", json: `{"use_ssl": true}`, want: config.TLSModeRequire, wantErr: false},
+		{
+			name:    "stricter mode is kept",
+			json:    `{"use_ssl": true, "tls": {"mode": "verify-full"}}`,
+			want:    config.TLSModeVerifyFull,
+			wantErr: false,
+		},
+		{name: "conflicts with disable", json: `{"use_ssl": true, "tls": {"mode": "disable"}}`, want: "", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var conn config.Connection
+			var storage config.PostgresConfig
+
+			connErr := json.Unmarshal([]byte(tc.json), &conn)
+			storageErr := json.Unmarshal([]byte(tc.json), &storage)
+			if tc.wantErr {
+				require.Error(t, connErr)
+				require.Error(t, storageErr)
+
+				return
+			}
+
+			require.NoError(t, connErr)
+			require.NoError(t, storageErr)
+			require.Equal(t, tc.want, conn.TLS.Mode)
+			require.Equal(t, tc.want, storage.TLS.Mode)
+		})
 	}
 }
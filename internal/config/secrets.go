@@ -0,0 +1,160 @@
+// Database Gateway provides access to servers with ACL for safe and restricted database interactions.
+// Copyright (C) 2024  Kirill Zhuravlev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	SecretSchemeEnv  = "env"
+	SecretSchemeFile = "file"
+)
+
+// SecretResolver returns the value of a secret. key is the part of the reference after the scheme, like VAR for
+// env:VAR.
+type SecretResolver interface {
+	Resolve(ctx context.Context, key string) (string, error)
+}
+
+// Secrets resolves secret references like env:VAR or file:/run/secrets/x. Values that do not start with a registered
+// scheme are literal secrets. There is no escape: a literal that starts with a registered scheme is always read as a
+// reference, so such secrets have to be passed through a reference themselves.
+type Secrets struct {
+	resolvers map[string]SecretResolver
+}
+
+// NewSecrets returns resolver of env: and file: references.
+func NewSecrets() *Secrets {
+	return &Secrets{
+		resolvers: map[string]SecretResolver{
+			SecretSchemeEnv:  EnvSecretResolver{},
+			SecretSchemeFile: FileSecretResolver{},
+		},
+	}
+}
+
+// Register adds a resolver for references with the scheme. It replaces the resolver that was registered before.
+func (s *Secrets) Register(scheme string, resolver SecretResolver) {
+	s.resolvers[scheme] = resolver
+}
+
+// IsReference reports whether the value refers to a secret instead of being the secret.
+func (s *Secrets) IsReference(value string) bool {
+	_, _, ok := s.parse(value)
+
+	return ok
+}
+
+// Resolve returns the secret. Literal values are returned as is.
+func (s *Secrets) Resolve(ctx context.Context, value string) (string, error) {
+	scheme, key, ok := s.parse(value)
+	if !ok {
+		return value, nil
+	}
+
+	// NOTE: the value is not printed, because it may be a literal secret that only looks like a reference.
+	secret, err := s.resolvers[scheme].Resolve(ctx, key)
+	if err != nil {
+		return "", fmt.Errorf("value starts with %q and is read as a secret reference: %w", scheme+":", err)
+	}
+
+	return secret, nil
+}
+
+func (s *Secrets) parse(value string) (string, string, bool) {
+	scheme, key, ok := strings.Cut(value, ":")
+	if !ok {
+		return "", "", false
+	}
+
+	if _, ok := s.resolvers[scheme]; !ok {
+		return "", "", false
+	}
+
+	return scheme, key, true
+}
+
+// EnvSecretResolver reads secrets from environment variables.
+type EnvSecretResolver struct{}
+
+func (EnvSecretResolver) Resolve(_ context.Context, key string) (string, error) {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return "", fmt.Errorf("environment variable %q is not set", key) //nolint:err113
+	}
+
+	return value, nil
+}
+
+// FileSecretResolver reads secrets from files, like docker and kubernetes secrets. The file is read on every call, so
+// rotated secrets are picked up. Trailing newline is trimmed.
+type FileSecretResolver struct{}
+
+func (FileSecretResolver) Resolve(_ context.Context, key string) (string, error) {
+	buf, err := os.ReadFile(key)
+	if err != nil {
+		return "", fmt.Errorf("read secret file: %w", err)
+	}
+
+	return strings.TrimRight(string(buf), "\r\n"), nil
+}
+
+// ResolveSecrets replaces secret references of storage, oidc, facade and remote policy with their values. Passwords of
+// targets and their replicas stay references, because pools resolve them on every new connection; they are only
+// checked here.
+func (c *Config) ResolveSecrets(ctx context.Context, secrets *Secrets) error {
+	type secretField struct {
+		name  string
+		value *string
+	}
+
+	fields := []secretField{
+		{name: "storage.password", value: &c.Storage.Password},
+		{name: "users.client_secret", value: &c.Users.ClientSecret},
+		{name: "facade.cookie_secret", value: &c.Facade.CookieSecret},
+	}
+	if c.Policy.Remote != nil {
+		fields = append(fields, secretField{name: "policy.remote.auth_header", value: &c.Policy.Remote.AuthHeader})
+	}
+	for _, field := range fields {
+		value, err := secrets.Resolve(ctx, *field.value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", field.name, err)
+		}
+
+		*field.value = value
+	}
+
+	for i := range c.Targets {
+		target := &c.Targets[i]
+		if _, err := secrets.Resolve(ctx, target.Connection.Password); err != nil {
+			return fmt.Errorf("password of target %q: %w", target.ID, err)
+		}
+
+		for j := range target.Replicas {
+			if _, err := secrets.Resolve(ctx, target.Replicas[j].Connection.Password); err != nil {
+				return fmt.Errorf("password of replica %q of target %q: %w", target.Replicas[j].ID, target.ID, err)
+			}
+		}
+	}
+
+	return nil
+}
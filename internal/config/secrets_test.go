@@ -0,0 +1,135 @@
+// Database Gateway provides access to servers with ACL for safe and restricted database interactions.
+// Copyright (C) 2024  Kirill Zhuravlev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package config_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kazhuravlev/database-gateway/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+type staticResolver map[string]string
+
+func (r staticResolver) Resolve(_ context.Context, key string) (string, error) {
+	return r[key], nil
+}
+
+func TestSecrets(t *testing.T) { //nolint:paralleltest // uses t.Setenv
+	ctx := context.Background()
+	secrets := config.NewSecrets()
+
+	t.Run("literal", func(t *testing.T) {
+		for _, value := range []string{"", "pg01", "pass:word"} {
+			require.False(t, secrets.IsReference(value))
+
+			got, err := secrets.Resolve(ctx, value)
+			require.NoError(t, err)
+			require.Equal(t, value, got)
+		}
+	})
+
+	t.Run("env", func(t *testing.T) {
+		t.Setenv("DBGW_TEST_SECRET", "from-env")
+
+		got, err := secrets.Resolve(ctx, "env:DBGW_TEST_SECRET")
+		require.NoError(t, err)
+		require.Equal(t, "from-env", got)
+
+		_, err = secrets.Resolve(ctx, "env:DBGW_TEST_MISSING")
+		require.ErrorContains(t, err, "DBGW_TEST_MISSING")
+	})
+
+	t.Run("file is read on every call", func(t *testing.T) {
+		filename := filepath.Join(t.TempDir(), "password")
+		require.NoError(t, os.WriteFile(filename, []byte("first\n"), 0o600))
+
+		got, err := secrets.Resolve(ctx, "file:"+filename)
+		require.NoError(t, err)
+		require.Equal(t, "first", got)
+
+		require.NoError(t, os.WriteFile(filename, []byte("second"), 0o600))
+
+		got, err = secrets.Resolve(ctx, "file:"+filename)
+		require.NoError(t, err)
+		require.Equal(t, "second", got)
+	})
+
+	t.Run("custom resolver", func(t *testing.T) {
+		custom := config.NewSecrets()
+		custom.Register("vault", staticResolver{"db/pg01": "from-vault"})
+
+		require.True(t, custom.IsReference("vault:db/pg01"))
+
+		got, err := custom.Resolve(ctx, "vault:db/pg01")
+		require.NoError(t, err)
+		require.Equal(t, "from-vault", got)
+	})
+}
+
+func TestConfigResolveSecrets(t *testing.T) {
+	t.Parallel()
+
+	secrets := config.NewSecrets()
+	secrets.Register("test", staticResolver{
+		"storage": "storage-password",
+		"oidc":    "client-secret",
+		"cookie":  "cookie-secret",
+		"opa":     "Bearer opa-token",
+	})
+
+	cfg := validConfigForTest()
+	cfg.Storage.Password = "test:storage"
+	cfg.Users.ClientSecret = "test:oidc"
+	cfg.Facade.CookieSecret = "test:cookie"
+	cfg.Targets[0].Connection.Password = "test:target"
+	cfg.Policy.Remote = &config.RemotePolicyConfig{URL: "http://opa:8181", Timeout: 0, AuthHeader: "test:opa"}
+
+	require.NoError(t, cfg.ResolveSecrets(context.Background(), secrets))
+	require.Equal(t, "storage-password", cfg.Storage.Password)
+	require.Equal(t, "client-secret", cfg.Users.ClientSecret)
+	require.Equal(t, "cookie-secret", cfg.Facade.CookieSecret)
+	require.Equal(t, "test:target", cfg.Targets[0].Connection.Password)
+	require.Equal(t, "Bearer opa-token", cfg.Policy.Remote.AuthHeader)
+
+	cfg.Targets[0].Connection.Password = "file:/nonexistent/dbgw/password"
+	require.ErrorContains(t, cfg.ResolveSecrets(context.Background(), secrets), "password of target")
+
+	cfg.Targets[0].Connection.Password = "test:target"
+	cfg.Targets[0].Replicas = []config.Replica{{
+		ID:         "replica-1",
+		Connection: config.Connection{Password: "file:/nonexistent/dbgw/replica_password"}, //nolint:exhaustruct
+	}}
+	require.ErrorContains(t, cfg.ResolveSecrets(context.Background(), secrets), `password of replica "replica-1"`)
+}
+
+func TestConfigResolveSecretsLiteralLikeReference(t *testing.T) {
+	t.Parallel()
+
+	cfg := validConfigForTest()
+	cfg.Storage.Password = "file:/nonexistent/dbgw/literal"
+
+	err := cfg.ResolveSecrets(context.Background(), config.NewSecrets())
+	require.ErrorContains(t, err, `storage.password: value starts with "file:" and is read as a secret reference`)
+
+	cfg = validConfigForTest()
+	cfg.Policy.Remote = &config.RemotePolicyConfig{URL: "http://opa:8181", Timeout: 0, AuthHeader: "file:/nonexistent/dbgw/token"}
+	require.ErrorContains(t, cfg.ResolveSecrets(context.Background(), config.NewSecrets()), "policy.remote.auth_header")
+}
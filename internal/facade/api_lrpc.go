@@ -27,6 +27,7 @@ import (
 	"github.com/kazhuravlev/database-gateway/internal/config"
 	"github.com/kazhuravlev/database-gateway/internal/structs"
 	"github.com/kazhuravlev/database-gateway/internal/uuid6"
+	"github.com/kazhuravlev/database-gateway/internal/validator"
 	"github.com/kazhuravlev/just"
 	"github.com/kazhuravlev/lrpc/ctypes"
 )
@@ -78,6 +79,24 @@ func (s *Service) lrpcTargetGet(ctx context.Context, _ ctypes.ID, req lrpcTarget
 	return &lrpcTargetGetResp{Target: *target}, nil
 }
 
+type lrpcAdminTargetPoolsListResp struct {
+	Pools []structs.TargetPoolStats `json:"pools"`
+}
+
+func (s *Service) lrpcAdminTargetPoolsList(ctx context.Context, _ ctypes.ID, _ any) (*lrpcAdminTargetPoolsListResp, error) {
+	user, err := userFromAPIToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	pools, err := s.opts.app.GetTargetPoolStats(ctx, user)
+	if err != nil {
+		return nil, fmt.Errorf("get target pool stats: %w", err)
+	}
+
+	return &lrpcAdminTargetPoolsListResp{Pools: pools}, nil
+}
+
 type Bookmark struct {
 	ID       string          `json:"id"`
 	TargetID config.TargetID `json:"target_id"`
@@ -296,11 +315,50 @@ func (*Service) lrpcProfileGet(ctx context.Context, _ ctypes.ID, _ any) (*lrpcPr
 type lrpcQueryRunReq struct {
 	TargetID string `json:"target_id"`
 	Query    string `json:"query"`
+	// QueryID is an optional client-generated uuid that allows cancelling the query with query.cancel while it runs.
+	// Ids that are already taken are refused before the query runs.
+	QueryID string `json:"query_id,omitempty"`
+	// Preview runs UPDATE/DELETE inside a transaction that waits for query.commit or query.rollback.
+	Preview bool `json:"preview,omitempty"`
+	// Primary runs a select-only query on the primary of the target instead of a read replica.
+	Primary bool `json:"primary,omitempty"`
+}
+
+func queryRoute(primary bool) app.QueryRoute {
+	return just.If(primary, app.QueryRoutePrimary, app.QueryRouteAuto)
 }
 
 type lrpcQueryRunResp struct {
 	QueryID string         `json:"query_id"`
 	Table   structs.QTable `json:"table"`
+	// Denied is filled instead of results when policy refuses the query.
+	Denied *lrpcQueryDenied `json:"denied,omitempty"`
+	// Pending is filled instead of results when the query waits for approval.
+	Pending *lrpcQueryPending `json:"pending,omitempty"`
+	// Cancelled is filled instead of results when the query was stopped. The cancellation is stored under QueryID.
+	Cancelled *lrpcQueryCancelled `json:"cancelled,omitempty"`
+	// Preview is filled for previewed writes. Table contains rows returned by RETURNING clause.
+	Preview *lrpcQueryPreview `json:"preview,omitempty"`
+}
+
+type lrpcQueryCancelled struct {
+	Reason string `json:"reason"`
+}
+
+type lrpcQueryPending struct {
+	ApprovalID string `json:"approval_id"`
+}
+
+type lrpcQueryPreview struct {
+	AffectedRows int64  `json:"affected_rows"`
+	ExpiresAt    string `json:"expires_at"`
+}
+
+type lrpcQueryDenied struct {
+	Op      string   `json:"op"`
+	Table   string   `json:"table"`
+	Columns []string `json:"columns"`
+	Reasons []string `json:"reasons"`
 }
 
 func (s *Service) lrpcQueryRun(ctx context.Context, _ ctypes.ID, req lrpcQueryRunReq) (*lrpcQueryRunResp, error) {
@@ -315,17 +373,377 @@ func (s *Service) lrpcQueryRun(ctx context.Context, _ ctypes.ID, req lrpcQueryRu
 		return nil, fmt.Errorf("target_id and query are required: %w", errBadInput)
 	}
 
-	queryID, table, err := s.opts.app.RunQuery(ctx, user, config.TargetID(targetID), query)
+	clientQueryID := uuid6.Nil()
+	if raw := strings.TrimSpace(req.QueryID); raw != "" {
+		clientQueryID, err = uuid6.ParseStr(raw)
+		if err != nil {
+			return nil, fmt.Errorf("bad query id: %w", errBadInput)
+		}
+	}
+
+	if req.Preview {
+		preview, err := s.opts.app.PreviewQuery(ctx, user, config.TargetID(targetID), clientQueryID, query)
+		if err != nil {
+			return adaptQueryRunError(err)
+		}
+
+		return &lrpcQueryRunResp{
+			QueryID:   preview.ID.S(),
+			Table:     preview.Table,
+			Denied:    nil,
+			Pending:   nil,
+			Cancelled: nil,
+			Preview: &lrpcQueryPreview{
+				AffectedRows: preview.AffectedRows,
+				ExpiresAt:    preview.ExpiresAt.Format(time.RFC3339),
+			},
+		}, nil
+	}
+
+	queryID, table, err := s.opts.app.RunQuery(ctx, user, config.TargetID(targetID), clientQueryID, query, queryRoute(req.Primary))
 	if err != nil {
-		return nil, fmt.Errorf("run query: %w", err)
+		return adaptQueryRunError(err)
 	}
 
 	return &lrpcQueryRunResp{
+		QueryID:   queryID.S(),
+		Table:     *table,
+		Denied:    nil,
+		Pending:   nil,
+		Cancelled: nil,
+		Preview:   nil,
+	}, nil
+}
+
+// adaptQueryRunError turns expected outcomes of query.run into a response. Other errors are returned as is.
+func adaptQueryRunError(err error) (*lrpcQueryRunResp, error) {
+	if denied, ok := just.ErrAs[*validator.AccessDeniedError](err); ok {
+		return &lrpcQueryRunResp{
+			QueryID: "",
+			Table:   structs.QTable{Headers: nil, Columns: nil, Rows: nil},
+			Denied: &lrpcQueryDenied{
+				Op:      denied.Vec.Op.S(),
+				Table:   denied.Vec.Tbl,
+				Columns: just.If(denied.Vec.Cols == nil, []string{}, denied.Vec.Cols),
+				Reasons: just.If(denied.Reasons == nil, []string{}, denied.Reasons),
+			},
+			Pending:   nil,
+			Cancelled: nil,
+			Preview:   nil,
+		}, nil
+	}
+
+	if pending, ok := just.ErrAs[*app.ApprovalRequiredError](err); ok {
+		return &lrpcQueryRunResp{
+			QueryID:   "",
+			Table:     structs.QTable{Headers: nil, Columns: nil, Rows: nil},
+			Denied:    nil,
+			Pending:   &lrpcQueryPending{ApprovalID: pending.ApprovalID.S()},
+			Cancelled: nil,
+			Preview:   nil,
+		}, nil
+	}
+
+	if cancelled, ok := just.ErrAs[*app.QueryCancelledError](err); ok {
+		return &lrpcQueryRunResp{
+			QueryID:   cancelled.QueryID.S(),
+			Table:     structs.QTable{Headers: nil, Columns: nil, Rows: nil},
+			Denied:    nil,
+			Pending:   nil,
+			Cancelled: &lrpcQueryCancelled{Reason: cancelled.Reason},
+			Preview:   nil,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("run query: %w", err)
+}
+
+type lrpcQuerySubmitReq struct {
+	TargetID string `json:"target_id"`
+	Query    string `json:"query"`
+	// QueryID is an optional client-generated uuid. The id of the query is returned in any case.
+	QueryID string `json:"query_id,omitempty"`
+	// Primary runs a select-only query on the primary of the target instead of a read replica.
+	Primary bool `json:"primary,omitempty"`
+}
+
+type lrpcQuerySubmitResp struct {
+	QueryID string `json:"query_id"`
+	// Denied is filled instead of query id when policy refuses the query.
+	Denied *lrpcQueryDenied `json:"denied,omitempty"`
+	// Pending is filled instead of query id when the query waits for approval.
+	Pending *lrpcQueryPending `json:"pending,omitempty"`
+}
+
+func (s *Service) lrpcQuerySubmit(ctx context.Context, _ ctypes.ID, req lrpcQuerySubmitReq) (*lrpcQuerySubmitResp, error) {
+	user, err := userFromAPIToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	targetID := strings.TrimSpace(req.TargetID)
+	query := strings.TrimSpace(req.Query)
+	if targetID == "" || query == "" {
+		return nil, fmt.Errorf("target_id and query are required: %w", errBadInput)
+	}
+
+	clientQueryID := uuid6.Nil()
+	if raw := strings.TrimSpace(req.QueryID); raw != "" {
+		clientQueryID, err = uuid6.ParseStr(raw)
+		if err != nil {
+			return nil, fmt.Errorf("bad query id: %w", errBadInput)
+		}
+	}
+
+	queryID, err := s.opts.app.SubmitQuery(ctx, user, config.TargetID(targetID), clientQueryID, query, queryRoute(req.Primary))
+	if err != nil {
+		resp, err := adaptQueryRunError(err)
+		if err != nil {
+			return nil, fmt.Errorf("submit query: %w", err)
+		}
+
+		return &lrpcQuerySubmitResp{
+			QueryID: "",
+			Denied:  resp.Denied,
+			Pending: resp.Pending,
+		}, nil
+	}
+
+	return &lrpcQuerySubmitResp{
 		QueryID: queryID.S(),
+		Denied:  nil,
+		Pending: nil,
+	}, nil
+}
+
+type lrpcQueryStatusReq struct {
+	QueryID string `json:"query_id"`
+}
+
+type lrpcQueryStatusResp struct {
+	QueryID   string              `json:"query_id"`
+	TargetID  config.TargetID     `json:"target_id"`
+	Status    structs.QueryStatus `json:"status"`
+	Error     string              `json:"error,omitempty"`
+	CreatedAt string              `json:"created_at"`
+}
+
+func (s *Service) lrpcQueryStatus(ctx context.Context, _ ctypes.ID, req lrpcQueryStatusReq) (*lrpcQueryStatusResp, error) {
+	user, err := userFromAPIToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	queryID, err := uuid6.ParseStr(strings.TrimSpace(req.QueryID))
+	if err != nil {
+		return nil, fmt.Errorf("bad query id: %w", errBadInput)
+	}
+
+	state, err := s.opts.app.GetQueryState(ctx, user, queryID)
+	if err != nil {
+		return nil, fmt.Errorf("get query state: %w", err)
+	}
+
+	return &lrpcQueryStatusResp{
+		QueryID:   state.ID.S(),
+		TargetID:  state.TargetID,
+		Status:    state.Status,
+		Error:     state.Error,
+		CreatedAt: state.CreatedAt.Format(time.RFC3339),
+	}, nil
+}
+
+type lrpcQueryFinishReq struct {
+	QueryID string `json:"query_id"`
+}
+
+type lrpcQueryCommitResp struct {
+	QueryID string         `json:"query_id"`
+	Table   structs.QTable `json:"table"`
+}
+
+func (s *Service) lrpcQueryCommit(ctx context.Context, _ ctypes.ID, req lrpcQueryFinishReq) (*lrpcQueryCommitResp, error) {
+	user, err := userFromAPIToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	queryID, err := uuid6.ParseStr(strings.TrimSpace(req.QueryID))
+	if err != nil {
+		return nil, fmt.Errorf("bad query id: %w", errBadInput)
+	}
+
+	resID, table, err := s.opts.app.CommitQuery(ctx, user, queryID)
+	if err != nil {
+		return nil, fmt.Errorf("commit query: %w", err)
+	}
+
+	return &lrpcQueryCommitResp{
+		QueryID: resID.S(),
 		Table:   *table,
 	}, nil
 }
 
+func (s *Service) lrpcQueryRollback(ctx context.Context, _ ctypes.ID, req lrpcQueryFinishReq) (*struct{}, error) {
+	user, err := userFromAPIToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	queryID, err := uuid6.ParseStr(strings.TrimSpace(req.QueryID))
+	if err != nil {
+		return nil, fmt.Errorf("bad query id: %w", errBadInput)
+	}
+
+	if err := s.opts.app.RollbackQuery(ctx, user, queryID); err != nil {
+		return nil, fmt.Errorf("rollback query: %w", err)
+	}
+
+	return &struct{}{}, nil
+}
+
+type lrpcQueryCancelReq struct {
+	QueryID string `json:"query_id"`
+}
+
+func (s *Service) lrpcQueryCancel(ctx context.Context, _ ctypes.ID, req lrpcQueryCancelReq) (*struct{}, error) {
+	user, err := userFromAPIToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	queryID, err := uuid6.ParseStr(strings.TrimSpace(req.QueryID))
+	if err != nil {
+		return nil, fmt.Errorf("bad query id: %w", errBadInput)
+	}
+
+	if err := s.opts.app.CancelQuery(ctx, user, queryID); err != nil {
+		return nil, fmt.Errorf("cancel query: %w", err)
+	}
+
+	return &struct{}{}, nil
+}
+
+type QueryApproval struct {
+	ID            string                `json:"id"`
+	UserID        config.UserID         `json:"user_id"`
+	TargetID      config.TargetID       `json:"target_id"`
+	Query         string                `json:"query"`
+	Vectors       []structs.QueryVector `json:"vectors"`
+	Status        string                `json:"status"`
+	ReviewerID    config.UserID         `json:"reviewer_id"`
+	ReviewComment string                `json:"review_comment"`
+	Error         string                `json:"error"`
+	QueryResultID string                `json:"query_result_id"`
+	CreatedAt     string                `json:"created_at"`
+	ReviewedAt    string                `json:"reviewed_at"`
+}
+
+type lrpcApprovalsListReq struct {
+	// Pending switches the list from own requests to the review queue.
+	Pending bool  `json:"pending"`
+	Limit   int64 `json:"limit"`
+}
+
+type lrpcApprovalsListResp struct {
+	Approvals []QueryApproval `json:"approvals"`
+}
+
+type lrpcApprovalsReviewReq struct {
+	ID      string `json:"id"`
+	Comment string `json:"comment"`
+}
+
+type lrpcApprovalsApproveResp struct {
+	QueryID string         `json:"query_id"`
+	Table   structs.QTable `json:"table"`
+}
+
+func (s *Service) lrpcApprovalsList(ctx context.Context, _ ctypes.ID, req lrpcApprovalsListReq) (*lrpcApprovalsListResp, error) {
+	user, err := userFromAPIToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := req.Limit
+	if limit <= 0 {
+		limit = 50
+	}
+
+	var approvals []structs.QueryApproval
+	if req.Pending {
+		approvals, err = s.opts.app.ListPendingApprovals(ctx, user, limit)
+	} else {
+		approvals, err = s.opts.app.ListUserApprovals(ctx, user.ID, limit)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("list approvals: %w", err)
+	}
+
+	return &lrpcApprovalsListResp{
+		Approvals: just.SliceMap(approvals, func(item structs.QueryApproval) QueryApproval {
+			return QueryApproval{
+				ID:            item.ID,
+				UserID:        item.UserID,
+				TargetID:      item.TargetID,
+				Query:         item.Query,
+				Vectors:       just.If(item.Vectors == nil, []structs.QueryVector{}, item.Vectors),
+				Status:        item.Status.S(),
+				ReviewerID:    item.ReviewerID,
+				ReviewComment: item.ReviewComment,
+				Error:         item.Error,
+				QueryResultID: item.QueryResultID,
+				CreatedAt:     item.CreatedAt,
+				ReviewedAt:    item.ReviewedAt,
+			}
+		}),
+	}, nil
+}
+
+func (s *Service) lrpcApprovalsApprove(
+	ctx context.Context,
+	_ ctypes.ID,
+	req lrpcApprovalsReviewReq,
+) (*lrpcApprovalsApproveResp, error) {
+	user, err := userFromAPIToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	approvalID, err := uuid6.ParseStr(strings.TrimSpace(req.ID))
+	if err != nil {
+		return nil, fmt.Errorf("bad approval id: %w", errBadInput)
+	}
+
+	queryID, table, err := s.opts.app.ApproveQuery(ctx, user, approvalID, req.Comment)
+	if err != nil {
+		return nil, fmt.Errorf("approve query: %w", err)
+	}
+
+	return &lrpcApprovalsApproveResp{
+		QueryID: queryID.S(),
+		Table:   *table,
+	}, nil
+}
+
+func (s *Service) lrpcApprovalsReject(ctx context.Context, _ ctypes.ID, req lrpcApprovalsReviewReq) (*struct{}, error) {
+	user, err := userFromAPIToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	approvalID, err := uuid6.ParseStr(strings.TrimSpace(req.ID))
+	if err != nil {
+		return nil, fmt.Errorf("bad approval id: %w", errBadInput)
+	}
+
+	if err := s.opts.app.RejectQuery(ctx, user, approvalID, req.Comment); err != nil {
+		return nil, fmt.Errorf("reject query: %w", err)
+	}
+
+	return &struct{}{}, nil
+}
+
 type lrpcQueryResultsGetReq struct {
 	ID            string `json:"id,omitempty"`
 	QueryResultID string `json:"query_result_id,omitempty"`
@@ -337,6 +755,8 @@ type lrpcQueryResultsGetResp struct {
 	TargetID  config.TargetID `json:"target_id"`
 	Query     string          `json:"query"`
 	CreatedAt string          `json:"created_at"`
+	Status    string          `json:"status"`
+	Error     string          `json:"error,omitempty"`
 	Table     structs.QTable  `json:"table"`
 	Meta      structs.QMeta   `json:"meta"`
 }
@@ -381,6 +801,8 @@ func (s *Service) lrpcQueryResultsGet(
 		TargetID:  config.TargetID(item.TargetID),
 		Query:     item.Query,
 		CreatedAt: item.CreatedAt.Format(time.RFC3339),
+		Status:    item.Status.S(),
+		Error:     item.Error,
 		Table:     item.QTable,
 		Meta:      item.Meta,
 	}, nil
@@ -422,6 +844,100 @@ func (s *Service) lrpcQueryResultsExportLink(
 	}, nil
 }
 
+type BreakGlassGrant struct {
+	ID        string          `json:"id"`
+	UserID    config.UserID   `json:"user_id"`
+	TargetID  config.TargetID `json:"target_id"`
+	Reason    string          `json:"reason"`
+	CreatedAt string          `json:"created_at"`
+	ExpiresAt string          `json:"expires_at"`
+}
+
+type lrpcBreakGlassRequestReq struct {
+	TargetID string          `json:"target_id"`
+	Reason   string          `json:"reason"`
+	Duration config.Duration `json:"duration"` // like "30m"
+}
+
+type lrpcBreakGlassRequestResp struct {
+	Grant BreakGlassGrant `json:"grant"`
+}
+
+type lrpcBreakGlassListResp struct {
+	Grants []BreakGlassGrant `json:"grants"`
+}
+
+type lrpcBreakGlassRevokeReq struct {
+	ID string `json:"id"`
+}
+
+func (s *Service) lrpcBreakGlassRequest(
+	ctx context.Context,
+	_ ctypes.ID,
+	req lrpcBreakGlassRequestReq,
+) (*lrpcBreakGlassRequestResp, error) {
+	user, err := userFromAPIToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	targetID := strings.TrimSpace(req.TargetID)
+	reason := strings.TrimSpace(req.Reason)
+	if targetID == "" || reason == "" || req.Duration <= 0 {
+		return nil, fmt.Errorf("target_id, reason and positive duration are required: %w", errBadInput)
+	}
+
+	grant, err := s.opts.app.RequestBreakGlass(ctx, user, config.TargetID(targetID), reason, req.Duration.D())
+	if err != nil {
+		return nil, fmt.Errorf("request break glass: %w", err)
+	}
+
+	return &lrpcBreakGlassRequestResp{Grant: adaptBreakGlassGrant(*grant)}, nil
+}
+
+func (s *Service) lrpcBreakGlassList(ctx context.Context, _ ctypes.ID, _ any) (*lrpcBreakGlassListResp, error) {
+	user, err := userFromAPIToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	grants, err := s.opts.app.ListBreakGlassGrants(ctx, user)
+	if err != nil {
+		return nil, fmt.Errorf("list break glass grants: %w", err)
+	}
+
+	return &lrpcBreakGlassListResp{Grants: just.SliceMap(grants, adaptBreakGlassGrant)}, nil
+}
+
+func (s *Service) lrpcBreakGlassRevoke(ctx context.Context, _ ctypes.ID, req lrpcBreakGlassRevokeReq) (*struct{}, error) {
+	user, err := userFromAPIToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	grantID, err := uuid6.ParseStr(strings.TrimSpace(req.ID))
+	if err != nil {
+		return nil, fmt.Errorf("bad grant id: %w", errBadInput)
+	}
+
+	if err := s.opts.app.RevokeBreakGlass(ctx, user, grantID); err != nil {
+		return nil, fmt.Errorf("revoke break glass: %w", err)
+	}
+
+	return &struct{}{}, nil
+}
+
+func adaptBreakGlassGrant(grant structs.BreakGlassGrant) BreakGlassGrant { //nolint:gocritic
+	return BreakGlassGrant{
+		ID:        grant.ID,
+		UserID:    grant.UserID,
+		TargetID:  grant.TargetID,
+		Reason:    grant.Reason,
+		CreatedAt: grant.CreatedAt.Format(time.RFC3339),
+		ExpiresAt: grant.ExpiresAt.Format(time.RFC3339),
+	}
+}
+
 func userFromAPIToken(ctx context.Context) (structs.User, error) {
 	user, ok := ctx.Value(ctxAPITokenUser).(structs.User)
 	if !ok {
@@ -23,7 +23,6 @@ import (
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/base64"
-	"encoding/csv"
 	"encoding/gob"
 	"encoding/json"
 	"errors"
@@ -56,6 +55,8 @@ const (
 	keyUserID      = "uid"
 	keyOIDCState   = "oidc-state"
 	exportNonceLen = 8
+
+	headerResultTruncated = "X-Result-Truncated"
 )
 
 var (
@@ -229,9 +230,12 @@ func (s *Service) Run(_ context.Context) error {
 
 	{
 		errorMapping := map[error]ctypes.ErrorCode{
-			errBadInput:      400,
-			app.ErrForbidden: 403,
-			app.ErrNotFound:  404,
+			errBadInput:                400,
+			app.ErrForbidden:           403,
+			app.ErrNotFound:            404,
+			app.ErrConflict:            409,
+			app.ErrPreviewNotSupported: 400,
+			app.ErrQueueFull:           503,
 		}
 
 		lrpcserver.RegisterHandler(s.lrpc, "profile.get.v1", s.lrpcProfileGet, errorMapping)
@@ -242,7 +246,19 @@ func (s *Service) Run(_ context.Context) error {
 		lrpcserver.RegisterHandler(s.lrpc, "bookmarks.delete.v1", s.lrpcBookmarksDelete, errorMapping)
 		lrpcserver.RegisterHandler(s.lrpc, "queries.list.v1", s.lrpcQueriesList, errorMapping)
 		lrpcserver.RegisterHandler(s.lrpc, "admin.requests.list.v1", s.lrpcAdminRequestsList, errorMapping)
+		lrpcserver.RegisterHandler(s.lrpc, "admin.target-pools.list.v1", s.lrpcAdminTargetPoolsList, errorMapping)
 		lrpcserver.RegisterHandler(s.lrpc, "query.run.v1", s.lrpcQueryRun, errorMapping)
+		lrpcserver.RegisterHandler(s.lrpc, "query.submit.v1", s.lrpcQuerySubmit, errorMapping)
+		lrpcserver.RegisterHandler(s.lrpc, "query.status.v1", s.lrpcQueryStatus, errorMapping)
+		lrpcserver.RegisterHandler(s.lrpc, "query.cancel.v1", s.lrpcQueryCancel, errorMapping)
+		lrpcserver.RegisterHandler(s.lrpc, "query.commit.v1", s.lrpcQueryCommit, errorMapping)
+		lrpcserver.RegisterHandler(s.lrpc, "query.rollback.v1", s.lrpcQueryRollback, errorMapping)
+		lrpcserver.RegisterHandler(s.lrpc, "approvals.list.v1", s.lrpcApprovalsList, errorMapping)
+		lrpcserver.RegisterHandler(s.lrpc, "approvals.approve.v1", s.lrpcApprovalsApprove, errorMapping)
+		lrpcserver.RegisterHandler(s.lrpc, "approvals.reject.v1", s.lrpcApprovalsReject, errorMapping)
+		lrpcserver.RegisterHandler(s.lrpc, "break-glass.request.v1", s.lrpcBreakGlassRequest, errorMapping)
+		lrpcserver.RegisterHandler(s.lrpc, "break-glass.list.v1", s.lrpcBreakGlassList, errorMapping)
+		lrpcserver.RegisterHandler(s.lrpc, "break-glass.revoke.v1", s.lrpcBreakGlassRevoke, errorMapping)
 		lrpcserver.RegisterHandler(s.lrpc, "query-results.get.v1", s.lrpcQueryResultsGet, errorMapping)
 		lrpcserver.RegisterHandler(s.lrpc, "query-results.export-link.v1", s.lrpcQueryResultsExportLink, errorMapping)
 
@@ -443,7 +459,7 @@ func (s *Service) downloadQueryResultsExport(c echo.Context) error {
 }
 
 func marshalQueryResultsJSON(qRes *app.QueryResults) ([]byte, error) {
-	qTbl := just.SliceMap(qRes.QTable.Rows, func(row []string) map[string]any {
+	qTbl := just.SliceMap(qRes.QTable.Rows, func(row []any) map[string]any {
 		m := make(map[string]any, len(qRes.QTable.Headers))
 		for i := range qRes.QTable.Headers {
 			m[qRes.QTable.Headers[i]] = row[i]
@@ -453,31 +469,83 @@ func marshalQueryResultsJSON(qRes *app.QueryResults) ([]byte, error) {
 	})
 
 	return json.Marshal(struct {
-		Meta structs.QMeta    `json:"meta"`
-		Rows []map[string]any `json:"rows"`
+		Meta    structs.QMeta     `json:"meta"`
+		Columns []structs.QColumn `json:"columns"`
+		Rows    []map[string]any  `json:"rows"`
 	}{
-		Meta: qRes.Meta,
-		Rows: qTbl,
+		Meta:    qRes.Meta,
+		Columns: just.If(qRes.QTable.Columns == nil, []structs.QColumn{}, qRes.QTable.Columns),
+		Rows:    qTbl,
 	})
 }
 
+// marshalQueryResultsCSV writes NULL as an empty field and an empty string as "", like COPY ... CSV in postgres.
+// Decoded json and arrays are written as json.
 func marshalQueryResultsCSV(qRes *app.QueryResults) ([]byte, error) {
 	var csvBuf bytes.Buffer
-	csvWriter := csv.NewWriter(&csvBuf)
-	if err := csvWriter.Write(qRes.QTable.Headers); err != nil {
-		return nil, err
-	}
-	if err := csvWriter.WriteAll(qRes.QTable.Rows); err != nil {
+	headers := just.SliceMap(qRes.QTable.Headers, func(header string) any { return header })
+	if err := writeCSVRecord(&csvBuf, headers); err != nil {
 		return nil, err
 	}
-	csvWriter.Flush()
-	if err := csvWriter.Error(); err != nil {
-		return nil, err
+	for _, row := range qRes.QTable.Rows {
+		if err := writeCSVRecord(&csvBuf, row); err != nil {
+			return nil, err
+		}
 	}
 
 	return csvBuf.Bytes(), nil
 }
 
+func writeCSVRecord(buf *bytes.Buffer, cells []any) error {
+	for i, cell := range cells {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		if cell == nil {
+			continue
+		}
+
+		text, err := csvCellText(cell)
+		if err != nil {
+			return err
+		}
+
+		if text != "" && !strings.ContainsAny(text, ",\"\r\n") && text[0] != ' ' && text[0] != '\t' {
+			buf.WriteString(text)
+
+			continue
+		}
+
+		buf.WriteByte('"')
+		buf.WriteString(strings.ReplaceAll(text, `"`, `""`))
+		buf.WriteByte('"')
+	}
+	buf.WriteString("\n")
+
+	return nil
+}
+
+func csvCellText(cell any) (string, error) {
+	switch cell := cell.(type) {
+	case string:
+		return cell, nil
+	case json.Number:
+		return cell.String(), nil
+	case bool:
+		return strconv.FormatBool(cell), nil
+	case int64:
+		return strconv.FormatInt(cell, 10), nil
+	default:
+		buf, err := json.Marshal(cell)
+		if err != nil {
+			return "", fmt.Errorf("marshal cell: %w", err)
+		}
+
+		return string(buf), nil
+	}
+}
+
 type queryResultsExportTokenClaims struct {
 	UserID        config.UserID `json:"user_id"`
 	QueryResultID uuid6.UUID    `json:"query_result_id"`
@@ -624,6 +692,10 @@ func serveExport(c echo.Context, format string, qRes *app.QueryResults) error {
 	}
 
 	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf(`%s; filename="%s"`, "attachment", filename)) //nolint
+	if qRes.Meta.Truncated {
+		// NOTE: CSV has no place for metadata, so the client learns about the cut from headers.
+		c.Response().Header().Set(headerResultTruncated, "true")
+	}
 	http.ServeContent(c.Response(), c.Request(), filename, time.Now(), bytes.NewReader(payload))
 
 	return nil
@@ -0,0 +1,75 @@
+// Database Gateway provides access to servers with ACL for safe and restricted database interactions.
+// Copyright (C) 2024  Kirill Zhuravlev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package facade //nolint:testpackage
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/kazhuravlev/database-gateway/internal/app"
+	"github.com/kazhuravlev/database-gateway/internal/structs"
+	"github.com/stretchr/testify/require"
+)
+
+func exportTestResults() *app.QueryResults {
+	return &app.QueryResults{ //nolint:exhaustruct
+		QTable: structs.QTable{
+			Headers: []string{"id", "name", "tags", "score"},
+			Columns: []structs.QColumn{
+				{Name: "id", TypeOID: 20, TypeName: "bigint", Nullable: false},
+				{Name: "name", TypeOID: 25, TypeName: "text", Nullable: true},
+				{Name: "tags", TypeOID: 1009, TypeName: "text[]", Nullable: true},
+				{Name: "score", TypeOID: 1700, TypeName: "numeric(10,2)", Nullable: true},
+			},
+			Rows: [][]any{
+				{int64(1), "plain", []any{"a", "b"}, json.Number("1.50")},
+				{int64(2), "", nil, nil},
+				{int64(3), nil, []any{}, json.Number("-2")},
+				{int64(4), "with, \"quotes\"", nil, nil},
+			},
+		},
+	}
+}
+
+func TestMarshalQueryResultsCSV(t *testing.T) {
+	t.Parallel()
+
+	buf, err := marshalQueryResultsCSV(exportTestResults())
+	require.NoError(t, err)
+	require.Equal(t, `id,name,tags,score
+1,plain,"[""a"",""b""]",1.50
+2,"",,
+3,,[],-2
+4,"with, ""quotes""",,
+`, string(buf))
+}
+
+func TestMarshalQueryResultsJSON(t *testing.T) {
+	t.Parallel()
+
+	buf, err := marshalQueryResultsJSON(exportTestResults())
+	require.NoError(t, err)
+
+	var got struct {
+		Columns []structs.QColumn `json:"columns"`
+		Rows    []json.RawMessage `json:"rows"`
+	}
+	require.NoError(t, json.Unmarshal(buf, &got))
+	require.Equal(t, exportTestResults().QTable.Columns, got.Columns)
+	require.JSONEq(t, `{"id":1,"name":"plain","tags":["a","b"],"score":1.50}`, string(got.Rows[0]))
+	require.JSONEq(t, `{"id":2,"name":"","tags":null,"score":null}`, string(got.Rows[1]))
+}
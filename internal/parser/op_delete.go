@@ -38,10 +38,18 @@ func (s *DeleteVec) Columns() []string {
 
 func (DeleteVec) isVector() {}
 
-func handleDelete(req *pg.DeleteStmt) ([]Vector, error) { //nolint:gocyclo
-	if req.UsingClause != nil ||
-		req.GetWithClause() != nil {
-		return nil, fmt.Errorf("unknown clause: %w", ErrNotImplemented)
+// handleDelete parses delete statement. Relations of the using clause and of nested selects are read, so they are
+// reported as select vectors. ctes are common table expressions visible to the statement.
+func handleDelete(req *pg.DeleteStmt, ctes cteNames) ([]Vector, error) { //nolint:gocyclo,cyclop,funlen
+	var vectors []Vector
+	if with := req.GetWithClause(); with != nil {
+		cteVectors, scope, err := handleWithClause(with, ctes, nil)
+		if err != nil {
+			return nil, fmt.Errorf("parse with clause: %w", err)
+		}
+
+		vectors = append(vectors, cteVectors...)
+		ctes = scope
 	}
 
 	tables := NewTables("public")
@@ -50,6 +58,16 @@ func handleDelete(req *pg.DeleteStmt) ([]Vector, error) { //nolint:gocyclo
 	if err != nil {
 		return nil, fmt.Errorf("failed to add table: %w", err)
 	}
+
+	for _, node := range req.GetUsingClause() {
+		usingVectors, err := collectSelectFromTables(tables, ctes, node)
+		if err != nil {
+			return nil, fmt.Errorf("parse using clause: %w", err)
+		}
+
+		vectors = append(vectors, usingVectors...)
+	}
+
 	if err := tables.Finalize(); err != nil {
 		return nil, fmt.Errorf("failed to finalize tables: %w", err)
 	}
@@ -59,32 +77,74 @@ func handleDelete(req *pg.DeleteStmt) ([]Vector, error) { //nolint:gocyclo
 		return nil, fmt.Errorf("parse returning: %w", err)
 	}
 
-	whereColumns, err := parseWhereClause(req.GetWhereClause())
+	sub := newSubqueryScope(ctes, tables)
+	whereColumns, err := parseWhereClause(req.GetWhereClause(), sub)
 	if err != nil {
 		return nil, fmt.Errorf("parse where: %w", err)
 	}
 
-	allColumns := slices.Concat(retCols, whereColumns)
+	allTables, err := tables.GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get all tables: %w", err)
+	}
 
-	table2target := make(map[string]Columns, len(allColumns))
-	table2target[fqTableName] = nil
-	for _, column := range allColumns {
-		tbl, ok := tables.Get(column.Table())
-		if !ok {
-			return nil, fmt.Errorf("table not found: %s", column.Table()) //nolint:err113
+	vec := &DeleteVec{Tbl: fqTableName, Target: nil, Filter: nil}
+	table2vec := make(map[string]*SelectVec, len(allTables))
+	for _, tableName := range allTables {
+		if tableName != fqTableName {
+			table2vec[tableName] = &SelectVec{Tbl: tableName, Target: nil, Filter: nil, Group: nil, Sort: nil}
 		}
+	}
 
-		table2target[tbl] = append(table2target[tbl], column)
+	clauses := []struct {
+		columns     Columns
+		field       func(vec *DeleteVec) *[]string
+		selectField selectField
+		mapVirtual  bool
+	}{
+		{
+			columns:     retCols,
+			field:       func(vec *DeleteVec) *[]string { return &vec.Target },
+			selectField: func(vec *SelectVec) *[]string { return &vec.Target },
+			mapVirtual:  false,
+		},
+		{
+			columns:     whereColumns,
+			field:       func(vec *DeleteVec) *[]string { return &vec.Filter },
+			selectField: func(vec *SelectVec) *[]string { return &vec.Filter },
+			mapVirtual:  true,
+		},
+	}
+	for _, clause := range clauses {
+		for _, column := range clause.columns {
+			tbl, ok, err := tables.attribute(column, clause.selectField, clause.mapVirtual)
+			if err != nil {
+				return nil, err
+			}
+
+			if !ok {
+				continue
+			}
+
+			if tbl == fqTableName {
+				field := clause.field(vec)
+				*field = append(*field, column.column)
+
+				continue
+			}
+
+			field := clause.selectField(table2vec[tbl])
+			*field = append(*field, column.column)
+		}
 	}
 
-	vectors := make([]Vector, 0, len(table2target))
-	for tbl, cols := range table2target {
-		vectors = append(vectors, DeleteVec{
-			Tbl:    tbl,
-			Target: cols.ListNames(),
-			Filter: nil, // TODO: impl
-		})
+	vectors = append(vectors, *vec)
+	for _, tableName := range allTables {
+		if sel, ok := table2vec[tableName]; ok {
+			vectors = append(vectors, sel)
+		}
 	}
+	vectors = append(vectors, sub.vectors...)
 
 	return vectors, nil
 }
@@ -57,6 +57,14 @@ func TestParseDeleteValid(t *testing.T) {
 
 	test("DELETE FROM t1 RETURNING c1")
 	test("DELETE FROM t1 WHERE c1 = 'value' RETURNING c1, c2")
+
+	test("DELETE FROM t1 WHERE c1 = (SELECT c1 FROM t2)")
+	test("DELETE FROM t1 WHERE c1 IN (SELECT c1 FROM t2 WHERE active = true)")
+	test("DELETE FROM t1 WHERE EXISTS (SELECT 1 FROM t2)")
+	test("DELETE FROM t1 WHERE NOT EXISTS (SELECT 1 FROM t2 WHERE t2.c1 = t1.c1)")
+
+	test("DELETE FROM t1 USING t2")
+	test("DELETE FROM t1 USING t2 WHERE t1.c1 = t2.c1")
 }
 
 func TestParseDeleteInvalid(t *testing.T) {
@@ -77,8 +85,5 @@ func TestParseDeleteInvalid(t *testing.T) {
 	test("DELETE FROM t1 WHERE c1 = (select 1)", "incomplete_condition")
 	test("DELETE FROM t1 RETURNING *", "returning_all")
 	test("DELETE FROM t1 WHERE c1 IN (select 1)", "empty_in_list")
-	test("DELETE FROM t1 WHERE c1 = (SELECT c1 FROM t2)", "subquery_in_where")
 	test("DELETE FROM t1 t1, t2", "multiple_tables")
-	test("DELETE FROM t1 USING t2", "using_clause")
-	test("DELETE FROM t1 WHERE EXISTS (SELECT 1 FROM t2)", "exists_subquery")
 }
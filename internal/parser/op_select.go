@@ -87,12 +87,55 @@ func isFunctionStarArg(node *pg.Node_ColumnRef) bool {
 	return ok
 }
 
-func addRangeVar(tables *Tables, tbl *pg.RangeVar) error {
+// checkVirtualStar allows star expressions only over CTE and derived tables. Their columns are covered by vectors of
+// their own selects. Stars over real tables should be expanded by ExpandStar before parsing.
+func checkVirtualStar(tables *Tables, node *pg.Node_ColumnRef) error {
+	fields := node.ColumnRef.GetFields()
+	if len(fields) == 1 {
+		allTables, err := tables.GetAll()
+		if err != nil {
+			return fmt.Errorf("failed to get all tables: %w", err)
+		}
+
+		if len(allTables) != 0 {
+			return fmt.Errorf("star expressions: %w", ErrNotImplemented)
+		}
+
+		return nil
+	}
+
+	qualifier := make([]string, 0, len(fields)-1)
+	for _, field := range fields[:len(fields)-1] {
+		str, ok := field.GetNode().(*pg.Node_String_)
+		if !ok {
+			return fmt.Errorf("columnRef field (%T): %w", field.GetNode(), ErrNotImplemented)
+		}
+
+		qualifier = append(qualifier, str.String_.GetSval())
+	}
+
+	if _, virtual, ok := tables.Resolve(strings.Join(qualifier, ".")); !ok || !virtual {
+		return fmt.Errorf("star expressions: %w", ErrNotImplemented)
+	}
+
+	return nil
+}
+
+func addRangeVar(tables *Tables, ctes cteNames, tbl *pg.RangeVar) error {
 	var alias string
 	if tbl.GetAlias() != nil {
 		alias = tbl.GetAlias().GetAliasname()
 	}
 
+	if ctes.has(tbl) {
+		if err := tables.PutVirtual(tbl.GetRelname(), alias); err != nil {
+			return fmt.Errorf("failed to add cte: %w", err)
+		}
+		tables.setColumns(tbl.GetRelname(), ctes[tbl.GetRelname()])
+
+		return nil
+	}
+
 	if _, err := tables.Put(tbl.GetCatalogname(), tbl.GetSchemaname(), tbl.GetRelname(), alias); err != nil {
 		return fmt.Errorf("failed to add table: %w", err)
 	}
@@ -100,54 +143,58 @@ func addRangeVar(tables *Tables, tbl *pg.RangeVar) error {
 	return nil
 }
 
-func collectSelectFromTables(tables *Tables, node *pg.Node) error { //nolint:cyclop
+func collectSelectFromTables(tables *Tables, ctes cteNames, node *pg.Node) ([]Vector, error) { //nolint:cyclop
 	switch fromNode := node.GetNode().(type) {
 	default:
-		return fmt.Errorf("from type (%T): %w", node.GetNode(), ErrNotImplemented)
+		return nil, fmt.Errorf("from type (%T): %w", node.GetNode(), ErrNotImplemented)
 	case *pg.Node_RangeVar:
-		if err := addRangeVar(tables, fromNode.RangeVar); err != nil {
-			return err
+		if err := addRangeVar(tables, ctes, fromNode.RangeVar); err != nil {
+			return nil, err
 		}
 
-		return nil
+		return nil, nil
+	case *pg.Node_RangeSubselect:
+		return addRangeSubselect(tables, ctes, fromNode.RangeSubselect)
 	case *pg.Node_JoinExpr:
 		join := fromNode.JoinExpr
 		if join.GetIsNatural() || len(join.GetUsingClause()) > 0 || join.GetJoinUsingAlias() != nil || join.GetAlias() != nil {
-			return fmt.Errorf("join expression: %w", ErrNotImplemented)
+			return nil, fmt.Errorf("join expression: %w", ErrNotImplemented)
 		}
 
-		if err := collectSelectFromTables(tables, join.GetLarg()); err != nil {
-			return err
+		leftVectors, err := collectSelectFromTables(tables, ctes, join.GetLarg())
+		if err != nil {
+			return nil, err
 		}
 
-		if err := collectSelectFromTables(tables, join.GetRarg()); err != nil {
-			return err
+		rightVectors, err := collectSelectFromTables(tables, ctes, join.GetRarg())
+		if err != nil {
+			return nil, err
 		}
 
-		return nil
+		return slices.Concat(leftVectors, rightVectors), nil
 	}
 }
 
-func parseSelectJoinColumns(node *pg.Node) (Columns, error) {
+func parseSelectJoinColumns(node *pg.Node, sub *subqueryScope) (Columns, error) {
 	switch fromNode := node.GetNode().(type) {
 	default:
 		return nil, fmt.Errorf("from type (%T): %w", node.GetNode(), ErrNotImplemented)
-	case *pg.Node_RangeVar:
+	case *pg.Node_RangeVar, *pg.Node_RangeSubselect:
 		return nil, nil
 	case *pg.Node_JoinExpr:
 		join := fromNode.JoinExpr
 
-		leftColumns, err := parseSelectJoinColumns(join.GetLarg())
+		leftColumns, err := parseSelectJoinColumns(join.GetLarg(), sub)
 		if err != nil {
 			return nil, err
 		}
 
-		rightColumns, err := parseSelectJoinColumns(join.GetRarg())
+		rightColumns, err := parseSelectJoinColumns(join.GetRarg(), sub)
 		if err != nil {
 			return nil, err
 		}
 
-		joinColumns, err := parseWhereClause(join.GetQuals())
+		joinColumns, err := parseWhereClause(join.GetQuals(), sub)
 		if err != nil {
 			return nil, fmt.Errorf("parse join clause: %w", err)
 		}
@@ -156,7 +203,191 @@ func parseSelectJoinColumns(node *pg.Node) (Columns, error) {
 	}
 }
 
-func handleSelect(sel *pg.SelectStmt) ([]Vector, error) { //nolint:gocyclo,gocognit,cyclop,funlen,maintidx
+// columnOrigin is a column of a real table that a column of a select result is read from.
+type columnOrigin struct {
+	vec    *SelectVec
+	column string
+}
+
+// outputColumn is a column of a select result. Clauses of an outer query over a CTE or derived table are added to
+// vectors of origins. Computed columns (functions, constants, columns of outer queries) can not be mapped.
+type outputColumn struct {
+	name     string
+	origins  []columnOrigin
+	computed bool
+}
+
+// relationColumns are output columns of a virtual relation. Nil means that columns are not tracked, like for a
+// recursive CTE inside its own body or a data-modifying CTE; rows of such relations are covered by their own vectors.
+type relationColumns []outputColumn
+
+func (c relationColumns) find(name string) (outputColumn, bool) {
+	for _, column := range c {
+		if column.name == name {
+			return column, true
+		}
+	}
+
+	return outputColumn{}, false //nolint:exhaustruct
+}
+
+// rename applies column aliases like `AS sub(a, b)` to the first columns.
+func (c relationColumns) rename(names []*pg.Node) (relationColumns, error) {
+	if c == nil || len(names) == 0 {
+		return c, nil
+	}
+
+	if len(names) > len(c) {
+		return nil, fmt.Errorf("%d column aliases for %d columns: %w", len(names), len(c), ErrNotImplemented)
+	}
+
+	res := slices.Clone(c)
+	for i, node := range names {
+		name, ok := node.GetNode().(*pg.Node_String_)
+		if !ok {
+			return nil, fmt.Errorf("column alias (%T): %w", node.GetNode(), ErrNotImplemented)
+		}
+
+		res[i].name = name.String_.GetSval()
+	}
+
+	return res, nil
+}
+
+// combineColumns joins columns of set operation branches by position. Names are taken from the left branch.
+func combineColumns(left, right relationColumns) (relationColumns, error) {
+	switch {
+	case left == nil:
+		return right, nil
+	case right == nil:
+		return left, nil
+	case len(left) != len(right):
+		return nil, fmt.Errorf("branches have %d and %d columns: %w", len(left), len(right), ErrNotImplemented)
+	}
+
+	res := make(relationColumns, len(left))
+	for i := range left {
+		res[i] = outputColumn{
+			name:     left[i].name,
+			origins:  slices.Concat(left[i].origins, right[i].origins),
+			computed: left[i].computed || right[i].computed,
+		}
+	}
+
+	return res, nil
+}
+
+// targetOutput is an item of the target list before its origins are resolved.
+type targetOutput struct {
+	name   string
+	column *Column
+	star   *pg.Node_ColumnRef
+}
+
+// handleSetOperation walks both branches of UNION / INTERSECT / EXCEPT. Every branch is a regular select that
+// produces its own vectors; they are not merged, so every branch is checked on its own.
+func handleSetOperation(sel *pg.SelectStmt, ctes cteNames, outer *Tables) ([]Vector, relationColumns, error) {
+	if sel.GetIntoClause() != nil ||
+		sel.LockingClause != nil ||
+		sel.GetLarg() == nil ||
+		sel.GetRarg() == nil {
+		return nil, nil, fmt.Errorf("unknown set operation clause: %w", ErrNotImplemented)
+	}
+
+	var vectors []Vector
+	if with := sel.GetWithClause(); with != nil {
+		cteVectors, scope, err := handleWithClause(with, ctes, outer)
+		if err != nil {
+			return nil, nil, fmt.Errorf("parse with clause: %w", err)
+		}
+
+		vectors = append(vectors, cteVectors...)
+		ctes = scope
+	}
+
+	leftVectors, leftColumns, err := selectWithOutput(sel.GetLarg(), ctes, outer)
+	if err != nil {
+		return nil, nil, fmt.Errorf("parse left branch of %s: %w", sel.GetOp().String(), err)
+	}
+
+	rightVectors, rightColumns, err := selectWithOutput(sel.GetRarg(), ctes, outer)
+	if err != nil {
+		return nil, nil, fmt.Errorf("parse right branch of %s: %w", sel.GetOp().String(), err)
+	}
+
+	columns, err := combineColumns(leftColumns, rightColumns)
+	if err != nil {
+		return nil, nil, fmt.Errorf("parse %s: %w", sel.GetOp().String(), err)
+	}
+
+	// NOTE: ORDER BY of set operation can reference only output columns of the result. They are added to sort of the
+	//  vectors the columns are read from.
+	for _, node := range sel.GetSortClause() {
+		sortBy, ok := node.GetNode().(*pg.Node_SortBy)
+		if !ok || sortBy.SortBy.UseOp != nil {
+			return nil, nil, fmt.Errorf("set operation sort clause (%T): %w", node.GetNode(), ErrNotImplemented)
+		}
+
+		var (
+			out   outputColumn
+			found bool
+		)
+		switch node := sortBy.SortBy.GetNode().GetNode().(type) {
+		default:
+			return nil, nil, fmt.Errorf("set operation sort clause (%T): %w", node, ErrNotImplemented)
+		case *pg.Node_AConst:
+			pos := int(node.AConst.GetIval().GetIval())
+			if pos >= 1 && pos <= len(columns) {
+				out, found = columns[pos-1], true
+			}
+		case *pg.Node_ColumnRef:
+			column, err := pNodeColumnRef(node)
+			if err != nil {
+				return nil, nil, fmt.Errorf("parse column: %w", err)
+			}
+
+			if column.Table() != "" {
+				return nil, nil, fmt.Errorf("qualified column in set operation sort clause: %w", ErrNotImplemented)
+			}
+
+			out, found = columns.find(column.column)
+		}
+
+		if columns == nil {
+			continue
+		}
+
+		if !found || out.computed {
+			return nil, nil, fmt.Errorf("set operation sort clause can not be checked: %w", ErrNotImplemented)
+		}
+
+		for _, origin := range out.origins {
+			origin.vec.Sort = append(origin.vec.Sort, origin.column)
+		}
+	}
+
+	return slices.Concat(vectors, leftVectors, rightVectors), columns, nil
+}
+
+// handleSelect parses the select statement. ctes are common table expressions visible on this level and outer is a
+// scope of the enclosing query (nil for top-level select).
+func handleSelect(sel *pg.SelectStmt, ctes cteNames, outer *Tables) ([]Vector, error) {
+	vectors, _, err := selectWithOutput(sel, ctes, outer)
+
+	return vectors, err
+}
+
+// selectWithOutput parses the select statement like handleSelect and also returns columns of its result, so the
+// select can be used as a CTE or derived table.
+func selectWithOutput( //nolint:gocyclo,gocognit,cyclop,funlen,maintidx
+	sel *pg.SelectStmt,
+	ctes cteNames,
+	outer *Tables,
+) ([]Vector, relationColumns, error) {
+	if sel.GetOp() != pg.SetOperation_SETOP_NONE {
+		return handleSetOperation(sel, ctes, outer)
+	}
+
 	if sel.DistinctClause != nil ||
 		sel.GetIntoClause() != nil ||
 		sel.GetHavingClause() != nil ||
@@ -164,74 +395,120 @@ func handleSelect(sel *pg.SelectStmt) ([]Vector, error) { //nolint:gocyclo,gocog
 		sel.GetGroupDistinct() ||
 		sel.GetOp() != pg.SetOperation_SETOP_NONE ||
 		sel.LockingClause != nil ||
-		sel.GetWithClause() != nil ||
 		sel.ValuesLists != nil ||
 		sel.GetLarg() != nil ||
 		sel.GetRarg() != nil ||
 		sel.GetAll() {
-		return nil, fmt.Errorf("unknown clause: %w", ErrNotImplemented)
+		return nil, nil, fmt.Errorf("unknown clause: %w", ErrNotImplemented)
+	}
+
+	var vectors []Vector
+	if with := sel.GetWithClause(); with != nil {
+		cteVectors, scope, err := handleWithClause(with, ctes, outer)
+		if err != nil {
+			return nil, nil, fmt.Errorf("parse with clause: %w", err)
+		}
+
+		vectors = append(vectors, cteVectors...)
+		ctes = scope
 	}
 
 	if len(sel.GetFromClause()) != 1 {
-		return nil, errors.New("from clause must contains only one expression") //nolint:err113
+		return nil, nil, errors.New("from clause must contains only one expression") //nolint:err113
 	}
 
 	tables := NewTables("public")
+	tables.parent = outer
 	from := sel.GetFromClause()[0]
-	if err := collectSelectFromTables(tables, from); err != nil {
-		return nil, err
+	fromVectors, err := collectSelectFromTables(tables, ctes, from)
+	if err != nil {
+		return nil, nil, err
 	}
+	vectors = append(vectors, fromVectors...)
 
 	if err := tables.Finalize(); err != nil {
-		return nil, fmt.Errorf("failed to finalize tables: %w", err)
+		return nil, nil, fmt.Errorf("failed to finalize tables: %w", err)
 	}
 
-	var allColumns Columns
+	sub := newSubqueryScope(ctes, tables)
+
+	var (
+		targetColumns, filterColumns, groupColumns, sortColumns Columns
+		outputs                                                 []targetOutput
+	)
 	// handle target fields
 	for _, target := range sel.GetTargetList() {
 		switch node := target.GetNode().(type) {
 		default:
-			return nil, fmt.Errorf("target type (%T): %w", target.GetNode(), ErrNotImplemented)
+			return nil, nil, fmt.Errorf("target type (%T): %w", target.GetNode(), ErrNotImplemented)
 		case *pg.Node_ResTarget:
 			resTarget := node.ResTarget
 			if resTarget.Indirection != nil {
-				return nil, fmt.Errorf("resTarget field: %w", ErrNotImplemented)
+				return nil, nil, fmt.Errorf("resTarget field: %w", ErrNotImplemented)
 			}
 
+			output := targetOutput{name: resTarget.GetName(), column: nil, star: nil}
 			switch node := resTarget.GetVal().GetNode().(type) {
 			default:
-				return nil, fmt.Errorf("resTarget type (%T): %w", resTarget.GetVal().GetNode(), ErrNotImplemented)
+				return nil, nil, fmt.Errorf("resTarget type (%T): %w", resTarget.GetVal().GetNode(), ErrNotImplemented)
+			case *pg.Node_AConst:
 			case *pg.Node_ColumnRef:
+				if isFunctionStarArg(node) {
+					if err := checkVirtualStar(tables, node); err != nil {
+						return nil, nil, fmt.Errorf("parse column: %w", err)
+					}
+
+					output.star = node
+					outputs = append(outputs, output)
+
+					continue
+				}
+
 				column, err := pNodeColumnRef(node)
 				if err != nil {
-					return nil, fmt.Errorf("parse column: %w", err)
+					return nil, nil, fmt.Errorf("parse column: %w", err)
+				}
+
+				targetColumns = append(targetColumns, column)
+				output.column = &column
+				if output.name == "" {
+					output.name = column.column
+				}
+			case *pg.Node_SubLink:
+				columns, err := sub.handleSubLink(node)
+				if err != nil {
+					return nil, nil, fmt.Errorf("parse target subquery: %w", err)
 				}
 
-				allColumns = append(allColumns, column)
+				targetColumns = append(targetColumns, columns...)
 			case *pg.Node_FuncCall:
 				funcCall := node.FuncCall
 				if funcCall.GetOver() != nil || funcCall.GetAggFilter() != nil || funcCall.AggOrder != nil {
-					return nil, fmt.Errorf("window functions (%T): %w", funcCall.GetOver(), ErrNotImplemented)
+					return nil, nil, fmt.Errorf("window functions (%T): %w", funcCall.GetOver(), ErrNotImplemented)
 				}
 
 				if len(funcCall.GetFuncname()) != 1 {
-					return nil, fmt.Errorf("only one function call support for one target: %w", ErrNotImplemented)
+					return nil, nil, fmt.Errorf("only one function call support for one target: %w", ErrNotImplemented)
 				}
 
 				switch node := funcCall.GetFuncname()[0].GetNode().(type) {
 				default:
-					return nil, fmt.Errorf("unknown function name type (%T): %w", node, ErrNotImplemented)
+					return nil, nil, fmt.Errorf("unknown function name type (%T): %w", node, ErrNotImplemented)
 				case *pg.Node_String_:
 					name := strings.ToLower(node.String_.GetSval())
 					if _, ok := allowedSelectFunctions[name]; !ok {
-						return nil, fmt.Errorf("unknown function name (%s): %w", name, ErrNotImplemented)
+						return nil, nil, fmt.Errorf("unknown function name (%s): %w", name, ErrNotImplemented)
+					}
+
+					if output.name == "" {
+						output.name = name
 					}
 				}
 
 				for _, node := range funcCall.GetArgs() {
 					switch node := node.GetNode().(type) {
 					default:
-						return nil, fmt.Errorf("unknown function argument type (%T)", node) //nolint:err113
+						return nil, nil, fmt.Errorf("unknown function argument type (%T)", node) //nolint:err113
 					case *pg.Node_ColumnRef:
 						if isFunctionStarArg(node) {
 							continue
@@ -239,48 +516,50 @@ func handleSelect(sel *pg.SelectStmt) ([]Vector, error) { //nolint:gocyclo,gocog
 
 						column, err := pNodeColumnRef(node)
 						if err != nil {
-							return nil, fmt.Errorf("parse column: %w", err)
+							return nil, nil, fmt.Errorf("parse column: %w", err)
 						}
-						allColumns = append(allColumns, column)
+						targetColumns = append(targetColumns, column)
 					}
 				}
 			}
+
+			outputs = append(outputs, output)
 		}
 	}
 
 	if sel.GetWhereClause() != nil {
-		whereColumns, err := parseWhereClause(sel.GetWhereClause())
+		whereColumns, err := parseWhereClause(sel.GetWhereClause(), sub)
 		if err != nil {
-			return nil, fmt.Errorf("parse where clause: %w", err)
+			return nil, nil, fmt.Errorf("parse where clause: %w", err)
 		}
-		allColumns = append(allColumns, whereColumns...)
+		filterColumns = append(filterColumns, whereColumns...)
 	}
 
-	joinColumns, err := parseSelectJoinColumns(from)
+	joinColumns, err := parseSelectJoinColumns(from, sub)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	allColumns = append(allColumns, joinColumns...)
+	filterColumns = append(filterColumns, joinColumns...)
 
 	for _, node := range sel.GetSortClause() {
 		switch node := node.GetNode().(type) {
 		default:
-			return nil, fmt.Errorf("sort clause (%T): %w", node, ErrNotImplemented)
+			return nil, nil, fmt.Errorf("sort clause (%T): %w", node, ErrNotImplemented)
 		case *pg.Node_SortBy:
 			if node.SortBy.UseOp != nil {
-				return nil, fmt.Errorf("sort useOp: %w", ErrNotImplemented)
+				return nil, nil, fmt.Errorf("sort useOp: %w", ErrNotImplemented)
 			}
 
 			switch node := node.SortBy.GetNode().GetNode().(type) {
 			default:
-				return nil, fmt.Errorf("sort clause (%T): %w", node, ErrNotImplemented)
+				return nil, nil, fmt.Errorf("sort clause (%T): %w", node, ErrNotImplemented)
 			case *pg.Node_ColumnRef:
 				column, err := pNodeColumnRef(node)
 				if err != nil {
-					return nil, fmt.Errorf("parse column: %w", err)
+					return nil, nil, fmt.Errorf("parse column: %w", err)
 				}
 
-				allColumns = append(allColumns, column)
+				sortColumns = append(sortColumns, column)
 			}
 		}
 	}
@@ -292,50 +571,139 @@ func handleSelect(sel *pg.SelectStmt) ([]Vector, error) { //nolint:gocyclo,gocog
 	for _, node := range sel.GetGroupClause() {
 		switch node := node.GetNode().(type) {
 		default:
-			return nil, fmt.Errorf("group by node (%T): %w", node, ErrNotImplemented)
+			return nil, nil, fmt.Errorf("group by node (%T): %w", node, ErrNotImplemented)
 		case *pg.Node_ColumnRef:
 			column, err := pNodeColumnRef(node)
 			if err != nil {
-				return nil, fmt.Errorf("parse group by column: %w", err)
+				return nil, nil, fmt.Errorf("parse group by column: %w", err)
 			}
 
-			allColumns = append(allColumns, column)
+			groupColumns = append(groupColumns, column)
 		}
 	}
 
 	allTables, err := tables.GetAll()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get all tables: %w", err)
+		return nil, nil, fmt.Errorf("failed to get all tables: %w", err)
 	}
 
-	table2target := make(map[string]Columns, len(allTables)+len(allColumns))
+	table2vec := make(map[string]*SelectVec, len(allTables))
 	for _, tableName := range allTables {
-		table2target[tableName] = nil
+		table2vec[tableName] = &SelectVec{Tbl: tableName, Target: nil, Filter: nil, Group: nil, Sort: nil}
 	}
 
-	for _, column := range allColumns {
-		if column.Table() == "" && tables.SourcesCount() > 1 {
-			return nil, fmt.Errorf("ambiguous column reference (%s): %w", column.column, ErrNotImplemented)
+	// NOTE: target columns of CTE and derived tables are covered by vectors of their own selects. Other clauses
+	//  change which rows are read, so they are added to vectors of the tables the columns are read from.
+	clauses := []struct {
+		columns    Columns
+		field      selectField
+		mapVirtual bool
+	}{
+		{columns: targetColumns, field: func(vec *SelectVec) *[]string { return &vec.Target }, mapVirtual: false},
+		{columns: filterColumns, field: func(vec *SelectVec) *[]string { return &vec.Filter }, mapVirtual: true},
+		{columns: groupColumns, field: func(vec *SelectVec) *[]string { return &vec.Group }, mapVirtual: true},
+		{columns: sortColumns, field: func(vec *SelectVec) *[]string { return &vec.Sort }, mapVirtual: true},
+	}
+	for _, clause := range clauses {
+		for _, column := range clause.columns {
+			tbl, ok, err := tables.attribute(column, clause.field, clause.mapVirtual)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			if !ok {
+				continue
+			}
+
+			vec, ok := table2vec[tbl]
+			if !ok {
+				vec = &SelectVec{Tbl: tbl, Target: nil, Filter: nil, Group: nil, Sort: nil}
+				table2vec[tbl] = vec
+			}
+
+			field := clause.field(vec)
+			*field = append(*field, column.column)
 		}
+	}
 
-		tbl, ok := tables.Get(column.Table())
-		if !ok {
-			return nil, fmt.Errorf("table not found: %s", column.Table()) //nolint:err113
+	columns, err := resolveOutputs(tables, table2vec, outputs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	vectors = append(vectors, sub.vectors...)
+	for _, vec := range table2vec {
+		vectors = append(vectors, vec)
+	}
+
+	return vectors, columns, nil
+}
+
+// resolveOutputs finds origins of the result columns. Star expressions are expanded to columns of the virtual
+// relations; when such relation does not track its columns, columns of the result are not tracked too.
+func resolveOutputs(tables *Tables, table2vec map[string]*SelectVec, outputs []targetOutput) (relationColumns, error) {
+	res := make(relationColumns, 0, len(outputs))
+	for _, output := range outputs {
+		switch {
+		case output.star != nil:
+			relations := tables.virtualOrder
+			if fields := output.star.ColumnRef.GetFields(); len(fields) > 1 {
+				column, err := ParseColumn(just.SliceMap(fields, func(node *pg.Node) string {
+					return node.GetString_().GetSval()
+				})...)
+				if err != nil {
+					return nil, fmt.Errorf("parse star: %w", err)
+				}
+
+				relation, _, _ := tables.Resolve(column.Table())
+				relations = []string{relation}
+			}
+
+			for _, relation := range relations {
+				columns := tables.columns[relation]
+				if columns == nil {
+					return nil, nil
+				}
+
+				res = append(res, columns...)
+			}
+		case output.column != nil:
+			res = append(res, resolveOutput(tables, table2vec, output.name, *output.column))
+		default:
+			res = append(res, outputColumn{name: just.If(output.name == "", "?column?", output.name), origins: nil, computed: true})
 		}
+	}
+
+	return res, nil
+}
+
+func resolveOutput(tables *Tables, table2vec map[string]*SelectVec, name string, column Column) outputColumn {
+	computed := outputColumn{name: name, origins: nil, computed: true}
+	if column.Table() != "" && !tables.HasMapping(column.Table()) {
+		// Columns of outer queries are parameters of the nested select.
+		return computed
+	}
 
-		table2target[tbl] = append(table2target[tbl], column)
+	tbl, virtual, ok := tables.Resolve(column.Table())
+	if !ok {
+		return computed
 	}
 
-	vectors := make([]Vector, 0, len(table2target))
-	for tbl, cols := range table2target {
-		vectors = append(vectors, SelectVec{
-			Tbl:    tbl,
-			Target: cols.ListNames(),
-			Filter: nil, // TODO: impl
-			Group:  nil, // TODO: impl
-			Sort:   nil, // TODO: impl
-		})
+	if !virtual {
+		return outputColumn{name: name, origins: []columnOrigin{{vec: table2vec[tbl], column: column.column}}, computed: false}
 	}
 
-	return vectors, nil
+	columns := tables.columns[tbl]
+	if columns == nil {
+		return outputColumn{name: name, origins: nil, computed: false}
+	}
+
+	out, ok := columns.find(column.column)
+	if !ok {
+		return computed
+	}
+
+	out.name = name
+
+	return out
 }
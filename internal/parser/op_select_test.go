@@ -18,6 +18,7 @@ package parser_test
 
 import (
 	"sort"
+	"strings"
 	"testing"
 
 	"github.com/kazhuravlev/database-gateway/internal/parser"
@@ -80,6 +81,13 @@ func TestParseSelectValid(t *testing.T) {
 	fValid(`SELECT count(*) from clients`)
 	fValid(`SELECT count(c.*) from clients AS c`)
 	fValid(`SELECT sum(total_sales) from sales_olap_42`)
+
+	fValid(`SELECT id FROM clients UNION SELECT id FROM orders`)
+	fValid(`SELECT id FROM clients UNION ALL SELECT id FROM orders`)
+	fValid(`SELECT id FROM clients INTERSECT SELECT client_id FROM orders`)
+	fValid(`SELECT id FROM clients EXCEPT SELECT client_id FROM orders`)
+	fValid(`SELECT id FROM clients UNION SELECT id FROM orders ORDER BY id LIMIT 10`)
+	fValid(`(SELECT id FROM clients UNION SELECT id FROM orders) EXCEPT SELECT id FROM archive`)
 }
 
 // TestParseSelectInvalid is just a list of special cases that is not supported (yet).
@@ -104,8 +112,10 @@ func TestParseSelectInvalid(t *testing.T) {
 	fInvalid("SELECT clients.* FROM public.clients", "star_expression_scm") //nolint:unqueryvet
 	fInvalid("SELECT public.clients.* FROM public.clients", "star_expression_scm")
 
-	fInvalid("SELECT id FROM (SELECT id FROM clients) AS sub", "nested_select")
-	fInvalid("SELECT id FROM clients UNION SELECT id FROM orders", "union_expression")
+	fInvalid("SELECT id FROM (SELECT id FROM clients)", "nested_select_without_alias")
+	fInvalid("SELECT c.id FROM clients AS c, LATERAL (SELECT id FROM orders WHERE client_id = c.id) AS o", "lateral_subquery")
+	fInvalid("SELECT id FROM clients UNION SELECT id FROM orders ORDER BY clients.id", "union_qualified_sort")
+	fInvalid("SELECT id FROM clients UNION SELECT * FROM orders", "union_star_branch")
 }
 
 func TestParseSelectJoinVectors(t *testing.T) {
@@ -195,6 +205,239 @@ WHERE parent.active = true`,
 	}
 }
 
+func TestParseSelectSetOperationVectors(t *testing.T) {
+	t.Parallel()
+
+	type expectedVec struct {
+		tbl  string
+		cols []string
+	}
+
+	testCases := []struct {
+		name  string
+		query string
+		exp   []expectedVec
+	}{
+		{
+			name:  "union_of_two_tables",
+			query: `SELECT id, name FROM clients UNION SELECT id, title FROM orders`,
+			exp: []expectedVec{
+				{tbl: "clients", cols: []string{"id", "name"}},
+				{tbl: "orders", cols: []string{"id", "title"}},
+			},
+		},
+		{
+			name:  "union_of_same_table_keeps_branches",
+			query: `SELECT id FROM clients WHERE status = 'new' UNION ALL SELECT name FROM clients WHERE status = 'old'`,
+			exp: []expectedVec{
+				{tbl: "clients", cols: []string{"id", "status"}},
+				{tbl: "clients", cols: []string{"name", "status"}},
+			},
+		},
+		{
+			name: "nested_set_operations",
+			query: `(SELECT id FROM clients UNION SELECT client_id FROM orders)
+EXCEPT
+SELECT client_id FROM blocked`,
+			exp: []expectedVec{
+				{tbl: "blocked", cols: []string{"client_id"}},
+				{tbl: "clients", cols: []string{"id"}},
+				{tbl: "orders", cols: []string{"client_id"}},
+			},
+		},
+		{
+			name: "branches_with_joins",
+			query: `SELECT c.id FROM clients AS c JOIN orders AS o ON c.id = o.client_id
+INTERSECT
+SELECT id FROM vip`,
+			exp: []expectedVec{
+				{tbl: "clients", cols: []string{"id"}},
+				{tbl: "orders", cols: []string{"client_id"}},
+				{tbl: "vip", cols: []string{"id"}},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			vecs, err := parser.Parse(tc.query)
+			require.NoError(t, err)
+			require.Len(t, vecs, len(tc.exp))
+
+			got := make([]expectedVec, 0, len(vecs))
+			for _, vec := range vecs {
+				sel, ok := vec.(parser.SelectVec)
+				require.True(t, ok)
+
+				cols := sel.Columns()
+				sort.Strings(cols)
+				got = append(got, expectedVec{
+					tbl:  sel.Tbl,
+					cols: cols,
+				})
+			}
+
+			sort.Slice(got, func(i, j int) bool {
+				if got[i].tbl != got[j].tbl {
+					return got[i].tbl < got[j].tbl
+				}
+
+				return strings.Join(got[i].cols, ",") < strings.Join(got[j].cols, ",")
+			})
+
+			require.Equal(t, tc.exp, got)
+		})
+	}
+}
+
+// TestParseSelectClauseScopes checks that filter and sort of one scope are not shared with another scope, and that
+// clauses over CTE and derived tables reach vectors of the tables the columns are read from.
+func TestParseSelectClauseScopes(t *testing.T) {
+	t.Parallel()
+
+	vec := func(target, filter, sorts []string) parser.SelectVec {
+		return parser.SelectVec{Tbl: "clients", Target: target, Filter: filter, Group: nil, Sort: sorts}
+	}
+
+	testCases := []struct {
+		name  string
+		query string
+		exp   []parser.SelectVec
+	}{
+		{
+			name:  "union_branch_filter_is_not_shared",
+			query: `SELECT phone FROM clients WHERE id = 1 UNION SELECT phone FROM clients`,
+			exp: []parser.SelectVec{
+				vec([]string{"phone"}, nil, nil),
+				vec([]string{"phone"}, []string{"id"}, nil),
+			},
+		},
+		{
+			name:  "union_sort_reaches_every_branch",
+			query: `SELECT phone FROM clients WHERE id = 1 UNION SELECT phone FROM clients WHERE id = 2 ORDER BY phone`,
+			exp: []parser.SelectVec{
+				vec([]string{"phone"}, []string{"id"}, []string{"phone"}),
+				vec([]string{"phone"}, []string{"id"}, []string{"phone"}),
+			},
+		},
+		{
+			name:  "union_sort_by_position",
+			query: `SELECT phone FROM clients WHERE id = 1 UNION SELECT email FROM clients WHERE id = 2 ORDER BY 1`,
+			exp: []parser.SelectVec{
+				vec([]string{"email"}, []string{"id"}, []string{"email"}),
+				vec([]string{"phone"}, []string{"id"}, []string{"phone"}),
+			},
+		},
+		{
+			name:  "subquery_in_target_list_has_own_filter",
+			query: `SELECT id, (SELECT max(phone) FROM clients) FROM clients WHERE id = 1`,
+			exp: []parser.SelectVec{
+				vec([]string{"id"}, []string{"id"}, nil),
+				vec([]string{"phone"}, nil, nil),
+			},
+		},
+		{
+			name:  "cte_filtered_and_sorted_from_outside",
+			query: `WITH c AS (SELECT id, phone FROM clients) SELECT phone FROM c WHERE id = 1 ORDER BY phone`,
+			exp: []parser.SelectVec{
+				vec([]string{"id", "phone"}, []string{"id"}, []string{"phone"}),
+			},
+		},
+		{
+			name:  "derived_table_sorted_from_outside",
+			query: `SELECT s.phone FROM (SELECT id, phone FROM clients WHERE id = 1) AS s ORDER BY s.phone`,
+			exp: []parser.SelectVec{
+				vec([]string{"id", "phone"}, []string{"id"}, []string{"phone"}),
+			},
+		},
+		{
+			name:  "derived_table_column_aliases",
+			query: `SELECT x FROM (SELECT phone FROM clients WHERE id = 1) AS s(x) ORDER BY x`,
+			exp: []parser.SelectVec{
+				vec([]string{"phone"}, []string{"id"}, []string{"phone"}),
+			},
+		},
+		{
+			name: "union_cte_sorted_from_outside",
+			query: `WITH u AS (SELECT phone FROM clients WHERE id = 1 UNION SELECT email FROM clients WHERE id = 2)
+SELECT phone FROM u ORDER BY phone`,
+			exp: []parser.SelectVec{
+				vec([]string{"email"}, []string{"id"}, []string{"email"}),
+				vec([]string{"phone"}, []string{"id"}, []string{"phone"}),
+			},
+		},
+		{
+			name: "correlated_column_belongs_to_outer_query",
+			query: `SELECT name FROM clients AS c
+WHERE c.id = 1 AND EXISTS (SELECT 1 FROM clients AS o WHERE o.id = c.referrer_id)`,
+			exp: []parser.SelectVec{
+				vec(nil, []string{"id"}, nil),
+				vec([]string{"name"}, []string{"id", "referrer_id"}, nil),
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			vecs, err := parser.Parse(tc.query)
+			require.NoError(t, err)
+
+			got := make([]parser.SelectVec, 0, len(vecs))
+			for _, vec := range vecs {
+				sel, ok := vec.(parser.SelectVec)
+				require.True(t, ok)
+
+				for _, field := range [][]string{sel.Target, sel.Filter, sel.Group, sel.Sort} {
+					sort.Strings(field)
+				}
+				got = append(got, sel)
+			}
+
+			sort.Slice(got, func(i, j int) bool {
+				return strings.Join(got[i].Target, ",")+strings.Join(got[i].Filter, ",") <
+					strings.Join(got[j].Target, ",")+strings.Join(got[j].Filter, ",")
+			})
+
+			require.Equal(t, tc.exp, got)
+		})
+	}
+}
+
+func TestParseSelectClauseScopesInvalid(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name  string
+		query string
+	}{
+		{
+			name:  "computed_column_filtered_from_outside",
+			query: `SELECT n FROM (SELECT count(id) AS n FROM clients) AS s WHERE n > 1`,
+		},
+		{
+			name:  "computed_column_sorted_in_union",
+			query: `SELECT count(id) FROM clients UNION SELECT count(id) FROM orders ORDER BY 1`,
+		},
+		{
+			name:  "unknown_column_of_cte",
+			query: `WITH c AS (SELECT id FROM clients) SELECT id FROM c WHERE phone = '1'`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := parser.Parse(tc.query)
+			require.ErrorIs(t, err, parser.ErrNotImplemented)
+		})
+	}
+}
+
 func TestParseSelectJoinInvalid(t *testing.T) {
 	t.Parallel()
 
@@ -210,10 +453,6 @@ func TestParseSelectJoinInvalid(t *testing.T) {
 			name:  "natural_join",
 			query: `SELECT c.id FROM clients AS c NATURAL JOIN orders AS o`,
 		},
-		{
-			name:  "join_subquery_rhs",
-			query: `SELECT c.id FROM clients AS c JOIN (SELECT client_id FROM orders) AS o ON c.id = o.client_id`,
-		},
 		{
 			name:  "ambiguous_unqualified_column_in_join_predicate",
 			query: `SELECT c.id FROM clients AS c JOIN orders AS o ON id = o.client_id`,
@@ -0,0 +1,143 @@
+// Database Gateway provides access to servers with ACL for safe and restricted database interactions.
+// Copyright (C) 2024  Kirill Zhuravlev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package parser
+
+import (
+	"fmt"
+
+	pg "github.com/pganalyze/pg_query_go/v6"
+)
+
+// subqueryScope collects vectors of nested selects met while walking expressions of the current query. Nil scope
+// means that the current statement does not support nested selects.
+type subqueryScope struct {
+	ctes    cteNames
+	tables  *Tables
+	vectors []Vector
+}
+
+func newSubqueryScope(ctes cteNames, tables *Tables) *subqueryScope {
+	return &subqueryScope{
+		ctes:    ctes,
+		tables:  tables,
+		vectors: nil,
+	}
+}
+
+// handleSubLink parses the nested select of sublink (`EXISTS (...)`, `IN (...)`, `= (...)`) within the current
+// scope and returns the columns of the outer query which are used in the test expression or referenced from the
+// nested select.
+func (s *subqueryScope) handleSubLink(node *pg.Node_SubLink) (Columns, error) { //nolint:cyclop
+	if s == nil {
+		return nil, fmt.Errorf("subquery: %w", ErrNotImplemented)
+	}
+
+	link := node.SubLink
+	switch link.GetSubLinkType() { //nolint:exhaustive // this is whitelist of allowed kinds
+	default:
+		return nil, fmt.Errorf("sublink type (%s): %w", link.GetSubLinkType().String(), ErrNotImplemented)
+	case pg.SubLinkType_EXISTS_SUBLINK, pg.SubLinkType_EXPR_SUBLINK:
+	case pg.SubLinkType_ANY_SUBLINK, pg.SubLinkType_ALL_SUBLINK:
+		for _, name := range link.GetOperName() {
+			opName, ok := name.GetNode().(*pg.Node_String_)
+			if !ok {
+				return nil, fmt.Errorf("sublink operator (%T): %w", name.GetNode(), ErrNotImplemented)
+			}
+
+			if _, ok := allowedOperators[opName.String_.GetSval()]; !ok {
+				return nil, fmt.Errorf("sublink operator (%s): %w", opName.String_.GetSval(), ErrNotImplemented)
+			}
+		}
+	}
+
+	var columns Columns
+	if link.GetTestexpr() != nil {
+		switch expr := link.GetTestexpr().GetNode().(type) {
+		default:
+			return nil, fmt.Errorf("sublink test expr (%T): %w", expr, ErrNotImplemented)
+		case *pg.Node_ColumnRef:
+			column, err := pNodeColumnRef(expr)
+			if err != nil {
+				return nil, fmt.Errorf("parse column: %w", err)
+			}
+
+			columns = append(columns, column)
+		case *pg.Node_AExpr:
+			cols, err := parseAexpr(expr, s)
+			if err != nil {
+				return nil, fmt.Errorf("sublink test expr: %w", err)
+			}
+
+			columns = append(columns, cols...)
+		}
+	}
+
+	sel, ok := link.GetSubselect().GetNode().(*pg.Node_SelectStmt)
+	if !ok {
+		return nil, fmt.Errorf("subselect (%T): %w", link.GetSubselect().GetNode(), ErrNotImplemented)
+	}
+
+	correlated := len(s.tables.correlated)
+	vectors, err := handleSelect(sel.SelectStmt, s.ctes, s.tables)
+	if err != nil {
+		return nil, fmt.Errorf("parse subquery: %w", err)
+	}
+
+	s.vectors = append(s.vectors, vectors...)
+
+	// NOTE: columns of the current query that are referenced from the nested select belong to the clause that
+	//  contains the sublink.
+	columns = append(columns, s.tables.correlated[correlated:]...)
+	s.tables.correlated = s.tables.correlated[:correlated]
+
+	return columns, nil
+}
+
+// addRangeSubselect registers derived table (`FROM (SELECT ...) AS alias`) as a virtual relation and returns
+// vectors of its select. Derived table can not see sibling relations, so it is resolved in the outer scope.
+func addRangeSubselect(tables *Tables, ctes cteNames, node *pg.RangeSubselect) ([]Vector, error) {
+	if node.GetLateral() {
+		return nil, fmt.Errorf("lateral subquery: %w", ErrNotImplemented)
+	}
+
+	alias := node.GetAlias().GetAliasname()
+	if alias == "" {
+		return nil, fmt.Errorf("subquery in from clause must have an alias: %w", ErrNotImplemented)
+	}
+
+	sel, ok := node.GetSubquery().GetNode().(*pg.Node_SelectStmt)
+	if !ok {
+		return nil, fmt.Errorf("subquery in from clause (%T): %w", node.GetSubquery().GetNode(), ErrNotImplemented)
+	}
+
+	vectors, columns, err := selectWithOutput(sel.SelectStmt, ctes, tables.parent)
+	if err != nil {
+		return nil, fmt.Errorf("parse subquery %q: %w", alias, err)
+	}
+
+	columns, err = columns.rename(node.GetAlias().GetColnames())
+	if err != nil {
+		return nil, fmt.Errorf("parse subquery %q: %w", alias, err)
+	}
+
+	if err := tables.PutVirtual(alias, ""); err != nil {
+		return nil, fmt.Errorf("failed to add subquery: %w", err)
+	}
+	tables.setColumns(alias, columns)
+
+	return vectors, nil
+}
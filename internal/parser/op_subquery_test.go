@@ -0,0 +1,265 @@
+// Database Gateway provides access to servers with ACL for safe and restricted database interactions.
+// Copyright (C) 2024  Kirill Zhuravlev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package parser_test
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/kazhuravlev/database-gateway/internal/parser"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSubqueryVectors(t *testing.T) {
+	t.Parallel()
+
+	type expectedVec struct {
+		tbl  string
+		cols []string
+	}
+
+	testCases := []struct {
+		name  string
+		query string
+		exp   []expectedVec
+	}{
+		{
+			name:  "where_in_subquery",
+			query: `SELECT name FROM clients WHERE id IN (SELECT client_id FROM transfers)`,
+			exp: []expectedVec{
+				{tbl: "clients", cols: []string{"id", "name"}},
+				{tbl: "transfers", cols: []string{"client_id"}},
+			},
+		},
+		{
+			name:  "where_not_in_subquery",
+			query: `SELECT name FROM clients WHERE id NOT IN (SELECT client_id FROM transfers WHERE amount > 100)`,
+			exp: []expectedVec{
+				{tbl: "clients", cols: []string{"id", "name"}},
+				{tbl: "transfers", cols: []string{"amount", "client_id"}},
+			},
+		},
+		{
+			name: "correlated_exists",
+			query: `SELECT c.name FROM clients AS c
+WHERE EXISTS (SELECT 1 FROM transfers AS t WHERE t.client_id = c.id AND t.secret_note IS NULL)`,
+			exp: []expectedVec{
+				{tbl: "clients", cols: []string{"id", "name"}},
+				{tbl: "transfers", cols: []string{"client_id", "secret_note"}},
+			},
+		},
+		{
+			name:  "scalar_subquery_in_where",
+			query: `SELECT id FROM clients WHERE balance > (SELECT max(amount) FROM transfers)`,
+			exp: []expectedVec{
+				{tbl: "clients", cols: []string{"balance", "id"}},
+				{tbl: "transfers", cols: []string{"amount"}},
+			},
+		},
+		{
+			name:  "scalar_subquery_in_target",
+			query: `SELECT c.id, (SELECT count(*) FROM transfers AS t WHERE t.client_id = c.id) FROM clients AS c`,
+			exp: []expectedVec{
+				{tbl: "clients", cols: []string{"id"}},
+				{tbl: "transfers", cols: []string{"client_id"}},
+			},
+		},
+		{
+			name:  "derived_table",
+			query: `SELECT sub.id FROM (SELECT id, email FROM clients WHERE active = true) AS sub`,
+			exp: []expectedVec{
+				{tbl: "clients", cols: []string{"active", "email", "id"}},
+			},
+		},
+		{
+			name:  "derived_table_star",
+			query: `SELECT sub.* FROM (SELECT id, email FROM clients) AS sub`,
+			exp: []expectedVec{
+				{tbl: "clients", cols: []string{"email", "id"}},
+			},
+		},
+		{
+			name:  "derived_table_in_join",
+			query: `SELECT c.name FROM clients AS c JOIN (SELECT client_id FROM transfers) AS t ON c.id = t.client_id`,
+			exp: []expectedVec{
+				{tbl: "clients", cols: []string{"id", "name"}},
+				{tbl: "transfers", cols: []string{"client_id"}},
+			},
+		},
+		{
+			name: "inner_alias_shadows_outer",
+			query: `SELECT c.id FROM clients AS c
+WHERE c.id IN (SELECT c.client_id FROM transfers AS c)`,
+			exp: []expectedVec{
+				{tbl: "clients", cols: []string{"id"}},
+				{tbl: "transfers", cols: []string{"client_id"}},
+			},
+		},
+		{
+			name: "schema_qualified_reference_skips_inner_alias",
+			query: `SELECT id FROM clients
+WHERE EXISTS (SELECT public.clients.email FROM orders AS clients)`,
+			exp: []expectedVec{
+				{tbl: "clients", cols: []string{"email", "id"}},
+				{tbl: "orders", cols: []string{}},
+			},
+		},
+		{
+			name: "nested_subqueries",
+			query: `SELECT id FROM clients
+WHERE id IN (SELECT client_id FROM transfers WHERE account_id IN (SELECT id FROM accounts WHERE frozen = true))`,
+			exp: []expectedVec{
+				{tbl: "accounts", cols: []string{"frozen", "id"}},
+				{tbl: "clients", cols: []string{"id"}},
+				{tbl: "transfers", cols: []string{"account_id", "client_id"}},
+			},
+		},
+		{
+			name:  "subquery_reads_cte",
+			query: `WITH vip AS (SELECT client_id FROM orders) SELECT id FROM clients WHERE id IN (SELECT client_id FROM vip)`,
+			exp: []expectedVec{
+				{tbl: "clients", cols: []string{"id"}},
+				{tbl: "orders", cols: []string{"client_id"}},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			vecs, err := parser.Parse(tc.query)
+			require.NoError(t, err)
+			require.Len(t, vecs, len(tc.exp))
+
+			got := make([]expectedVec, 0, len(vecs))
+			for _, vec := range vecs {
+				sel, ok := vec.(parser.SelectVec)
+				require.True(t, ok)
+
+				cols := sel.Columns()
+				sort.Strings(cols)
+				got = append(got, expectedVec{
+					tbl:  sel.Tbl,
+					cols: cols,
+				})
+			}
+
+			sort.Slice(got, func(i, j int) bool {
+				return got[i].tbl < got[j].tbl
+			})
+
+			require.Equal(t, tc.exp, got)
+		})
+	}
+}
+
+func TestParseWriteSubqueryVectors(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name  string
+		query string
+		exp   []parser.Vector
+	}{
+		{
+			name:  "delete_where_in_subquery",
+			query: `DELETE FROM clients WHERE id IN (SELECT client_id FROM transfers WHERE amount > 100)`,
+			exp: []parser.Vector{
+				parser.DeleteVec{Tbl: "clients", Target: nil, Filter: []string{"id"}},
+				parser.SelectVec{Tbl: "transfers", Target: []string{"client_id"}, Filter: []string{"amount"}, Group: nil, Sort: nil},
+			},
+		},
+		{
+			name:  "delete_correlated_exists",
+			query: `DELETE FROM clients AS c WHERE EXISTS (SELECT 1 FROM transfers AS t WHERE t.client_id = c.id)`,
+			exp: []parser.Vector{
+				parser.DeleteVec{Tbl: "clients", Target: nil, Filter: []string{"id"}},
+				parser.SelectVec{Tbl: "transfers", Target: nil, Filter: []string{"client_id"}, Group: nil, Sort: nil},
+			},
+		},
+		{
+			name:  "update_where_in_subquery",
+			query: `UPDATE clients SET name = 'x' WHERE id IN (SELECT client_id FROM transfers WHERE amount > 100)`,
+			exp: []parser.Vector{
+				parser.UpdateVec{Tbl: "clients", Target: []string{"name"}, Filter: []string{"id"}},
+				parser.SelectVec{Tbl: "transfers", Target: []string{"client_id"}, Filter: []string{"amount"}, Group: nil, Sort: nil},
+			},
+		},
+		{
+			name:  "update_correlated_not_exists",
+			query: `UPDATE clients AS c SET name = 'x' WHERE NOT EXISTS (SELECT 1 FROM transfers AS t WHERE t.client_id = c.id)`,
+			exp: []parser.Vector{
+				parser.UpdateVec{Tbl: "clients", Target: []string{"name"}, Filter: []string{"id"}},
+				parser.SelectVec{Tbl: "transfers", Target: nil, Filter: []string{"client_id"}, Group: nil, Sort: nil},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			vecs, err := parser.Parse(tc.query)
+			require.NoError(t, err)
+			require.Equal(t, tc.exp, vecs)
+		})
+	}
+}
+
+func TestParseSubqueryInvalid(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name  string
+		query string
+	}{
+		{
+			name:  "derived_table_cannot_see_siblings",
+			query: `SELECT c.id FROM clients AS c JOIN (SELECT id FROM orders WHERE client_id = c.id) AS o ON c.id = o.id`,
+		},
+		{
+			name:  "unknown_alias_in_subquery",
+			query: `SELECT id FROM clients WHERE id IN (SELECT x.client_id FROM transfers)`,
+		},
+		{
+			name:  "row_comparison_with_subquery",
+			query: `SELECT id FROM clients WHERE (id, name) IN (SELECT client_id, name FROM transfers)`,
+		},
+		{
+			name:  "dotted_alias_in_subquery",
+			query: `SELECT id FROM clients WHERE EXISTS (SELECT public.clients.email FROM orders AS "public.clients")`,
+		},
+		{
+			name:  "array_sublink",
+			query: `SELECT ARRAY(SELECT client_id FROM transfers) FROM clients`,
+		},
+		{
+			name:  "unknown_alias_in_delete_subquery",
+			query: `DELETE FROM clients WHERE EXISTS (SELECT 1 FROM transfers WHERE transfers.client_id = c.id)`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := parser.Parse(tc.query)
+			require.Error(t, err)
+		})
+	}
+}
@@ -39,7 +39,23 @@ func (s *UpdateVec) Columns() []string {
 
 func (UpdateVec) isVector() {}
 
-func parseAexpr(node *pg.Node_AExpr) (Columns, error) { //nolint:cyclop
+var allowedOperators = map[string]struct{}{ //nolint:gochecknoglobals
+	"*":       {},
+	"-":       {},
+	"+":       {},
+	"=":       {},
+	">":       {},
+	"<":       {},
+	"<=":      {},
+	">=":      {},
+	"!=":      {},
+	"<>":      {},
+	"~~":      {},
+	"||":      {},
+	"BETWEEN": {},
+}
+
+func parseAexpr(node *pg.Node_AExpr, sub *subqueryScope) (Columns, error) { //nolint:cyclop,gocognit
 	var columns Columns
 
 	expr := node.AExpr
@@ -56,11 +72,8 @@ func parseAexpr(node *pg.Node_AExpr) (Columns, error) { //nolint:cyclop
 		default:
 			return nil, fmt.Errorf("aexpr clause name (%T): %w", expr, ErrNotImplemented)
 		case *pg.Node_String_:
-			switch expr.String_.GetSval() {
-			default:
+			if _, ok := allowedOperators[expr.String_.GetSval()]; !ok {
 				return nil, fmt.Errorf("aexpr clause operator (%s): %w", expr.String_.GetSval(), ErrNotImplemented)
-			// NOTE: allowed operators
-			case "*", "-", "+", "=", ">", "<", "<=", ">=", "!=", "<>", "~~", "||", "BETWEEN":
 			}
 		}
 
@@ -68,7 +81,7 @@ func parseAexpr(node *pg.Node_AExpr) (Columns, error) { //nolint:cyclop
 		default:
 			return nil, fmt.Errorf("aexpr clause left expr (%T): %w", left, ErrNotImplemented)
 		case *pg.Node_AExpr:
-			nested, err := parseAexpr(left)
+			nested, err := parseAexpr(left, sub)
 			if err != nil {
 				return nil, fmt.Errorf("aexpr clause left nested expr: %w", err)
 			}
@@ -80,6 +93,12 @@ func parseAexpr(node *pg.Node_AExpr) (Columns, error) { //nolint:cyclop
 			}
 
 			columns = append(columns, column)
+		case *pg.Node_SubLink:
+			cols, err := sub.handleSubLink(left)
+			if err != nil {
+				return nil, fmt.Errorf("aexpr clause left subquery: %w", err)
+			}
+			columns = append(columns, cols...)
 		}
 
 		switch right := expr.GetRexpr().GetNode().(type) {
@@ -93,6 +112,12 @@ func parseAexpr(node *pg.Node_AExpr) (Columns, error) { //nolint:cyclop
 			}
 
 			columns = append(columns, column)
+		case *pg.Node_SubLink:
+			cols, err := sub.handleSubLink(right)
+			if err != nil {
+				return nil, fmt.Errorf("aexpr clause right subquery: %w", err)
+			}
+			columns = append(columns, cols...)
 		case *pg.Node_List:
 			for _, node := range right.List.GetItems() {
 				switch node := node.GetNode().(type) {
@@ -107,12 +132,24 @@ func parseAexpr(node *pg.Node_AExpr) (Columns, error) { //nolint:cyclop
 	return columns, nil
 }
 
-func handleUpdate(req *pg.UpdateStmt) ([]Vector, error) { //nolint:gocyclo,cyclop,funlen,gocognit
-	if req.FromClause != nil ||
-		req.GetWithClause() != nil {
+// handleUpdate parses update statement. ctes are common table expressions visible to the statement, nested selects
+// of the where clause can read them.
+func handleUpdate(req *pg.UpdateStmt, ctes cteNames) ([]Vector, error) { //nolint:gocyclo,cyclop,funlen,gocognit
+	if req.FromClause != nil {
 		return nil, fmt.Errorf("unknown clause: %w", ErrNotImplemented)
 	}
 
+	var vectors []Vector
+	if with := req.GetWithClause(); with != nil {
+		cteVectors, scope, err := handleWithClause(with, ctes, nil)
+		if err != nil {
+			return nil, fmt.Errorf("parse with clause: %w", err)
+		}
+
+		vectors = append(vectors, cteVectors...)
+		ctes = scope
+	}
+
 	rel := req.GetRelation()
 	tables := NewTables("public")
 	fqTableName, err := tables.Put(rel.GetCatalogname(), rel.GetSchemaname(), rel.GetRelname(), rel.GetAlias().GetAliasname())
@@ -164,7 +201,7 @@ func handleUpdate(req *pg.UpdateStmt) ([]Vector, error) { //nolint:gocyclo,cyclo
 			case *pg.Node_AConst:
 			case *pg.Node_SetToDefault:
 			case *pg.Node_AExpr:
-				cols, err := parseAexpr(node)
+				cols, err := parseAexpr(node, nil)
 				if err != nil {
 					return nil, fmt.Errorf("resTarget expr: %w", err)
 				}
@@ -186,36 +223,50 @@ func handleUpdate(req *pg.UpdateStmt) ([]Vector, error) { //nolint:gocyclo,cyclo
 		return nil, fmt.Errorf("parse returning columns: %w", err)
 	}
 
-	whereColumns, err := parseWhereClause(req.GetWhereClause())
+	sub := newSubqueryScope(ctes, tables)
+	whereColumns, err := parseWhereClause(req.GetWhereClause(), sub)
 	if err != nil {
 		return nil, fmt.Errorf("parse where clause: %w", err)
 	}
 
-	allColumns := slices.Concat(targetCols, retCols, whereColumns)
+	table2vec := map[string]*UpdateVec{
+		fqTableName: {Tbl: fqTableName, Target: nil, Filter: nil},
+	}
 
-	table2target := make(map[string]Columns, len(allColumns))
-	for _, column := range allColumns {
-		tbl, ok := tables.Get(column.Table())
-		if !ok {
-			return nil, fmt.Errorf("table not found: %s", column.Table()) //nolint:err113
-		}
+	clauses := []struct {
+		columns Columns
+		field   func(vec *UpdateVec) *[]string
+	}{
+		{columns: slices.Concat(targetCols, retCols), field: func(vec *UpdateVec) *[]string { return &vec.Target }},
+		{columns: whereColumns, field: func(vec *UpdateVec) *[]string { return &vec.Filter }},
+	}
+	for _, clause := range clauses {
+		for _, column := range clause.columns {
+			tbl, ok := tables.Get(column.Table())
+			if !ok {
+				return nil, fmt.Errorf("table not found: %s", column.Table()) //nolint:err113
+			}
 
-		table2target[tbl] = append(table2target[tbl], column)
+			vec, ok := table2vec[tbl]
+			if !ok {
+				vec = &UpdateVec{Tbl: tbl, Target: nil, Filter: nil}
+				table2vec[tbl] = vec
+			}
+
+			field := clause.field(vec)
+			*field = append(*field, column.column)
+		}
 	}
 
-	vectors := make([]Vector, 0, len(table2target))
-	for tbl, cols := range table2target {
-		vectors = append(vectors, UpdateVec{
-			Tbl:    tbl,
-			Target: cols.ListNames(),
-			Filter: nil, // TODO: impl
-		})
+	for _, vec := range table2vec {
+		vectors = append(vectors, *vec)
 	}
+	vectors = append(vectors, sub.vectors...)
 
 	return vectors, nil
 }
 
-func parseWhereClause(node *pg.Node) (Columns, error) { //nolint:cyclop,gocognit,gocyclo
+func parseWhereClause(node *pg.Node, sub *subqueryScope) (Columns, error) { //nolint:cyclop,gocognit,gocyclo
 	var columns Columns
 	if node == nil {
 		return columns, nil
@@ -231,31 +282,44 @@ func parseWhereClause(node *pg.Node) (Columns, error) { //nolint:cyclop,gocognit
 			default:
 				return nil, fmt.Errorf("bool expr argument (%T): %w", node.GetNode(), ErrNotImplemented)
 			case *pg.Node_NullTest:
-				cols, err := parseWhereClause(node)
+				cols, err := parseWhereClause(node, sub)
 				if err != nil {
 					return nil, fmt.Errorf("parse update where null test: %w", err)
 				}
 				columns = append(columns, cols...)
 			case *pg.Node_BoolExpr:
-				cols, err := parseWhereClause(node)
+				cols, err := parseWhereClause(node, sub)
 				if err != nil {
 					return nil, fmt.Errorf("parse update where bool: %w", err)
 				}
 				columns = append(columns, cols...)
 			case *pg.Node_AExpr:
-				cols, err := parseWhereClause(node)
+				cols, err := parseWhereClause(node, sub)
 				if err != nil {
 					return nil, fmt.Errorf("parse update where bool 2: %w", err)
 				}
 				columns = append(columns, cols...)
+			case *pg.Node_SubLink:
+				cols, err := parseWhereClause(node, sub)
+				if err != nil {
+					return nil, fmt.Errorf("parse where bool subquery: %w", err)
+				}
+				columns = append(columns, cols...)
 			}
 		}
 	case *pg.Node_AExpr:
-		cols, err := parseAexpr(node)
+		cols, err := parseAexpr(node, sub)
 		if err != nil {
 			return nil, fmt.Errorf("resTarget expr: %w", err)
 		}
 
+		columns = append(columns, cols...)
+	case *pg.Node_SubLink:
+		cols, err := sub.handleSubLink(node)
+		if err != nil {
+			return nil, fmt.Errorf("where subquery: %w", err)
+		}
+
 		columns = append(columns, cols...)
 	case *pg.Node_NullTest:
 		switch arg := node.NullTest.GetArg().GetNode().(type) {
@@ -269,7 +333,7 @@ func parseWhereClause(node *pg.Node) (Columns, error) { //nolint:cyclop,gocognit
 
 			columns = append(columns, column)
 		case *pg.Node_AExpr:
-			cols, err := parseAexpr(arg)
+			cols, err := parseAexpr(arg, sub)
 			if err != nil {
 				return nil, fmt.Errorf("null test arg expr: %w", err)
 			}
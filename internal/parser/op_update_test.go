@@ -48,6 +48,8 @@ func TestParseUpdateValid(t *testing.T) {
 	test("UPDATE t1 SET c1 = 'value' WHERE c2 IN (1, 2, 3)")
 	test("UPDATE t1 SET c1 = 'value' WHERE c2 LIKE 'prefix%'")
 	test("UPDATE t1 SET c1 = 'value' WHERE c2 IS NULL")
+	test("UPDATE t1 SET c1 = 'value' WHERE id IN (SELECT id FROM t2 WHERE active = true)")
+	test("UPDATE t1 SET c1 = 'value' WHERE EXISTS (SELECT 1 FROM t2 WHERE t2.id = t1.id)")
 
 	test("UPDATE t1 SET c1 = c1 + 1")
 	test("UPDATE t1 SET c1 = c1 * 1.1")
@@ -73,5 +75,4 @@ func TestParseUpdateInvalid(t *testing.T) {
 	test("UPDATE t1 SET c1 = t2.c1 FROM t2 WHERE t1.id = t2.id", "update_from")
 	test("UPDATE t1 SET c1 = t2.c1 FROM t2 WHERE t1.id = t2.id", "update_from")
 	test("UPDATE t1 SET c1 = (SELECT MAX(c1) FROM t2)", "subquery_in_target_field")
-	test("UPDATE t1 SET c1 = 'value' WHERE id IN (SELECT id FROM t2 WHERE active = true)", "subquery_in_where")
 }
@@ -0,0 +1,102 @@
+// Database Gateway provides access to servers with ACL for safe and restricted database interactions.
+// Copyright (C) 2024  Kirill Zhuravlev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package parser
+
+import (
+	"fmt"
+	"maps"
+
+	pg "github.com/pganalyze/pg_query_go/v6"
+)
+
+// cteNames is a set of common table expressions that are visible on the current query level, with their output
+// columns.
+type cteNames map[string]relationColumns
+
+func (c cteNames) has(rel *pg.RangeVar) bool {
+	if rel.GetCatalogname() != "" || rel.GetSchemaname() != "" {
+		return false
+	}
+
+	_, ok := c[rel.GetRelname()]
+
+	return ok
+}
+
+// handleWithClause parses every CTE body into vectors and returns the set of names that are visible to the main
+// query. Parent scope is not modified.
+func handleWithClause(with *pg.WithClause, parent cteNames, outer *Tables) ([]Vector, cteNames, error) { //nolint:cyclop
+	scope := maps.Clone(parent)
+	if scope == nil {
+		scope = make(cteNames)
+	}
+
+	var vectors []Vector
+	for _, node := range with.GetCtes() {
+		cteNode, ok := node.GetNode().(*pg.Node_CommonTableExpr)
+		if !ok {
+			return nil, nil, fmt.Errorf("with clause item (%T): %w", node.GetNode(), ErrNotImplemented)
+		}
+
+		cte := cteNode.CommonTableExpr
+		if cte.GetSearchClause() != nil || cte.GetCycleClause() != nil {
+			return nil, nil, fmt.Errorf("cte search/cycle clause: %w", ErrNotImplemented)
+		}
+
+		name := cte.GetCtename()
+		if name == "" {
+			return nil, nil, ErrRelationEmpty
+		}
+
+		// Recursive CTE can reference itself from its own body. Its columns are not known yet, and rows of the self
+		// reference are read by the CTE itself.
+		if with.GetRecursive() {
+			scope[name] = nil
+		}
+
+		var (
+			cteVectors []Vector
+			columns    relationColumns
+			err        error
+		)
+		switch query := cte.GetCtequery().GetNode().(type) {
+		default:
+			return nil, nil, fmt.Errorf("cte query (%T): %w", query, ErrNotImplemented)
+		case *pg.Node_SelectStmt:
+			cteVectors, columns, err = selectWithOutput(query.SelectStmt, scope, outer)
+		case *pg.Node_InsertStmt:
+			cteVectors, err = handleInsert(query.InsertStmt)
+		case *pg.Node_UpdateStmt:
+			cteVectors, err = handleUpdate(query.UpdateStmt, scope)
+		case *pg.Node_DeleteStmt:
+			cteVectors, err = handleDelete(query.DeleteStmt, scope)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("parse cte %q: %w", name, err)
+		}
+
+		columns, err = columns.rename(cte.GetAliascolnames())
+		if err != nil {
+			return nil, nil, fmt.Errorf("parse cte %q: %w", name, err)
+		}
+
+		vectors = append(vectors, cteVectors...)
+		scope[name] = columns
+	}
+
+	return vectors, scope, nil
+}
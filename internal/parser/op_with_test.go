@@ -0,0 +1,277 @@
+// Database Gateway provides access to servers with ACL for safe and restricted database interactions.
+// Copyright (C) 2024  Kirill Zhuravlev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package parser_test
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/kazhuravlev/database-gateway/internal/parser"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseWithVectors(t *testing.T) {
+	t.Parallel()
+
+	type expectedVec struct {
+		op   string
+		tbl  string
+		cols []string
+	}
+
+	testCases := []struct {
+		name  string
+		query string
+		exp   []expectedVec
+	}{
+		{
+			name: "single_cte",
+			query: `WITH paid AS (SELECT client_id FROM orders WHERE status = 'paid')
+SELECT client_id FROM paid`,
+			exp: []expectedVec{
+				{op: "select", tbl: "orders", cols: []string{"client_id", "status"}},
+			},
+		},
+		{
+			name: "cte_joined_with_table",
+			query: `WITH paid AS (SELECT client_id FROM orders WHERE status = 'paid')
+SELECT c.name FROM clients AS c JOIN paid AS p ON c.id = p.client_id`,
+			exp: []expectedVec{
+				{op: "select", tbl: "clients", cols: []string{"id", "name"}},
+				{op: "select", tbl: "orders", cols: []string{"client_id", "status"}},
+			},
+		},
+		{
+			name: "cte_references_previous_cte",
+			query: `WITH a AS (SELECT id FROM clients), b AS (SELECT id FROM a)
+SELECT id FROM b`,
+			exp: []expectedVec{
+				{op: "select", tbl: "clients", cols: []string{"id"}},
+			},
+		},
+		{
+			name: "nested_with",
+			query: `WITH a AS (WITH b AS (SELECT id FROM clients) SELECT id FROM b)
+SELECT id FROM a`,
+			exp: []expectedVec{
+				{op: "select", tbl: "clients", cols: []string{"id"}},
+			},
+		},
+		{
+			name: "schema_qualified_name_is_not_cte",
+			query: `WITH clients AS (SELECT id FROM orders)
+SELECT id FROM public.clients`,
+			exp: []expectedVec{
+				{op: "select", tbl: "orders", cols: []string{"id"}},
+				{op: "select", tbl: "public.clients", cols: []string{"id"}},
+			},
+		},
+		{
+			// Without RECURSIVE the CTE name inside its own body refers to a real table.
+			name:  "cte_body_shadowed_name_is_real_table",
+			query: `WITH clients AS (SELECT id FROM clients) SELECT id FROM clients`,
+			exp: []expectedVec{
+				{op: "select", tbl: "clients", cols: []string{"id"}},
+			},
+		},
+		{
+			name: "recursive_cte",
+			query: `WITH RECURSIVE tree AS (
+    SELECT id, parent_id FROM categories WHERE parent_id IS NULL
+    UNION ALL
+    SELECT c.id, c.parent_id FROM categories AS c JOIN tree AS t ON c.parent_id = t.id
+)
+SELECT id FROM tree`,
+			exp: []expectedVec{
+				{op: "select", tbl: "categories", cols: []string{"id", "parent_id"}},
+				{op: "select", tbl: "categories", cols: []string{"id", "parent_id"}},
+			},
+		},
+		{
+			name: "delete_cte",
+			query: `WITH removed AS (DELETE FROM orders WHERE status = 'draft' RETURNING id)
+SELECT id FROM removed`,
+			exp: []expectedVec{
+				{op: "delete", tbl: "orders", cols: []string{"id", "status"}},
+			},
+		},
+		{
+			name: "update_cte",
+			query: `WITH changed AS (UPDATE orders SET status = 'paid' WHERE id = 1 RETURNING client_id)
+SELECT c.name FROM clients AS c JOIN changed AS ch ON c.id = ch.client_id`,
+			exp: []expectedVec{
+				{op: "select", tbl: "clients", cols: []string{"id", "name"}},
+				{op: "update", tbl: "orders", cols: []string{"client_id", "id", "status"}},
+			},
+		},
+		{
+			name: "insert_cte",
+			query: `WITH created AS (INSERT INTO orders (client_id) VALUES (1) RETURNING id)
+SELECT id FROM created`,
+			exp: []expectedVec{
+				{op: "insert", tbl: "orders", cols: []string{"client_id", "id"}},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			vecs, err := parser.Parse(tc.query)
+			require.NoError(t, err)
+
+			got := make([]expectedVec, 0, len(vecs))
+			for _, vec := range vecs {
+				var item expectedVec
+				switch vec := vec.(type) {
+				default:
+					t.Fatalf("unexpected vector type %T", vec)
+				case parser.SelectVec:
+					item = expectedVec{op: "select", tbl: vec.Tbl, cols: vec.Columns()}
+				case parser.InsertVec:
+					item = expectedVec{op: "insert", tbl: vec.Tbl, cols: vec.Columns()}
+				case parser.UpdateVec:
+					item = expectedVec{op: "update", tbl: vec.Tbl, cols: vec.Columns()}
+				case parser.DeleteVec:
+					item = expectedVec{op: "delete", tbl: vec.Tbl, cols: vec.Columns()}
+				}
+
+				sort.Strings(item.cols)
+				got = append(got, item)
+			}
+
+			sort.Slice(got, func(i, j int) bool {
+				return got[i].tbl < got[j].tbl
+			})
+
+			require.Equal(t, tc.exp, got)
+		})
+	}
+}
+
+func TestParseWithWriteScopes(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name  string
+		query string
+		exp   []parser.Vector
+	}{
+		{
+			name: "delete_using_cte",
+			query: `WITH a AS (SELECT id FROM clients WHERE active = true)
+DELETE FROM orders USING a WHERE orders.client_id = a.id`,
+			exp: []parser.Vector{
+				parser.SelectVec{Tbl: "clients", Target: []string{"id"}, Filter: []string{"active", "id"}, Group: nil, Sort: nil},
+				parser.DeleteVec{Tbl: "orders", Target: nil, Filter: []string{"client_id"}},
+			},
+		},
+		{
+			name: "delete_where_in_cte",
+			query: `WITH a AS (SELECT id FROM clients WHERE active = true)
+DELETE FROM orders WHERE client_id IN (SELECT id FROM a)`,
+			exp: []parser.Vector{
+				parser.SelectVec{Tbl: "clients", Target: []string{"id"}, Filter: []string{"active"}, Group: nil, Sort: nil},
+				parser.DeleteVec{Tbl: "orders", Target: nil, Filter: []string{"client_id"}},
+			},
+		},
+		{
+			name: "delete_using_table",
+			query: `DELETE FROM orders AS o USING clients AS c
+WHERE o.client_id = c.id AND c.email = 'x' RETURNING o.id`,
+			exp: []parser.Vector{
+				parser.DeleteVec{Tbl: "orders", Target: []string{"id"}, Filter: []string{"client_id"}},
+				parser.SelectVec{Tbl: "clients", Target: nil, Filter: []string{"id", "email"}, Group: nil, Sort: nil},
+			},
+		},
+		{
+			name: "delete_cte_reads_sibling_cte",
+			query: `WITH a AS (SELECT id FROM clients WHERE active = true),
+d AS (DELETE FROM orders USING a WHERE orders.client_id = a.id RETURNING orders.id)
+SELECT id FROM d`,
+			exp: []parser.Vector{
+				parser.SelectVec{Tbl: "clients", Target: []string{"id"}, Filter: []string{"active", "id"}, Group: nil, Sort: nil},
+				parser.DeleteVec{Tbl: "orders", Target: []string{"id"}, Filter: []string{"client_id"}},
+			},
+		},
+		{
+			name: "update_cte_reads_sibling_cte",
+			query: `WITH a AS (SELECT id FROM clients WHERE active = true),
+u AS (UPDATE orders SET status = 'x' WHERE client_id IN (SELECT id FROM a) RETURNING id)
+SELECT id FROM u`,
+			exp: []parser.Vector{
+				parser.SelectVec{Tbl: "clients", Target: []string{"id"}, Filter: []string{"active"}, Group: nil, Sort: nil},
+				parser.UpdateVec{Tbl: "orders", Target: []string{"status", "id"}, Filter: []string{"client_id"}},
+			},
+		},
+		{
+			name: "update_where_exists_in_cte",
+			query: `WITH a AS (SELECT id FROM clients)
+UPDATE orders SET status = 'x' WHERE EXISTS (SELECT 1 FROM a WHERE a.id = orders.client_id)`,
+			exp: []parser.Vector{
+				parser.SelectVec{Tbl: "clients", Target: []string{"id"}, Filter: []string{"id"}, Group: nil, Sort: nil},
+				parser.UpdateVec{Tbl: "orders", Target: []string{"status"}, Filter: []string{"client_id"}},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			vecs, err := parser.Parse(tc.query)
+			require.NoError(t, err)
+			require.Equal(t, tc.exp, vecs)
+		})
+	}
+}
+
+func TestParseWithInvalid(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name  string
+		query string
+	}{
+		{
+			name:  "unknown_alias_in_main_query",
+			query: `WITH a AS (SELECT id FROM clients) SELECT b.id FROM a`,
+		},
+		{
+			name:  "unsupported_clause_in_cte_body",
+			query: `WITH a AS (SELECT id FROM clients FOR UPDATE) SELECT id FROM a`,
+		},
+		{
+			name:  "ambiguous_column_in_delete_using_cte",
+			query: `WITH a AS (SELECT id FROM clients) DELETE FROM orders USING a WHERE client_id = 1`,
+		},
+		{
+			name:  "computed_column_of_cte_in_delete",
+			query: `WITH a AS (SELECT count(id) AS n FROM clients) DELETE FROM orders USING a WHERE a.n > 1`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := parser.Parse(tc.query)
+			require.Error(t, err)
+		})
+	}
+}
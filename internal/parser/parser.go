@@ -29,6 +29,7 @@ var (
 	ErrNotImplemented      = errors.New("not implemented")
 	ErrRelationEmpty       = errors.New("relation cannot be empty")
 	ErrInvalidTableMapping = errors.New("invalid table mapping")
+	ErrInvalidAlias        = errors.New("invalid alias")
 )
 
 type Vector interface {
@@ -51,12 +52,12 @@ func Parse(query string) ([]Vector, error) { //nolint:cyclop // this is not so c
 	default:
 		return nil, fmt.Errorf("unsupported root node type (%T): %w", node, ErrNotImplemented)
 	case *pg.Node_SelectStmt:
-		res, err := handleSelect(node.SelectStmt)
+		res, err := handleSelect(node.SelectStmt, nil, nil)
 		if err != nil {
 			return nil, fmt.Errorf("parse select: %w", err)
 		}
 
-		return res, nil
+		return derefVectors(res), nil
 	case *pg.Node_InsertStmt:
 		res, err := handleInsert(node.InsertStmt)
 		if err != nil {
@@ -65,33 +66,69 @@ func Parse(query string) ([]Vector, error) { //nolint:cyclop // this is not so c
 
 		return res, nil
 	case *pg.Node_UpdateStmt:
-		res, err := handleUpdate(node.UpdateStmt)
+		res, err := handleUpdate(node.UpdateStmt, nil)
 		if err != nil {
 			return nil, fmt.Errorf("parse update: %w", err)
 		}
 
-		return res, nil
+		return derefVectors(res), nil
 	case *pg.Node_DeleteStmt:
-		res, err := handleDelete(node.DeleteStmt)
+		res, err := handleDelete(node.DeleteStmt, nil)
 		if err != nil {
 			return nil, fmt.Errorf("parse delete: %w", err)
 		}
 
-		return res, nil
+		return derefVectors(res), nil
+	}
+}
+
+// derefVectors replaces select vectors that are collected by pointer with their values. Vectors of nested scopes are
+// not merged, so every scope is checked on its own.
+func derefVectors(vectors []Vector) []Vector {
+	res := make([]Vector, len(vectors))
+	for i, vec := range vectors {
+		if sel, ok := vec.(*SelectVec); ok {
+			res[i] = *sel
+
+			continue
+		}
+
+		res[i] = vec
 	}
+
+	return res
 }
 
 type Tables struct {
-	m             map[string]string
+	// m maps real relation names (bare, schema-qualified and fully qualified) to fully qualified table names.
+	m map[string]string
+	// aliases maps aliases and names of virtual relations. It is kept apart from m so that a schema-qualified
+	// reference never matches an alias.
+	aliases       map[string]string
+	virtual       map[string]struct{}
 	defaultSchema string
 	sources       int
+	// parent is a scope of the outer query. It is used to resolve correlated references from subqueries.
+	parent *Tables
+	// columns are output columns of virtual relations in the order of sources. Nil columns are not tracked.
+	columns      map[string]relationColumns
+	virtualOrder []string
+	// correlated are columns of this scope that are referenced from nested selects. The nested select leaves them to
+	// the clause of this scope that contains it.
+	correlated Columns
 }
 
 func NewTables(defaultSchema string) *Tables {
 	return &Tables{
 		m:             make(map[string]string),
+		aliases:       make(map[string]string),
+		virtual:       make(map[string]struct{}),
 		defaultSchema: defaultSchema,
 		sources:       0,
+		parent:        nil,
+		columns:       make(map[string]relationColumns),
+		virtualOrder:  nil,
+		correlated:    nil,
 	}
 }
 
@@ -100,6 +137,10 @@ func (t *Tables) Put(catalog, schema, relation, alias string) (string, error) {
 		return "", ErrRelationEmpty
 	}
 
+	if err := validateAlias(alias); err != nil {
+		return "", err
+	}
+
 	fqtnBuf := bytes.NewBuffer(nil)
 	if catalog != "" {
 		fqtnBuf.WriteString(catalog)
@@ -122,17 +163,144 @@ func (t *Tables) Put(catalog, schema, relation, alias string) (string, error) {
 		t.m[t.defaultSchema+"."+relation] = fqtn
 	}
 	if alias != "" {
-		t.m[alias] = fqtn
+		t.aliases[alias] = fqtn
 	}
 
 	return fqtn, nil
 }
 
+// PutVirtual registers a relation that is not backed by a real table (like a CTE). Columns of virtual relations are
+// resolved as usual, but such relations are never reported by GetAll.
+func (t *Tables) PutVirtual(relation, alias string) error {
+	if relation == "" {
+		return ErrRelationEmpty
+	}
+
+	if err := validateAlias(relation); err != nil {
+		return err
+	}
+
+	if err := validateAlias(alias); err != nil {
+		return err
+	}
+
+	t.sources++
+
+	t.aliases[relation] = relation
+	t.virtual[relation] = struct{}{}
+	t.virtualOrder = append(t.virtualOrder, relation)
+	if alias != "" {
+		t.aliases[alias] = relation
+	}
+
+	return nil
+}
+
+// setColumns remembers output columns of the virtual relation, so clauses over it can be mapped to real tables.
+func (t *Tables) setColumns(relation string, columns relationColumns) {
+	t.columns[relation] = columns
+}
+
+// validateAlias rejects aliases that contain a dot. Column references are compared by their joined qualifier, so
+// such an alias is indistinguishable from a schema-qualified table name.
+func validateAlias(alias string) error {
+	if strings.Contains(alias, ".") {
+		return fmt.Errorf("alias %q contains a dot: %w", alias, ErrInvalidAlias)
+	}
+
+	return nil
+}
+
+// selectField returns the clause of the select vector that receives a column.
+type selectField func(vec *SelectVec) *[]string
+
+// attribute resolves the column of a clause in the current scope. It returns the real table of the column when the
+// caller should add the column to the vector of that table. Columns of outer queries are left to the clause of the
+// outer query that contains the nested select. When mapVirtual is set, columns of CTE and derived tables are added to
+// vectors of the tables they are read from, and refused when they are computed.
+func (t *Tables) attribute(column Column, field selectField, mapVirtual bool) (string, bool, error) {
+	name := column.Table()
+	if name == "" && t.sources > 1 {
+		return "", false, fmt.Errorf("ambiguous column reference (%s): %w", column.column, ErrNotImplemented)
+	}
+
+	if name != "" && t.parent != nil && !t.HasMapping(name) {
+		if _, _, ok := t.parent.Resolve(name); !ok {
+			return "", false, fmt.Errorf("table not found: %s", name) //nolint:err113
+		}
+
+		t.parent.correlated = append(t.parent.correlated, column)
+
+		return "", false, nil
+	}
+
+	tbl, virtual, ok := t.Resolve(name)
+	if !ok {
+		return "", false, fmt.Errorf("table not found: %s", name) //nolint:err113
+	}
+
+	if !virtual {
+		return tbl, true, nil
+	}
+
+	columns := t.columns[tbl]
+	if !mapVirtual || columns == nil {
+		return "", false, nil
+	}
+
+	out, ok := columns.find(column.column)
+	if !ok {
+		return "", false, fmt.Errorf("column %q of %q: %w", column.column, tbl, ErrNotImplemented)
+	}
+
+	if out.computed {
+		return "", false, fmt.Errorf("computed column %q of %q can not be checked: %w", column.column, tbl, ErrNotImplemented)
+	}
+
+	for _, origin := range out.origins {
+		dst := field(origin.vec)
+		*dst = append(*dst, origin.column)
+	}
+
+	return "", false, nil
+}
+
+// IsVirtual returns true when the resolved table name belongs to a virtual relation.
+func (t *Tables) IsVirtual(fqtn string) bool {
+	_, ok := t.virtual[fqtn]
+
+	return ok
+}
+
 func (t *Tables) SourcesCount() int {
 	return t.sources
 }
 
+// lookup returns the direct mapping for the name. Qualified names are matched only against real relations.
+func (t *Tables) lookup(name string) (string, bool) {
+	if !strings.Contains(name, ".") {
+		if res, ok := t.aliases[name]; ok {
+			return res, true
+		}
+	}
+
+	res, ok := t.m[name]
+
+	return res, ok
+}
+
 func (t *Tables) Get(name string) (string, bool) {
+	if !strings.Contains(name, ".") {
+		if res, ok := t.aliases[name]; ok {
+			// Aliases always point to a final table name.
+			return res, true
+		}
+	}
+
+	return t.getRelation(name)
+}
+
+func (t *Tables) getRelation(name string) (string, bool) {
 	res, ok := t.m[name]
 	if !ok {
 		return "", false
@@ -142,12 +310,28 @@ func (t *Tables) Get(name string) (string, bool) {
 		return res, true
 	}
 
-	return t.Get(res)
+	return t.getRelation(res)
+}
+
+// Resolve returns the table for the given reference. References that are unknown for the current scope are
+// resolved through outer scopes, except the empty (unqualified) one. virtual is true when the reference points to a
+// virtual relation.
+func (t *Tables) Resolve(name string) (string, bool, bool) {
+	if _, ok := t.lookup(name); ok || name == "" || t.parent == nil {
+		tbl, ok := t.Get(name)
+		if !ok {
+			return "", false, false
+		}
+
+		return tbl, t.IsVirtual(tbl), true
+	}
+
+	return t.parent.Resolve(name)
 }
 
 // HasMapping returns true if a direct mapping exists for the given name (for testing).
 func (t *Tables) HasMapping(name string) bool {
-	_, exists := t.m[name]
+	_, exists := t.lookup(name)
 
 	return exists
 }
@@ -159,12 +343,12 @@ func (t *Tables) SetMapping(name, target string) {
 
 // Finalize will check the collected tables and in case of only one source table exists - creates a new Empty mapping.
 func (t *Tables) Finalize() error {
-	all, err := t.GetAll()
+	all, err := t.resolveAll()
 	if err != nil {
 		return fmt.Errorf("failed to get all tables: %w", err)
 	}
 	if t.sources == 1 && len(all) == 1 {
-		t.m[""] = all[0]
+		t.aliases[""] = all[0]
 	}
 
 	return nil
@@ -179,10 +363,32 @@ func (t *Tables) Len() (int, error) {
 	return len(all), nil
 }
 
+// GetAll returns all real tables. Virtual relations are excluded.
 func (t *Tables) GetAll() ([]string, error) {
+	all, err := t.resolveAll()
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]string, 0, len(all))
+	for _, tbl := range all {
+		if t.IsVirtual(tbl) {
+			continue
+		}
+
+		res = append(res, tbl)
+	}
+
+	return res, nil
+}
+
+func (t *Tables) resolveAll() ([]string, error) {
 	final := make(map[string]struct{})
+	for _, res := range t.aliases {
+		final[res] = struct{}{}
+	}
 	for k := range t.m {
-		res, ok := t.Get(k)
+		res, ok := t.getRelation(k)
 		if !ok {
 			return nil, fmt.Errorf("%w for key %q", ErrInvalidTableMapping, k)
 		}
@@ -0,0 +1,90 @@
+// Database Gateway provides access to servers with ACL for safe and restricted database interactions.
+// Copyright (C) 2024  Kirill Zhuravlev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	pg "github.com/pganalyze/pg_query_go/v6"
+)
+
+// AddReturning appends a RETURNING clause with columns of the changed table to UPDATE and DELETE statements, so the
+// changed rows can be shown to the user. Columns are returned by columns, the same way ExpandStar resolves them.
+// Other statements and statements that already have a RETURNING clause are returned unchanged.
+func AddReturning(query string, columns ColumnsFn) (string, error) {
+	result, err := pg.Parse(query)
+	if err != nil {
+		return "", fmt.Errorf("parse error: %w", err)
+	}
+
+	if len(result.GetStmts()) != 1 {
+		return query, nil
+	}
+
+	var (
+		rel       *pg.RangeVar
+		returning *[]*pg.Node
+	)
+	switch node := result.GetStmts()[0].GetStmt().GetNode().(type) {
+	case *pg.Node_UpdateStmt:
+		rel, returning = node.UpdateStmt.GetRelation(), &node.UpdateStmt.ReturningList
+	case *pg.Node_DeleteStmt:
+		rel, returning = node.DeleteStmt.GetRelation(), &node.DeleteStmt.ReturningList
+	default:
+		return query, nil
+	}
+
+	if len(*returning) != 0 {
+		return query, nil
+	}
+
+	var parts []string
+	for _, part := range []string{rel.GetCatalogname(), rel.GetSchemaname(), rel.GetRelname()} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+
+	cols, err := columns(strings.Join(parts, "."))
+	if err != nil {
+		return "", fmt.Errorf("columns of %q: %w", rel.GetRelname(), err)
+	}
+
+	if len(cols) == 0 {
+		return "", fmt.Errorf("no columns to return from %q: %w", rel.GetRelname(), ErrNotImplemented)
+	}
+
+	qualifier := rel.GetRelname()
+	if alias := rel.GetAlias().GetAliasname(); alias != "" {
+		qualifier = alias
+	}
+
+	for _, col := range cols {
+		*returning = append(*returning, pg.MakeResTargetNodeWithVal(
+			pg.MakeColumnRefNode(makeStringNodes([]string{qualifier, col}), -1),
+			-1,
+		))
+	}
+
+	res, err := pg.Deparse(result)
+	if err != nil {
+		return "", fmt.Errorf("deparse query: %w", err)
+	}
+
+	return res, nil
+}
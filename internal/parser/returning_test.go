@@ -0,0 +1,80 @@
+// Database Gateway provides access to servers with ACL for safe and restricted database interactions.
+// Copyright (C) 2024  Kirill Zhuravlev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package parser_test
+
+import (
+	"testing"
+
+	"github.com/kazhuravlev/database-gateway/internal/parser"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddReturning(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name  string
+		query string
+		exp   string
+	}{
+		{
+			name:  "update",
+			query: `update clients set name = 'x' where id = 1`,
+			exp:   `UPDATE clients SET name = 'x' WHERE id = 1 RETURNING clients.id, clients.name`,
+		},
+		{
+			name:  "schema_qualified_delete",
+			query: `delete from public.orders where client_id = 1`,
+			exp:   `DELETE FROM public.orders WHERE client_id = 1 RETURNING orders.id, orders.client_id`,
+		},
+		{
+			name:  "alias",
+			query: `update clients c set name = 'x' where c.id = 1`,
+			exp:   `UPDATE clients c SET name = 'x' WHERE c.id = 1 RETURNING c.id, c.name`,
+		},
+		{
+			name:  "own_returning_is_kept",
+			query: `delete from clients where id = 1 returning id`,
+			exp:   `delete from clients where id = 1 returning id`,
+		},
+		{
+			name:  "select_is_not_changed",
+			query: `select id from clients`,
+			exp:   `select id from clients`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			res, err := parser.AddReturning(tc.query, testStarColumns)
+			require.NoError(t, err)
+			require.Equal(t, tc.exp, res)
+
+			_, err = parser.Parse(res)
+			require.NoError(t, err)
+		})
+	}
+
+	t.Run("unknown_table", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := parser.AddReturning(`delete from secrets`, testStarColumns)
+		require.ErrorIs(t, err, errUnknownTable)
+	})
+}
@@ -0,0 +1,289 @@
+// Database Gateway provides access to servers with ACL for safe and restricted database interactions.
+// Copyright (C) 2024  Kirill Zhuravlev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package parser
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	pg "github.com/pganalyze/pg_query_go/v6"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// ColumnsFn returns the list of columns that can be selected from the table.
+type ColumnsFn func(table string) ([]string, error)
+
+// ExpandStar rewrites star expressions (`*`, `alias.*`) of every select target list into explicit lists of columns
+// returned by columns. Stars over CTE and derived tables are kept as is, because their columns are produced by
+// nested selects that are expanded too. The query is returned unchanged when it has nothing to expand.
+func ExpandStar(query string, columns ColumnsFn) (string, error) {
+	result, err := pg.Parse(query)
+	if err != nil {
+		return "", fmt.Errorf("parse error: %w", err)
+	}
+
+	if len(result.GetStmts()) != 1 {
+		return query, nil
+	}
+
+	sel, ok := result.GetStmts()[0].GetStmt().GetNode().(*pg.Node_SelectStmt)
+	if !ok {
+		return query, nil
+	}
+
+	expander := starExpander{columns: columns, changed: false}
+	if err := expander.rewriteSelect(sel.SelectStmt, nil); err != nil {
+		return "", err
+	}
+
+	if !expander.changed {
+		return query, nil
+	}
+
+	res, err := pg.Deparse(result)
+	if err != nil {
+		return "", fmt.Errorf("deparse query: %w", err)
+	}
+
+	return res, nil
+}
+
+// starRelation is a relation from FROM clause which can be a source of star expression.
+type starRelation struct {
+	// table is a fully qualified name of real table. Empty for virtual relations.
+	table string
+	// qualifier is a list of names that should be used to reference columns of relation.
+	qualifier []string
+	// names contains all names that can be used to reference relation.
+	names []string
+}
+
+type starExpander struct {
+	columns ColumnsFn
+	changed bool
+}
+
+func (e *starExpander) rewriteSelect(sel *pg.SelectStmt, ctes cteNames) error { //nolint:cyclop
+	if sel == nil {
+		return nil
+	}
+
+	if with := sel.GetWithClause(); with != nil {
+		scope := maps.Clone(ctes)
+		if scope == nil {
+			scope = make(cteNames)
+		}
+
+		for _, node := range with.GetCtes() {
+			cte := node.GetCommonTableExpr()
+			if cte == nil {
+				continue
+			}
+
+			if with.GetRecursive() {
+				scope[cte.GetCtename()] = nil
+			}
+
+			if err := e.rewriteSelect(cte.GetCtequery().GetSelectStmt(), scope); err != nil {
+				return err
+			}
+
+			scope[cte.GetCtename()] = nil
+		}
+
+		ctes = scope
+	}
+
+	if sel.GetOp() != pg.SetOperation_SETOP_NONE {
+		if err := e.rewriteSelect(sel.GetLarg(), ctes); err != nil {
+			return err
+		}
+
+		return e.rewriteSelect(sel.GetRarg(), ctes)
+	}
+
+	var relations []starRelation
+	for _, node := range sel.GetFromClause() {
+		relations = collectStarRelations(relations, ctes, node)
+	}
+
+	targets := make([]*pg.Node, 0, len(sel.GetTargetList()))
+	for _, target := range sel.GetTargetList() {
+		expanded, err := e.expandTarget(target, relations)
+		if err != nil {
+			return err
+		}
+
+		targets = append(targets, expanded...)
+	}
+	sel.TargetList = targets
+
+	// Nested selects from expressions and FROM clause see the same CTE as the current select.
+	return walkNestedSelects(sel.ProtoReflect(), func(nested *pg.SelectStmt) error {
+		return e.rewriteSelect(nested, ctes)
+	})
+}
+
+func (e *starExpander) expandTarget(target *pg.Node, relations []starRelation) ([]*pg.Node, error) {
+	resTarget := target.GetResTarget()
+	colRef := resTarget.GetVal().GetColumnRef()
+	if colRef == nil || !isFunctionStarArg(&pg.Node_ColumnRef{ColumnRef: colRef}) {
+		return []*pg.Node{target}, nil
+	}
+
+	fields := colRef.GetFields()
+	qualifier := make([]string, 0, len(fields)-1)
+	for _, field := range fields[:len(fields)-1] {
+		qualifier = append(qualifier, field.GetString_().GetSval())
+	}
+	name := strings.Join(qualifier, ".")
+
+	var res []*pg.Node
+	matched := false
+	for _, rel := range relations {
+		if name != "" && !slices.Contains(rel.names, name) {
+			continue
+		}
+		matched = true
+
+		if rel.table == "" {
+			res = append(res, pg.MakeResTargetNodeWithVal(
+				pg.MakeColumnRefNode(append(makeStringNodes(rel.qualifier), pg.MakeAStarNode()), colRef.GetLocation()),
+				resTarget.GetLocation(),
+			))
+
+			continue
+		}
+
+		cols, err := e.columns(rel.table)
+		if err != nil {
+			return nil, fmt.Errorf("expand star for %q: %w", rel.table, err)
+		}
+
+		for _, col := range cols {
+			res = append(res, pg.MakeResTargetNodeWithVal(
+				pg.MakeColumnRefNode(makeStringNodes(append(slices.Clone(rel.qualifier), col)), colRef.GetLocation()),
+				resTarget.GetLocation(),
+			))
+		}
+	}
+
+	if !matched {
+		return nil, fmt.Errorf("star expression for unknown relation (%s): %w", name, ErrNotImplemented)
+	}
+
+	e.changed = true
+
+	return res, nil
+}
+
+func collectStarRelations(relations []starRelation, ctes cteNames, node *pg.Node) []starRelation {
+	switch node := node.GetNode().(type) {
+	case *pg.Node_RangeVar:
+		rel := node.RangeVar
+		alias := rel.GetAlias().GetAliasname()
+
+		var qualifier []string
+		for _, part := range []string{rel.GetCatalogname(), rel.GetSchemaname(), rel.GetRelname()} {
+			if part != "" {
+				qualifier = append(qualifier, part)
+			}
+		}
+
+		table := strings.Join(qualifier, ".")
+		names := make([]string, 0, len(qualifier))
+		for i := range qualifier {
+			names = append(names, strings.Join(qualifier[i:], "."))
+		}
+
+		if alias != "" {
+			qualifier = []string{alias}
+			names = []string{alias}
+		}
+
+		if ctes.has(rel) {
+			table = ""
+		}
+
+		return append(relations, starRelation{table: table, qualifier: qualifier, names: names})
+	case *pg.Node_RangeSubselect:
+		alias := node.RangeSubselect.GetAlias().GetAliasname()
+
+		return append(relations, starRelation{table: "", qualifier: []string{alias}, names: []string{alias}})
+	case *pg.Node_JoinExpr:
+		relations = collectStarRelations(relations, ctes, node.JoinExpr.GetLarg())
+
+		return collectStarRelations(relations, ctes, node.JoinExpr.GetRarg())
+	default:
+		return relations
+	}
+}
+
+// walkNestedSelects calls fn for every select of sublinks and derived tables of the message. CTE bodies and branches
+// of set operations are not visited because they have their own scope.
+func walkNestedSelects(msg protoreflect.Message, fn func(*pg.SelectStmt) error) error {
+	var walkErr error
+	msg.Range(func(field protoreflect.FieldDescriptor, value protoreflect.Value) bool {
+		switch field.Name() {
+		case "with_clause", "larg", "rarg":
+			return true
+		}
+
+		if field.Kind() != protoreflect.MessageKind {
+			return true
+		}
+
+		if field.IsList() {
+			list := value.List()
+			for i := range list.Len() {
+				if walkErr = walkNestedSelect(list.Get(i).Message(), fn); walkErr != nil {
+					return false
+				}
+			}
+
+			return true
+		}
+
+		walkErr = walkNestedSelect(value.Message(), fn)
+
+		return walkErr == nil
+	})
+
+	return walkErr
+}
+
+func walkNestedSelect(msg protoreflect.Message, fn func(*pg.SelectStmt) error) error {
+	switch node := msg.Interface().(type) {
+	case *pg.SubLink:
+		return fn(node.GetSubselect().GetSelectStmt())
+	case *pg.RangeSubselect:
+		return fn(node.GetSubquery().GetSelectStmt())
+	default:
+		return walkNestedSelects(msg, fn)
+	}
+}
+
+func makeStringNodes(values []string) []*pg.Node {
+	nodes := make([]*pg.Node, len(values))
+	for i, value := range values {
+		nodes[i] = pg.MakeStrNode(value)
+	}
+
+	return nodes
+}
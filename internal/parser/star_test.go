@@ -0,0 +1,143 @@
+// Database Gateway provides access to servers with ACL for safe and restricted database interactions.
+// Copyright (C) 2024  Kirill Zhuravlev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package parser_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/kazhuravlev/database-gateway/internal/parser"
+	"github.com/stretchr/testify/require"
+)
+
+var errUnknownTable = errors.New("unknown table")
+
+func testStarColumns(table string) ([]string, error) {
+	switch table {
+	case "clients", "public.clients":
+		return []string{"id", "name"}, nil
+	case "orders", "public.orders":
+		return []string{"id", "client_id"}, nil
+	default:
+		return nil, errUnknownTable
+	}
+}
+
+func TestExpandStar(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name  string
+		query string
+		exp   string
+	}{
+		{
+			name:  "no_star",
+			query: `select id from clients`,
+			exp:   `select id from clients`,
+		},
+		{
+			name:  "count_star_is_not_expanded",
+			query: `select count(*) from clients`,
+			exp:   `select count(*) from clients`,
+		},
+		{
+			name:  "star",
+			query: `select * from clients`, //nolint:unqueryvet
+			exp:   `SELECT clients.id, clients.name FROM clients`,
+		},
+		{
+			name:  "schema_qualified_star",
+			query: `select * from public.clients`, //nolint:unqueryvet
+			exp:   `SELECT public.clients.id, public.clients.name FROM public.clients`,
+		},
+		{
+			name:  "alias_star",
+			query: `select c.*, 1 from clients c`,
+			exp:   `SELECT c.id, c.name, 1 FROM clients c`,
+		},
+		{
+			name:  "table_star_without_alias",
+			query: `select clients.* from public.clients`,
+			exp:   `SELECT public.clients.id, public.clients.name FROM public.clients`,
+		},
+		{
+			name:  "star_over_join",
+			query: `select * from clients c join orders o on o.client_id = c.id`, //nolint:unqueryvet
+			exp:   `SELECT c.id, c.name, o.id, o.client_id FROM clients c JOIN orders o ON o.client_id = c.id`,
+		},
+		{
+			name:  "star_over_derived_table",
+			query: `select * from (select * from orders) sub`, //nolint:unqueryvet
+			exp:   `SELECT sub.* FROM (SELECT orders.id, orders.client_id FROM orders) sub`,
+		},
+		{
+			name:  "star_over_cte",
+			query: `with o as (select * from orders) select o.* from o`, //nolint:unqueryvet
+			exp:   `WITH o AS (SELECT orders.id, orders.client_id FROM orders) SELECT o.* FROM o`,
+		},
+		{
+			name:  "star_in_union",
+			query: `select * from clients union select * from orders`, //nolint:unqueryvet
+			exp:   `SELECT clients.id, clients.name FROM clients UNION SELECT orders.id, orders.client_id FROM orders`,
+		},
+		{
+			name:  "star_in_sublink",
+			query: `select id from clients where exists (select * from orders where orders.client_id = clients.id)`,
+			exp:   `SELECT id FROM clients WHERE EXISTS (SELECT orders.id, orders.client_id FROM orders WHERE orders.client_id = clients.id)`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			res, err := parser.ExpandStar(tc.query, testStarColumns)
+			require.NoError(t, err)
+			require.Equal(t, tc.exp, res)
+
+			// Expanded query must be parseable.
+			_, err = parser.Parse(res)
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestExpandStarInvalid(t *testing.T) {
+	t.Parallel()
+
+	t.Run("unknown_table", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := parser.ExpandStar(`select * from secrets`, testStarColumns) //nolint:unqueryvet
+		require.ErrorIs(t, err, errUnknownTable)
+	})
+
+	t.Run("unknown_qualifier", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := parser.ExpandStar(`select x.* from clients c`, testStarColumns)
+		require.ErrorIs(t, err, parser.ErrNotImplemented)
+	})
+
+	t.Run("bad_sql", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := parser.ExpandStar(`select * fro clients`, testStarColumns)
+		require.Error(t, err)
+	})
+}
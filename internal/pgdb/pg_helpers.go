@@ -23,10 +23,18 @@ import (
 	"time"
 
 	"github.com/kazhuravlev/database-gateway/internal/config"
-	"github.com/kazhuravlev/just"
+	"github.com/lib/pq"
 )
 
-func BuildDBDsn(cfg config.PostgresConfig) string { //nolint:gocritic
+// storageTLSConfigName is the name of tls config of the storage registered in lib/pq.
+const storageTLSConfigName = "dbgw-storage"
+
+func BuildDBDsn(cfg config.PostgresConfig) (string, error) { //nolint:gocritic
+	sslMode, err := storageSSLMode(cfg)
+	if err != nil {
+		return "", err
+	}
+
 	return fmt.Sprintf(
 		"host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
 		cfg.Host,
@@ -34,12 +42,35 @@ func BuildDBDsn(cfg config.PostgresConfig) string { //nolint:gocritic
 		cfg.Username,
 		cfg.Password,
 		cfg.Database,
-		just.If(cfg.UseSSL, "prefer", "disable"),
-	)
+		sslMode,
+	), nil
+}
+
+// storageSSLMode registers tls config of the storage in lib/pq and returns sslmode that refers to it. lib/pq cannot
+// override the server name or verify ca without the host name, so the config is always built by BuildTLSConfig.
+func storageSSLMode(cfg config.PostgresConfig) (string, error) { //nolint:gocritic
+	tlsCfg, err := BuildTLSConfig(cfg.TLS, cfg.Host)
+	if err != nil {
+		return "", fmt.Errorf("build storage tls config: %w", err)
+	}
+
+	if tlsCfg == nil {
+		return string(config.TLSModeDisable), nil
+	}
+
+	if err := pq.RegisterTLSConfig(storageTLSConfigName, tlsCfg); err != nil {
+		return "", fmt.Errorf("register storage tls config: %w", err)
+	}
+
+	return "pqgo-" + storageTLSConfigName, nil
 }
 
 func ConnectToPg(cfg config.PostgresConfig) (*sql.DB, error) { //nolint:gocritic
-	postgresDSN := BuildDBDsn(cfg)
+	postgresDSN, err := BuildDBDsn(cfg)
+	if err != nil {
+		return nil, err
+	}
+
 	dbConn, err := sql.Open("postgres", postgresDSN)
 	if err != nil {
 		return nil, fmt.Errorf("connect to postgres: %w", err)
@@ -0,0 +1,120 @@
+// Database Gateway provides access to servers with ACL for safe and restricted database interactions.
+// Copyright (C) 2024  Kirill Zhuravlev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package pgdb
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/kazhuravlev/database-gateway/internal/config"
+)
+
+// BuildTLSConfig converts tls settings into tls config of a client. It returns nil when tls is disabled. Files are
+// read right away, so broken certificates are reported at startup.
+func BuildTLSConfig(cfg config.TLSConfig, host string) (*tls.Config, error) { //nolint:gocritic
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("validate tls config: %w", err)
+	}
+
+	mode := cfg.EffectiveMode()
+	if mode == config.TLSModeDisable {
+		return nil, nil //nolint:nilnil
+	}
+
+	// NOTE: crypto/tls matches ip addresses against ip SANs and does not send them as SNI.
+	serverName := cfg.ServerName
+	if serverName == "" {
+		serverName = host
+	}
+
+	tlsCfg := &tls.Config{ //nolint:exhaustruct
+		MinVersion: tls.VersionTLS12,
+		ServerName: serverName,
+	}
+
+	if cfg.ClientCert != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.ClientCert, cfg.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate: %w", err)
+		}
+
+		tlsCfg.Certificates = []tls.Certificate{cert}
+	}
+
+	if cfg.RootCert != "" {
+		buf, err := os.ReadFile(cfg.RootCert)
+		if err != nil {
+			return nil, fmt.Errorf("read root certificate: %w", err)
+		}
+
+		rootCAs := x509.NewCertPool()
+		if !rootCAs.AppendCertsFromPEM(buf) {
+			return nil, errors.New("root certificate does not contain pem certificates") //nolint:err113
+		}
+
+		tlsCfg.RootCAs = rootCAs
+	}
+
+	switch mode {
+	case config.TLSModeRequire:
+		tlsCfg.InsecureSkipVerify = true //nolint:gosec // require encrypts without verification, like libpq does
+	case config.TLSModeVerifyCA:
+		// NOTE: crypto/tls always checks the host name, so the chain is verified by hand.
+		tlsCfg.InsecureSkipVerify = true //nolint:gosec
+		tlsCfg.VerifyPeerCertificate = verifyChain(tlsCfg.RootCAs)
+	case config.TLSModeDisable, config.TLSModeVerifyFull:
+	}
+
+	return tlsCfg, nil
+}
+
+// verifyChain checks that the server certificate is signed by roots without checking the host name.
+func verifyChain(roots *x509.CertPool) func([][]byte, [][]*x509.Certificate) error {
+	return func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		if len(rawCerts) == 0 {
+			return errors.New("server did not present a certificate") //nolint:err113
+		}
+
+		certs := make([]*x509.Certificate, len(rawCerts))
+		for i, raw := range rawCerts {
+			cert, err := x509.ParseCertificate(raw)
+			if err != nil {
+				return fmt.Errorf("parse server certificate: %w", err)
+			}
+
+			certs[i] = cert
+		}
+
+		intermediates := x509.NewCertPool()
+		for _, cert := range certs[1:] {
+			intermediates.AddCert(cert)
+		}
+
+		_, err := certs[0].Verify(x509.VerifyOptions{ //nolint:exhaustruct
+			Roots:         roots,
+			Intermediates: intermediates,
+		})
+		if err != nil {
+			return fmt.Errorf("verify server certificate: %w", err)
+		}
+
+		return nil
+	}
+}
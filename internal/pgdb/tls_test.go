@@ -0,0 +1,176 @@
+// Database Gateway provides access to servers with ACL for safe and restricted database interactions.
+// Copyright (C) 2024  Kirill Zhuravlev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package pgdb //nolint:testpackage
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kazhuravlev/database-gateway/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+type testCert struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+	keyPEM  []byte
+}
+
+func newTestCert(t *testing.T, commonName string, parent *testCert) *testCert {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{ //nolint:exhaustruct
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: commonName}, //nolint:exhaustruct
+		DNSNames:              []string{commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  parent == nil,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+
+	parentCert, parentKey := tmpl, key
+	if parent != nil {
+		parentCert, parentKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parentCert, &key.PublicKey, parentKey)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	return &testCert{
+		cert:    cert,
+		key:     key,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),       //nolint:exhaustruct
+		keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), //nolint:exhaustruct
+	}
+}
+
+func writeFile(t *testing.T, name string, buf []byte) string {
+	t.Helper()
+
+	filename := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(filename, buf, 0o600))
+
+	return filename
+}
+
+func TestBuildTLSConfig(t *testing.T) {
+	t.Parallel()
+
+	ca := newTestCert(t, "dbgw test ca", nil)
+	server := newTestCert(t, "pg.internal", ca)
+	client := newTestCert(t, "dbgw", ca)
+	otherCA := newTestCert(t, "other ca", nil)
+
+	rootCert := writeFile(t, "root.crt", ca.certPEM)
+
+	t.Run("disable", func(t *testing.T) {
+		t.Parallel()
+
+		tlsCfg, err := BuildTLSConfig(config.TLSConfig{}, "pg.internal") //nolint:exhaustruct
+		require.NoError(t, err)
+		require.Nil(t, tlsCfg)
+	})
+
+	t.Run("require skips verification", func(t *testing.T) {
+		t.Parallel()
+
+		tlsCfg, err := BuildTLSConfig(config.TLSConfig{Mode: config.TLSModeRequire}, "pg.internal") //nolint:exhaustruct
+		require.NoError(t, err)
+		require.True(t, tlsCfg.InsecureSkipVerify)
+		require.Equal(t, "pg.internal", tlsCfg.ServerName)
+	})
+
+	t.Run("verify-full with server name and client cert", func(t *testing.T) {
+		t.Parallel()
+
+		tlsCfg, err := BuildTLSConfig(config.TLSConfig{
+			Mode:       config.TLSModeVerifyFull,
+			RootCert:   rootCert,
+			ClientCert: writeFile(t, "client.crt", client.certPEM),
+			ClientKey:  writeFile(t, "client.key", client.keyPEM),
+			ServerName: "pg.internal",
+		}, "10.0.0.5")
+		require.NoError(t, err)
+		require.False(t, tlsCfg.InsecureSkipVerify)
+		require.Equal(t, "pg.internal", tlsCfg.ServerName)
+		require.Len(t, tlsCfg.Certificates, 1)
+
+		_, err = server.cert.Verify(x509.VerifyOptions{Roots: tlsCfg.RootCAs, DNSName: tlsCfg.ServerName}) //nolint:exhaustruct
+		require.NoError(t, err)
+	})
+
+	t.Run("verify-ca checks the chain only", func(t *testing.T) {
+		t.Parallel()
+
+		tlsCfg, err := BuildTLSConfig(config.TLSConfig{ //nolint:exhaustruct
+			Mode:     config.TLSModeVerifyCA,
+			RootCert: rootCert,
+		}, "10.0.0.5")
+		require.NoError(t, err)
+		require.True(t, tlsCfg.InsecureSkipVerify)
+		require.NoError(t, tlsCfg.VerifyPeerCertificate([][]byte{server.cert.Raw}, nil))
+
+		stranger := newTestCert(t, "pg.internal", otherCA)
+		require.Error(t, tlsCfg.VerifyPeerCertificate([][]byte{stranger.cert.Raw}, nil))
+	})
+
+	t.Run("broken files are reported", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := BuildTLSConfig(config.TLSConfig{ //nolint:exhaustruct
+			Mode:     config.TLSModeVerifyFull,
+			RootCert: writeFile(t, "root.crt", []byte("not a certificate")),
+		}, "pg.internal")
+		require.Error(t, err)
+
+		_, err = BuildTLSConfig(config.TLSConfig{ //nolint:exhaustruct
+			Mode:       config.TLSModeVerifyFull,
+			ClientCert: writeFile(t, "client.crt", client.certPEM),
+			ClientKey:  writeFile(t, "client.key", otherCA.keyPEM),
+		}, "pg.internal")
+		require.Error(t, err)
+	})
+
+	t.Run("unsupported mode", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := BuildTLSConfig(config.TLSConfig{Mode: "prefer"}, "pg.internal") //nolint:exhaustruct
+		require.Error(t, err)
+	})
+}
@@ -19,5 +19,23 @@ package policy
 // Authorizer decides whether a subject can see a target or access a query vector.
 type Authorizer interface {
 	AllowTarget(subjects []string, target string) bool
-	AllowQuery(subjects []string, target, op, table string) bool
+	AllowQuery(subjects []string, query Query) bool
+	// QueryDenyReasons explains why the query vector is denied. Returns nil when policy has no explanation.
+	QueryDenyReasons(subjects []string, query Query) []string
+}
+
+// Query is a single query vector that should be authorized.
+type Query struct {
+	Target string
+	Op     string
+	// Table is a canonical `schema.table` name.
+	Table string
+	// Columns contains all columns of the table used in the query.
+	Columns []string
+	// Filter contains columns used by WHERE and JOIN conditions.
+	Filter []string
+	// Group contains columns used by GROUP BY.
+	Group []string
+	// Sort contains columns used by ORDER BY.
+	Sort []string
 }
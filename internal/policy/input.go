@@ -0,0 +1,57 @@
+// Database Gateway provides access to servers with ACL for safe and restricted database interactions.
+// Copyright (C) 2024  Kirill Zhuravlev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package policy
+
+// Input is a document that policies receive as `input`.
+type Input struct {
+	Subjects []string `json:"subjects"`
+	Target   string   `json:"target"`
+	Op       string   `json:"op,omitempty"`
+	Table    string   `json:"table,omitempty"`
+	Columns  []string `json:"columns,omitempty"`
+	Filter   []string `json:"filter,omitempty"`
+	Group    []string `json:"group,omitempty"`
+	Sort     []string `json:"sort,omitempty"`
+}
+
+// NewTargetInput builds an input for `allow_target` rule.
+func NewTargetInput(subjects []string, target string) Input {
+	return Input{
+		Subjects: subjects,
+		Target:   target,
+		Op:       "",
+		Table:    "",
+		Columns:  nil,
+		Filter:   nil,
+		Group:    nil,
+		Sort:     nil,
+	}
+}
+
+// NewQueryInput builds an input for `allow_query` and `deny_reasons` rules.
+func NewQueryInput(subjects []string, query Query) Input {
+	return Input{
+		Subjects: subjects,
+		Target:   query.Target,
+		Op:       query.Op,
+		Table:    query.Table,
+		Columns:  query.Columns,
+		Filter:   query.Filter,
+		Group:    query.Group,
+		Sort:     query.Sort,
+	}
+}
@@ -23,6 +23,7 @@ import (
 	"path/filepath"
 	"slices"
 	"strings"
+	"sync/atomic"
 
 	"github.com/kazhuravlev/database-gateway/internal/policy"
 	oparego "github.com/open-policy-agent/opa/v1/rego"
@@ -31,55 +32,57 @@ import (
 const (
 	queryAllowTarget = "x = data.gateway.allow_target"
 	queryAllowQuery  = "x = data.gateway.allow_query"
+	queryDenyReasons = "x = data.gateway.deny_reasons"
 )
 
 type Authorizer struct {
-	targetQuery oparego.PreparedEvalQuery
-	queryQuery  oparego.PreparedEvalQuery
+	queries atomic.Pointer[preparedQueries]
+}
+
+type preparedQueries struct {
+	target      oparego.PreparedEvalQuery
+	query       oparego.PreparedEvalQuery
+	denyReasons oparego.PreparedEvalQuery
 }
 
 var _ policy.Authorizer = (*Authorizer)(nil)
 
 func New(ctx context.Context, modules map[string]string) (*Authorizer, error) {
-	targetQuery, err := prepareQuery(ctx, modules, queryAllowTarget)
+	queries, err := prepareQueries(ctx, modules)
 	if err != nil {
-		return nil, fmt.Errorf("prepare target query: %w", err)
+		return nil, err
 	}
 
-	queryQuery, err := prepareQuery(ctx, modules, queryAllowQuery)
+	authorizer := new(Authorizer)
+	authorizer.queries.Store(queries)
+
+	return authorizer, nil
+}
+
+// Reload compiles modules and atomically replaces the current policy. On error the current policy is kept.
+func (a *Authorizer) Reload(ctx context.Context, modules map[string]string) error {
+	queries, err := prepareQueries(ctx, modules)
 	if err != nil {
-		return nil, fmt.Errorf("prepare vector query: %w", err)
+		return err
 	}
 
-	return &Authorizer{
-		targetQuery: targetQuery,
-		queryQuery:  queryQuery,
-	}, nil
+	a.queries.Store(queries)
+
+	return nil
 }
 
 func (a *Authorizer) AllowTarget(subjects []string, target string) bool {
-	return evalBool(a.targetQuery, policyInput{
-		Subjects: subjects,
-		Target:   target,
-		Op:       "",
-		Table:    "",
-	})
+	return evalBool(a.queries.Load().target, policy.NewTargetInput(subjects, target))
 }
 
-func (a *Authorizer) AllowQuery(subjects []string, target, op, table string) bool {
-	return evalBool(a.queryQuery, policyInput{
-		Subjects: subjects,
-		Target:   target,
-		Op:       op,
-		Table:    table,
-	})
+func (a *Authorizer) AllowQuery(subjects []string, query policy.Query) bool {
+	return evalBool(a.queries.Load().query, policy.NewQueryInput(subjects, query))
 }
 
-type policyInput struct {
-	Subjects []string `json:"subjects"`
-	Target   string   `json:"target"`
-	Op       string   `json:"op,omitempty"`
-	Table    string   `json:"table,omitempty"`
+// QueryDenyReasons evaluates optional `data.gateway.deny_reasons` rule. The rule can be a string, a list or a set of
+// strings.
+func (a *Authorizer) QueryDenyReasons(subjects []string, query policy.Query) []string {
+	return evalStrings(a.queries.Load().denyReasons, policy.NewQueryInput(subjects, query))
 }
 
 func LoadModules(dir string) (map[string]string, error) {
@@ -118,6 +121,34 @@ func SubjectRole(role string) string {
 	return "role:" + strings.TrimSpace(role)
 }
 
+// SubjectBreakGlass marks a user that holds an active break-glass grant for the target.
+func SubjectBreakGlass(targetID string) string {
+	return "breakglass:" + strings.TrimSpace(targetID)
+}
+
+func prepareQueries(ctx context.Context, modules map[string]string) (*preparedQueries, error) {
+	targetQuery, err := prepareQuery(ctx, modules, queryAllowTarget)
+	if err != nil {
+		return nil, fmt.Errorf("prepare target query: %w", err)
+	}
+
+	queryQuery, err := prepareQuery(ctx, modules, queryAllowQuery)
+	if err != nil {
+		return nil, fmt.Errorf("prepare vector query: %w", err)
+	}
+
+	denyReasonsQuery, err := prepareQuery(ctx, modules, queryDenyReasons)
+	if err != nil {
+		return nil, fmt.Errorf("prepare deny reasons query: %w", err)
+	}
+
+	return &preparedQueries{
+		target:      targetQuery,
+		query:       queryQuery,
+		denyReasons: denyReasonsQuery,
+	}, nil
+}
+
 func prepareQuery(
 	ctx context.Context,
 	modules map[string]string,
@@ -138,7 +169,7 @@ func prepareQuery(
 	return oparego.New(opts...).PrepareForEval(ctx)
 }
 
-func evalBool(query oparego.PreparedEvalQuery, input policyInput) bool {
+func evalBool(query oparego.PreparedEvalQuery, input policy.Input) bool {
 	results, err := query.Eval(context.Background(), oparego.EvalInput(input))
 	if err != nil || len(results) == 0 {
 		return false
@@ -151,3 +182,24 @@ func evalBool(query oparego.PreparedEvalQuery, input policyInput) bool {
 
 	return value
 }
+
+func evalStrings(query oparego.PreparedEvalQuery, input policy.Input) []string {
+	results, err := query.Eval(context.Background(), oparego.EvalInput(input))
+	if err != nil || len(results) == 0 {
+		return nil
+	}
+
+	var res []string
+	switch value := results[0].Bindings["x"].(type) {
+	case string:
+		res = append(res, value)
+	case []any:
+		for _, item := range value {
+			if str, ok := item.(string); ok {
+				res = append(res, str)
+			}
+		}
+	}
+
+	return res
+}
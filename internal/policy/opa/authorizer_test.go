@@ -18,8 +18,10 @@ package opa_test
 
 import (
 	"context"
+	"slices"
 	"testing"
 
+	"github.com/kazhuravlev/database-gateway/internal/policy"
 	"github.com/kazhuravlev/database-gateway/internal/policy/opa"
 	"github.com/stretchr/testify/require"
 )
@@ -67,24 +69,220 @@ func TestAuthorizerAllowQuery(t *testing.T) {
 
 	require.True(t, authz.AllowQuery(
 		[]string{"user:alice@example.com", "role:user"},
-		"local-1",
-		"select",
-		"public.clients",
+		policy.Query{
+			Target:  "local-1",
+			Op:      "select",
+			Table:   "public.clients",
+			Columns: []string{"id"},
+			Filter:  nil,
+			Group:   nil,
+			Sort:    nil,
+		},
 	))
 	require.False(t, authz.AllowQuery(
 		[]string{"user:alice@example.com", "role:user"},
-		"local-1",
-		"update",
-		"public.clients",
+		policy.Query{
+			Target:  "local-1",
+			Op:      "update",
+			Table:   "public.clients",
+			Columns: []string{"id"},
+			Filter:  nil,
+			Group:   nil,
+			Sort:    nil,
+		},
 	))
 	require.False(t, authz.AllowQuery(
 		[]string{"user:alice@example.com", "role:user"},
-		"local-1",
-		"select",
-		"public.orders",
+		policy.Query{
+			Target:  "local-1",
+			Op:      "select",
+			Table:   "public.orders",
+			Columns: []string{"id"},
+			Filter:  nil,
+			Group:   nil,
+			Sort:    nil,
+		},
 	))
 }
 
+// ExamplePolicyClauses restricts the query by columns of WHERE and ORDER BY clauses.
+const ExamplePolicyClauses = `
+package gateway
+
+default allow_target := false
+default allow_query := false
+
+allow_query if {
+	"role:support" in input.subjects
+	input.op == "select"
+	input.table == "public.clients"
+	"id" in input.filter
+	not "phone" in object.get(input, "sort", [])
+}
+`
+
+func TestAuthorizerAllowQueryClauses(t *testing.T) {
+	t.Parallel()
+
+	authz, err := opa.New(context.Background(), map[string]string{
+		"example.rego": ExamplePolicyClauses,
+	})
+	require.NoError(t, err)
+
+	subjects := []string{"user:bob@example.com", "role:support"}
+	query := func(filter, sort []string) policy.Query {
+		return policy.Query{
+			Target:  "local-1",
+			Op:      "select",
+			Table:   "public.clients",
+			Columns: slices.Concat(filter, sort),
+			Filter:  filter,
+			Group:   nil,
+			Sort:    sort,
+		}
+	}
+
+	require.True(t, authz.AllowQuery(subjects, query([]string{"id"}, nil)))
+	require.True(t, authz.AllowQuery(subjects, query([]string{"id", "name"}, []string{"name"})))
+	require.False(t, authz.AllowQuery(subjects, query(nil, nil)))
+	require.False(t, authz.AllowQuery(subjects, query([]string{"name"}, nil)))
+	require.False(t, authz.AllowQuery(subjects, query([]string{"id"}, []string{"phone"})))
+}
+
+// ExamplePolicyColumns allows developers to read every column of clients except personal data.
+const ExamplePolicyColumns = `
+package gateway
+
+default allow_target := false
+default allow_query := false
+
+pii_columns := {"email", "phone"}
+
+allow_query if {
+	"role:developer" in input.subjects
+	input.op == "select"
+	input.table == "public.clients"
+	count({column | some column in input.columns; column in pii_columns}) == 0
+}
+`
+
+func TestAuthorizerAllowQueryColumns(t *testing.T) {
+	t.Parallel()
+
+	authz, err := opa.New(context.Background(), map[string]string{
+		"example.rego": ExamplePolicyColumns,
+	})
+	require.NoError(t, err)
+
+	subjects := []string{"user:carol@example.com", "role:developer"}
+	query := func(columns ...string) policy.Query {
+		return policy.Query{
+			Target:  "local-1",
+			Op:      "select",
+			Table:   "public.clients",
+			Columns: columns,
+			Filter:  nil,
+			Group:   nil,
+			Sort:    nil,
+		}
+	}
+
+	require.True(t, authz.AllowQuery(subjects, query("id", "name")))
+	require.True(t, authz.AllowQuery(subjects, query()))
+	require.False(t, authz.AllowQuery(subjects, query("id", "email")))
+	require.False(t, authz.AllowQuery(subjects, query("phone")))
+}
+
+// ExamplePolicyDenyReasons explains why the query is denied.
+const ExamplePolicyDenyReasons = `
+package gateway
+
+default allow_target := false
+default allow_query := false
+
+allow_query if {
+	input.op == "select"
+	not "phone" in input.columns
+}
+
+deny_reasons contains "only select is allowed" if {
+	input.op != "select"
+}
+
+deny_reasons contains msg if {
+	"phone" in input.columns
+	msg := sprintf("column phone of %s is restricted", [input.table])
+}
+`
+
+func TestAuthorizerQueryDenyReasons(t *testing.T) {
+	t.Parallel()
+
+	query := func(op string, columns ...string) policy.Query {
+		return policy.Query{
+			Target:  "local-1",
+			Op:      op,
+			Table:   "public.clients",
+			Columns: columns,
+			Filter:  nil,
+			Group:   nil,
+			Sort:    nil,
+		}
+	}
+
+	t.Run("reasons", func(t *testing.T) {
+		t.Parallel()
+
+		authz, err := opa.New(context.Background(), map[string]string{
+			"example.rego": ExamplePolicyDenyReasons,
+		})
+		require.NoError(t, err)
+
+		subjects := []string{"user:alice@example.com", "role:user"}
+		require.Empty(t, authz.QueryDenyReasons(subjects, query("select", "id")))
+		require.Equal(t,
+			[]string{"only select is allowed"},
+			authz.QueryDenyReasons(subjects, query("delete", "id")))
+		require.Equal(t,
+			[]string{"column phone of public.clients is restricted", "only select is allowed"},
+			authz.QueryDenyReasons(subjects, query("update", "phone")))
+	})
+
+	t.Run("rule_is_optional", func(t *testing.T) {
+		t.Parallel()
+
+		authz, err := opa.New(context.Background(), map[string]string{
+			"example.rego": ExamplePolicySimple,
+		})
+		require.NoError(t, err)
+
+		require.Nil(t, authz.QueryDenyReasons([]string{"role:user"}, query("update", "id")))
+	})
+}
+
+func TestAuthorizerReload(t *testing.T) {
+	t.Parallel()
+
+	authz, err := opa.New(context.Background(), map[string]string{
+		"example.rego": ExamplePolicySimple,
+	})
+	require.NoError(t, err)
+
+	subjects := []string{"user:alice@example.com", "role:user"}
+
+	err = authz.Reload(context.Background(), map[string]string{
+		"broken.rego": "package gateway\nallow_target if { this is bad }",
+	})
+	require.Error(t, err)
+	require.True(t, authz.AllowTarget(subjects, "local-1"))
+
+	err = authz.Reload(context.Background(), map[string]string{
+		"deny.rego": "package gateway\ndefault allow_target := false\ndefault allow_query := false",
+	})
+	require.NoError(t, err)
+	require.False(t, authz.AllowTarget(subjects, "local-1"))
+}
+
 func TestNewFailsForInvalidModule(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,108 @@
+// Database Gateway provides access to servers with ACL for safe and restricted database interactions.
+// Copyright (C) 2024  Kirill Zhuravlev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package opa
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"os/signal"
+	"slices"
+	"syscall"
+	"time"
+)
+
+// Watcher reloads policy modules of the authorizer when files in the policy directory are changed or the process
+// receives SIGHUP. When new modules can not be loaded or compiled the authorizer keeps the previous policy.
+type Watcher struct {
+	opts WatcherOptions
+	// checksum of the modules that were loaded last time.
+	checksum string
+}
+
+// NewWatcher returns a watcher that detects changes against the modules the authorizer was built from, so a file
+// changed after the authorizer was built is loaded on the first check.
+func NewWatcher(opts WatcherOptions) (*Watcher, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, fmt.Errorf("bad configuration: %w", err)
+	}
+
+	return &Watcher{
+		opts:     opts,
+		checksum: modulesChecksum(opts.modules),
+	}, nil
+}
+
+// Run blocks until ctx is done.
+func (w *Watcher) Run(ctx context.Context) error {
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+	defer signal.Stop(sighup)
+
+	ticker := time.NewTicker(w.opts.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-sighup:
+			w.reload(ctx, true)
+		case <-ticker.C:
+			w.reload(ctx, false)
+		}
+	}
+}
+
+func (w *Watcher) reload(ctx context.Context, force bool) {
+	logger := w.opts.logger.With(slog.String("dir", w.opts.dir))
+
+	modules, err := LoadModules(w.opts.dir)
+	if err != nil {
+		logger.Error("unable to load policy modules, keep previous policy", slog.String("error", err.Error()))
+
+		return
+	}
+
+	checksum := modulesChecksum(modules)
+	if !force && checksum == w.checksum {
+		return
+	}
+	// NOTE: remember the checksum even when compilation fails to not report the same error on every tick.
+	w.checksum = checksum
+
+	if err := w.opts.authorizer.Reload(ctx, modules); err != nil {
+		logger.Error("unable to compile policy modules, keep previous policy", slog.String("error", err.Error()))
+
+		return
+	}
+
+	logger.Info("policy reloaded", slog.Int("modules", len(modules)))
+}
+
+func modulesChecksum(modules map[string]string) string {
+	hash := sha256.New()
+	for _, name := range slices.Sorted(maps.Keys(modules)) {
+		_, _ = fmt.Fprintf(hash, "%d:%s%d:%s", len(name), name, len(modules[name]), modules[name])
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
@@ -0,0 +1,34 @@
+// Database Gateway provides access to servers with ACL for safe and restricted database interactions.
+// Copyright (C) 2024  Kirill Zhuravlev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package opa
+
+import (
+	"log/slog"
+	"time"
+)
+
+//go:generate toolset run options-gen -from-struct=WatcherOptions -out-filename=watcher_options_generated.go
+type WatcherOptions struct {
+	logger     *slog.Logger `option:"mandatory" validate:"required"`
+	authorizer *Authorizer  `option:"mandatory" validate:"required"`
+	// dir is a directory with .rego modules.
+	dir string `option:"mandatory" validate:"required"`
+	// modules are the modules the authorizer was built from. Changes are detected against them.
+	modules map[string]string `option:"mandatory" validate:"min=1"`
+	// interval is a period of checking the modules for changes.
+	interval time.Duration `default:"5s" validate:"min=100ms"`
+}
@@ -0,0 +1,130 @@
+// Database Gateway provides access to servers with ACL for safe and restricted database interactions.
+// Copyright (C) 2024  Kirill Zhuravlev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package opa_test
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kazhuravlev/database-gateway/internal/policy/opa"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatcherReloadsChangedModules(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "policy.rego")
+	require.NoError(t, os.WriteFile(filename, []byte(ExamplePolicySimple), 0o600))
+
+	modules, err := opa.LoadModules(dir)
+	require.NoError(t, err)
+
+	authz, err := opa.New(ctx, modules)
+	require.NoError(t, err)
+
+	watcher, err := opa.NewWatcher(opa.NewWatcherOptions(
+		slog.New(slog.DiscardHandler),
+		authz,
+		dir,
+		modules,
+		opa.WithInterval(100*time.Millisecond),
+	))
+	require.NoError(t, err)
+
+	go func() { _ = watcher.Run(ctx) }()
+
+	subjects := []string{"user:alice@example.com", "role:user"}
+	require.True(t, authz.AllowTarget(subjects, "local-1"))
+	require.False(t, authz.AllowTarget(subjects, "local-2"))
+
+	changed := strings.ReplaceAll(ExamplePolicySimple, `"local-1"`, `"local-2"`)
+	require.NoError(t, os.WriteFile(filename, []byte(changed), 0o600))
+
+	require.Eventually(t, func() bool {
+		return authz.AllowTarget(subjects, "local-2")
+	}, 5*time.Second, 50*time.Millisecond)
+	require.False(t, authz.AllowTarget(subjects, "local-1"))
+
+	// Broken module should not replace the working policy.
+	require.NoError(t, os.WriteFile(filename, []byte("package gateway\nallow_target if { this is bad }"), 0o600))
+	time.Sleep(500 * time.Millisecond)
+	require.True(t, authz.AllowTarget(subjects, "local-2"))
+}
+
+func TestWatcherReloadsModulesChangedBeforeStart(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "policy.rego")
+	require.NoError(t, os.WriteFile(filename, []byte(ExamplePolicySimple), 0o600))
+
+	modules, err := opa.LoadModules(dir)
+	require.NoError(t, err)
+
+	authz, err := opa.New(ctx, modules)
+	require.NoError(t, err)
+
+	// The file is changed after the authorizer was built and before the watcher was created.
+	changed := strings.ReplaceAll(ExamplePolicySimple, `"local-1"`, `"local-2"`)
+	require.NoError(t, os.WriteFile(filename, []byte(changed), 0o600))
+
+	watcher, err := opa.NewWatcher(opa.NewWatcherOptions(
+		slog.New(slog.DiscardHandler),
+		authz,
+		dir,
+		modules,
+		opa.WithInterval(100*time.Millisecond),
+	))
+	require.NoError(t, err)
+
+	go func() { _ = watcher.Run(ctx) }()
+
+	subjects := []string{"user:alice@example.com", "role:user"}
+	require.Eventually(t, func() bool {
+		return authz.AllowTarget(subjects, "local-2")
+	}, 5*time.Second, 50*time.Millisecond)
+}
+
+func TestNewWatcherFailsWithoutModules(t *testing.T) {
+	t.Parallel()
+
+	authz, err := opa.New(context.Background(), map[string]string{
+		"example.rego": ExamplePolicySimple,
+	})
+	require.NoError(t, err)
+
+	watcher, err := opa.NewWatcher(opa.NewWatcherOptions(
+		slog.New(slog.DiscardHandler),
+		authz,
+		t.TempDir(),
+		map[string]string{},
+	))
+	require.Error(t, err)
+	require.Nil(t, watcher)
+}
@@ -0,0 +1,162 @@
+// Database Gateway provides access to servers with ACL for safe and restricted database interactions.
+// Copyright (C) 2024  Kirill Zhuravlev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package opahttp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/kazhuravlev/database-gateway/internal/policy"
+)
+
+const (
+	ruleAllowTarget = "allow_target"
+	ruleAllowQuery  = "allow_query"
+	ruleDenyReasons = "deny_reasons"
+)
+
+// Authorizer asks a remote OPA server for decisions through the REST Data API. It sends the same input document as
+// the embedded authorizer. Every failure (network, timeout, bad status, undefined or malformed result) is a denial.
+type Authorizer struct {
+	opts     Options
+	endpoint string
+	client   *http.Client
+}
+
+var _ policy.Authorizer = (*Authorizer)(nil)
+
+func New(opts Options) (*Authorizer, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, fmt.Errorf("bad configuration: %w", err)
+	}
+
+	client := opts.httpClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return &Authorizer{
+		opts:     opts,
+		endpoint: strings.TrimRight(opts.url, "/") + "/v1/data/gateway/",
+		client:   client,
+	}, nil
+}
+
+func (a *Authorizer) AllowTarget(subjects []string, target string) bool {
+	return a.evalBool(ruleAllowTarget, policy.NewTargetInput(subjects, target))
+}
+
+func (a *Authorizer) AllowQuery(subjects []string, query policy.Query) bool {
+	return a.evalBool(ruleAllowQuery, policy.NewQueryInput(subjects, query))
+}
+
+// QueryDenyReasons asks for optional `deny_reasons` rule. The rule can be a string, a list or a set of strings.
+func (a *Authorizer) QueryDenyReasons(subjects []string, query policy.Query) []string {
+	result, err := a.eval(ruleDenyReasons, policy.NewQueryInput(subjects, query))
+	if err != nil {
+		a.opts.logger.Warn("evaluate remote policy", slog.String("rule", ruleDenyReasons), slog.String("error", err.Error()))
+
+		return nil
+	}
+
+	if len(result) == 0 {
+		return nil
+	}
+
+	var reasons []string
+	if err := json.Unmarshal(result, &reasons); err == nil {
+		return reasons
+	}
+
+	var reason string
+	if err := json.Unmarshal(result, &reason); err == nil {
+		return []string{reason}
+	}
+
+	return nil
+}
+
+func (a *Authorizer) evalBool(rule string, input policy.Input) bool {
+	result, err := a.eval(rule, input)
+	if err != nil {
+		a.opts.logger.Warn("evaluate remote policy, deny", slog.String("rule", rule), slog.String("error", err.Error()))
+
+		return false
+	}
+
+	var value bool
+	if err := json.Unmarshal(result, &value); err != nil {
+		return false
+	}
+
+	return value
+}
+
+type dataRequest struct {
+	Input policy.Input `json:"input"`
+}
+
+type dataResponse struct {
+	// Result is absent when the rule is undefined.
+	Result json.RawMessage `json:"result"`
+}
+
+// eval returns raw result of the rule. Result is empty when the rule is undefined.
+func (a *Authorizer) eval(rule string, input policy.Input) (json.RawMessage, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), a.opts.timeout)
+	defer cancel()
+
+	body, err := json.Marshal(dataRequest{Input: input})
+	if err != nil {
+		return nil, fmt.Errorf("marshal input: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.endpoint+rule, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("build request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if a.opts.authHeader != "" {
+		req.Header.Set("Authorization", a.opts.authHeader)
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("do request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		_, _ = io.Copy(io.Discard, resp.Body)
+
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode) //nolint:err113
+	}
+
+	var res dataResponse
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+
+	return res.Result, nil
+}
@@ -0,0 +1,187 @@
+// Database Gateway provides access to servers with ACL for safe and restricted database interactions.
+// Copyright (C) 2024  Kirill Zhuravlev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package opahttp_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kazhuravlev/database-gateway/internal/policy"
+	"github.com/kazhuravlev/database-gateway/internal/policy/opahttp"
+	"github.com/stretchr/testify/require"
+)
+
+type dataRequest struct {
+	Input policy.Input `json:"input"`
+}
+
+// newOPA starts a stand-in of OPA REST Data API. handle returns the response body for the rule.
+func newOPA(t *testing.T, handle func(rule string, input policy.Input) string) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+
+		var req dataRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+
+			return
+		}
+
+		rule, ok := map[string]string{
+			"/v1/data/gateway/allow_target": "allow_target",
+			"/v1/data/gateway/allow_query":  "allow_query",
+			"/v1/data/gateway/deny_reasons": "deny_reasons",
+		}[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+
+			return
+		}
+
+		_, _ = w.Write([]byte(handle(rule, req.Input)))
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func newAuthorizer(t *testing.T, url string, opts ...opahttp.OptOptionsSetter) *opahttp.Authorizer {
+	t.Helper()
+
+	authz, err := opahttp.New(opahttp.NewOptions(
+		slog.New(slog.DiscardHandler),
+		url,
+		append([]opahttp.OptOptionsSetter{opahttp.WithAuthHeader("Bearer secret")}, opts...)...,
+	))
+	require.NoError(t, err)
+
+	return authz
+}
+
+func testQuery(op string) policy.Query {
+	return policy.Query{
+		Target:  "local-1",
+		Op:      op,
+		Table:   "public.clients",
+		Columns: []string{"id"},
+		Filter:  nil,
+		Group:   nil,
+		Sort:    nil,
+	}
+}
+
+func TestAuthorizer(t *testing.T) {
+	t.Parallel()
+
+	srv := newOPA(t, func(rule string, input policy.Input) string {
+		switch rule {
+		case "allow_target":
+			return fmt.Sprintf(`{"result": %t}`, input.Target == "local-1")
+		case "allow_query":
+			return fmt.Sprintf(`{"result": %t}`, input.Op == "select")
+		default:
+			return `{"result": ["only select is allowed"]}`
+		}
+	})
+
+	authz := newAuthorizer(t, srv.URL)
+	subjects := []string{"user:alice@example.com", "role:user"}
+
+	require.True(t, authz.AllowTarget(subjects, "local-1"))
+	require.False(t, authz.AllowTarget(subjects, "local-2"))
+	require.True(t, authz.AllowQuery(subjects, testQuery("select")))
+	require.False(t, authz.AllowQuery(subjects, testQuery("delete")))
+	require.Equal(t, []string{"only select is allowed"}, authz.QueryDenyReasons(subjects, testQuery("delete")))
+}
+
+func TestAuthorizerFailsClosed(t *testing.T) {
+	t.Parallel()
+
+	subjects := []string{"role:admin"}
+
+	t.Run("undefined_result", func(t *testing.T) {
+		t.Parallel()
+
+		srv := newOPA(t, func(string, policy.Input) string { return `{}` })
+		authz := newAuthorizer(t, srv.URL)
+
+		require.False(t, authz.AllowTarget(subjects, "local-1"))
+		require.False(t, authz.AllowQuery(subjects, testQuery("select")))
+		require.Nil(t, authz.QueryDenyReasons(subjects, testQuery("select")))
+	})
+
+	t.Run("not_a_bool", func(t *testing.T) {
+		t.Parallel()
+
+		srv := newOPA(t, func(string, policy.Input) string { return `{"result": "yes"}` })
+		authz := newAuthorizer(t, srv.URL)
+
+		require.False(t, authz.AllowQuery(subjects, testQuery("select")))
+		require.Equal(t, []string{"yes"}, authz.QueryDenyReasons(subjects, testQuery("select")))
+	})
+
+	t.Run("bad_auth_header", func(t *testing.T) {
+		t.Parallel()
+
+		srv := newOPA(t, func(string, policy.Input) string { return `{"result": true}` })
+		authz := newAuthorizer(t, srv.URL, opahttp.WithAuthHeader("Bearer wrong"))
+
+		require.False(t, authz.AllowQuery(subjects, testQuery("select")))
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		t.Parallel()
+
+		srv := newOPA(t, func(string, policy.Input) string {
+			time.Sleep(200 * time.Millisecond)
+
+			return `{"result": true}`
+		})
+		authz := newAuthorizer(t, srv.URL, opahttp.WithTimeout(20*time.Millisecond))
+
+		require.False(t, authz.AllowQuery(subjects, testQuery("select")))
+	})
+
+	t.Run("server_is_down", func(t *testing.T) {
+		t.Parallel()
+
+		srv := newOPA(t, func(string, policy.Input) string { return `{"result": true}` })
+		url := srv.URL
+		srv.Close()
+
+		authz := newAuthorizer(t, url)
+		require.False(t, authz.AllowTarget(subjects, "local-1"))
+	})
+}
+
+func TestNewValidatesOptions(t *testing.T) {
+	t.Parallel()
+
+	_, err := opahttp.New(opahttp.NewOptions(slog.New(slog.DiscardHandler), "not a url"))
+	require.Error(t, err)
+}
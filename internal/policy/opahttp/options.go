@@ -0,0 +1,36 @@
+// Database Gateway provides access to servers with ACL for safe and restricted database interactions.
+// Copyright (C) 2024  Kirill Zhuravlev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package opahttp
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+//go:generate toolset run options-gen -from-struct=Options
+type Options struct {
+	logger *slog.Logger `option:"mandatory" validate:"required"`
+	// url is a base url of OPA server, like http://opa:8181.
+	url string `option:"mandatory" validate:"required,url"`
+	// timeout of one decision request.
+	timeout time.Duration `default:"2s" validate:"min=1ms"`
+	// authHeader is a value of Authorization header. Not sent when empty.
+	authHeader string
+	// httpClient is used to send requests. http.DefaultClient by default.
+	httpClient *http.Client
+}
@@ -0,0 +1,114 @@
+// Database Gateway provides access to servers with ACL for safe and restricted database interactions.
+// Copyright (C) 2024  Kirill Zhuravlev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package policytest
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/kazhuravlev/database-gateway/internal/policy"
+	"sigs.k8s.io/yaml"
+)
+
+var ErrBadFixture = errors.New("bad fixture")
+
+// Fixture is a set of policy test cases. It can be written in YAML or JSON.
+type Fixture struct {
+	Cases []Case `json:"cases"`
+}
+
+// Case describes a single policy decision. When Op is empty the case checks target visibility (allow_target),
+// otherwise it checks access to the query vector (allow_query).
+type Case struct {
+	Name     string   `json:"name"`
+	Subjects []string `json:"subjects"`
+	Target   string   `json:"target"`
+	Op       string   `json:"op,omitempty"`
+	Table    string   `json:"table,omitempty"`
+	Columns  []string `json:"columns,omitempty"`
+	Filter   []string `json:"filter,omitempty"`
+	Group    []string `json:"group,omitempty"`
+	Sort     []string `json:"sort,omitempty"`
+	Allow    bool     `json:"allow"`
+}
+
+// Result is an outcome of a single case.
+type Result struct {
+	Case Case
+	// Got is an actual decision of the policy.
+	Got bool
+}
+
+func (r Result) Passed() bool {
+	return r.Case.Allow == r.Got
+}
+
+// LoadFixture reads and validates fixture file.
+func LoadFixture(filename string) (*Fixture, error) {
+	buf, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("read fixture: %w", err)
+	}
+
+	var fixture Fixture
+	if err := yaml.UnmarshalStrict(buf, &fixture); err != nil {
+		return nil, fmt.Errorf("parse fixture: %w", errors.Join(ErrBadFixture, err))
+	}
+
+	if len(fixture.Cases) == 0 {
+		return nil, fmt.Errorf("fixture has no cases: %w", ErrBadFixture)
+	}
+
+	for i, c := range fixture.Cases {
+		if strings.TrimSpace(c.Name) == "" {
+			return nil, fmt.Errorf("case #%d has no name: %w", i+1, ErrBadFixture)
+		}
+
+		if c.Op != "" && c.Table == "" {
+			return nil, fmt.Errorf("case %q has op but no table: %w", c.Name, ErrBadFixture)
+		}
+	}
+
+	return &fixture, nil
+}
+
+// Run evaluates all cases of fixture against authorizer.
+func Run(authorizer policy.Authorizer, fixture Fixture) []Result {
+	results := make([]Result, len(fixture.Cases))
+	for i, c := range fixture.Cases {
+		var got bool
+		if c.Op == "" {
+			got = authorizer.AllowTarget(c.Subjects, c.Target)
+		} else {
+			got = authorizer.AllowQuery(c.Subjects, policy.Query{
+				Target:  c.Target,
+				Op:      c.Op,
+				Table:   c.Table,
+				Columns: c.Columns,
+				Filter:  c.Filter,
+				Group:   c.Group,
+				Sort:    c.Sort,
+			})
+		}
+
+		results[i] = Result{Case: c, Got: got}
+	}
+
+	return results
+}
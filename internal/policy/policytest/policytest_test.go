@@ -0,0 +1,104 @@
+// Database Gateway provides access to servers with ACL for safe and restricted database interactions.
+// Copyright (C) 2024  Kirill Zhuravlev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package policytest_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kazhuravlev/database-gateway/internal/policy/opa"
+	"github.com/kazhuravlev/database-gateway/internal/policy/policytest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExampleFixturePasses(t *testing.T) {
+	t.Parallel()
+
+	modules, err := opa.LoadModules("../../../example/opa/basic")
+	require.NoError(t, err)
+
+	authz, err := opa.New(context.Background(), modules)
+	require.NoError(t, err)
+
+	fixture, err := policytest.LoadFixture("../../../example/opa/basic.cases.yaml")
+	require.NoError(t, err)
+
+	for _, res := range policytest.Run(authz, *fixture) {
+		require.True(t, res.Passed(), res.Case.Name)
+	}
+}
+
+func TestRunReportsFailures(t *testing.T) {
+	t.Parallel()
+
+	authz, err := opa.New(context.Background(), map[string]string{
+		"policy.rego": `
+package gateway
+
+default allow_target := false
+default allow_query := false
+
+allow_query if input.op == "select"
+`,
+	})
+	require.NoError(t, err)
+
+	filename := filepath.Join(t.TempDir(), "cases.json")
+	require.NoError(t, os.WriteFile(filename, []byte(`{"cases": [
+		{"name": "select", "subjects": ["role:user"], "target": "t", "op": "select", "table": "public.a", "allow": true},
+		{"name": "delete", "subjects": ["role:user"], "target": "t", "op": "delete", "table": "public.a", "allow": true},
+		{"name": "target", "subjects": ["role:user"], "target": "t", "allow": false}
+	]}`), 0o600))
+
+	fixture, err := policytest.LoadFixture(filename)
+	require.NoError(t, err)
+
+	results := policytest.Run(authz, *fixture)
+	require.Len(t, results, 3)
+	require.True(t, results[0].Passed())
+	require.False(t, results[1].Passed())
+	require.True(t, results[2].Passed())
+}
+
+func TestLoadFixtureInvalid(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name    string
+		content string
+	}{
+		{name: "empty", content: ``},
+		{name: "no_cases", content: `cases: []`},
+		{name: "unknown_field", content: "cases:\n  - name: a\n    target: t\n    allowed: true\n"},
+		{name: "case_without_name", content: "cases:\n  - target: t\n    allow: true\n"},
+		{name: "op_without_table", content: "cases:\n  - name: a\n    target: t\n    op: select\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			filename := filepath.Join(t.TempDir(), "cases.yaml")
+			require.NoError(t, os.WriteFile(filename, []byte(tc.content), 0o600))
+
+			_, err := policytest.LoadFixture(filename)
+			require.ErrorIs(t, err, policytest.ErrBadFixture)
+		})
+	}
+}
@@ -15,3 +15,47 @@
 // along with this program.  If not, see <https://www.gnu.org/licenses/>.
 
 package storage
+
+import (
+	"github.com/kazhuravlev/database-gateway/internal/storage/jetgen/model"
+	"github.com/kazhuravlev/database-gateway/internal/structs"
+	"github.com/kazhuravlev/database-gateway/internal/uuid6"
+)
+
+func adaptQueryApproval(obj model.QueryApprovals) QueryApproval { //nolint:gocritic
+	resultID := uuid6.Nil()
+	if obj.QueryResultID != nil {
+		resultID = uuid6.FromUUID(*obj.QueryResultID)
+	}
+
+	return QueryApproval{
+		ID:            obj.ID,
+		UserID:        obj.UserID,
+		UserName:      obj.UserName,
+		UserRole:      obj.UserRole,
+		TargetID:      obj.TargetID,
+		Query:         obj.Query,
+		TargetQuery:   obj.TargetQuery,
+		Vectors:       obj.Vectors,
+		Status:        structs.ApprovalStatus(obj.Status),
+		ReviewerID:    obj.ReviewerID,
+		ReviewComment: obj.ReviewComment,
+		Error:         obj.Error,
+		QueryResultID: resultID,
+		CreatedAt:     obj.CreatedAt,
+		ReviewedAt:    obj.ReviewedAt,
+	}
+}
+
+func adaptBreakGlassGrant(obj model.BreakGlassGrants) BreakGlassGrant { //nolint:gocritic
+	return BreakGlassGrant{
+		ID:        obj.ID,
+		UserID:    obj.UserID,
+		TargetID:  obj.TargetID,
+		Reason:    obj.Reason,
+		CreatedAt: obj.CreatedAt,
+		ExpiresAt: obj.ExpiresAt,
+		RevokedAt: obj.RevokedAt,
+		RevokedBy: obj.RevokedBy,
+	}
+}
@@ -25,7 +25,9 @@ import (
 	"github.com/kazhuravlev/database-gateway/internal/config"
 	"github.com/kazhuravlev/database-gateway/internal/storage/jetgen/model"
 	tbl "github.com/kazhuravlev/database-gateway/internal/storage/jetgen/table"
+	"github.com/kazhuravlev/database-gateway/internal/structs"
 	"github.com/kazhuravlev/database-gateway/internal/uuid6"
+	"github.com/kazhuravlev/just"
 )
 
 type InsertQueryResultsReq struct {
@@ -35,6 +37,8 @@ type InsertQueryResultsReq struct {
 	CreatedAt time.Time
 	Query     string
 	Response  json.RawMessage
+	Status    structs.QueryStatus
+	Error     string
 }
 
 func (*Service) InsertQueryResults(conn qrm.DB, req InsertQueryResultsReq) error { //nolint:gocritic
@@ -45,6 +49,8 @@ func (*Service) InsertQueryResults(conn qrm.DB, req InsertQueryResultsReq) error
 		CreatedAt: req.CreatedAt,
 		Query:     req.Query,
 		Response:  req.Response,
+		Status:    req.Status.S(),
+		Error:     req.Error,
 	}
 	//nolint:unqueryvet // ok while reading into model
 	res, err := tbl.QueryResults.
@@ -73,6 +79,87 @@ func (*Service) GetQueryResultsByID(conn qrm.DB, queryID uuid6.UUID) (*model.Que
 	return &obj, nil
 }
 
+// GetQueryResultsStatus returns the query without the response, so polling does not read large results.
+func (*Service) GetQueryResultsStatus(conn qrm.DB, queryID uuid6.UUID) (*QueryResult, error) {
+	var obj model.QueryResults
+	err := tbl.QueryResults.
+		SELECT(
+			tbl.QueryResults.ID,
+			tbl.QueryResults.UserID,
+			tbl.QueryResults.TargetID,
+			tbl.QueryResults.CreatedAt,
+			tbl.QueryResults.Query,
+			tbl.QueryResults.Status,
+			tbl.QueryResults.Error,
+		).
+		WHERE(tbl.QueryResults.ID.EQ(postgres.UUID(queryID.ToUUID()))).
+		LIMIT(1).
+		Query(conn, &obj)
+	if err := handleError("get query results status", err, nil); err != nil {
+		return nil, err
+	}
+
+	return &QueryResult{
+		ID:        obj.ID,
+		UserID:    obj.UserID,
+		TargetID:  obj.TargetID,
+		CreatedAt: obj.CreatedAt,
+		Query:     obj.Query,
+		Response:  nil,
+		Status:    structs.QueryStatus(obj.Status),
+		Error:     obj.Error,
+	}, nil
+}
+
+// StartQueryResults moves a queued query to running. ErrNotFound means the query is not queued anymore, for
+// example it was cancelled.
+func (*Service) StartQueryResults(conn qrm.DB, queryID uuid6.UUID) error {
+	res, err := tbl.QueryResults.
+		UPDATE().
+		SET(tbl.QueryResults.Status.SET(postgres.String(structs.QueryStatusRunning.S()))).
+		WHERE(postgres.AND(
+			tbl.QueryResults.ID.EQ(postgres.UUID(queryID.ToUUID())),
+			tbl.QueryResults.Status.EQ(postgres.String(structs.QueryStatusQueued.S())),
+		)).
+		Exec(conn)
+	if err := handleError("start query results", err, res); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+type FinishQueryResultsReq struct {
+	ID       uuid6.UUID
+	Status   structs.QueryStatus
+	Response json.RawMessage
+	Error    string
+}
+
+// FinishQueryResults stores the outcome of a queued or running query. Finished queries are not changed.
+func (*Service) FinishQueryResults(conn qrm.DB, req FinishQueryResultsReq) error { //nolint:gocritic
+	res, err := tbl.QueryResults.
+		UPDATE().
+		SET(
+			tbl.QueryResults.Status.SET(postgres.String(req.Status.S())),
+			tbl.QueryResults.Response.SET(postgres.Json(string(req.Response))),
+			tbl.QueryResults.Error.SET(postgres.String(req.Error)),
+		).
+		WHERE(postgres.AND(
+			tbl.QueryResults.ID.EQ(postgres.UUID(req.ID.ToUUID())),
+			tbl.QueryResults.Status.IN(
+				postgres.String(structs.QueryStatusQueued.S()),
+				postgres.String(structs.QueryStatusRunning.S()),
+			),
+		)).
+		Exec(conn)
+	if err := handleError("finish query results", err, res); err != nil {
+		return err
+	}
+
+	return nil
+}
+
 func (*Service) ListQueryResultsByUser(conn qrm.DB, uid config.UserID, limit int64) ([]QueryResult, error) {
 	var items []model.QueryResults
 	//nolint:unqueryvet // ok while reading into model
@@ -99,6 +186,8 @@ func (*Service) ListQueryResultsByUser(conn qrm.DB, uid config.UserID, limit int
 			CreatedAt: item.CreatedAt,
 			Query:     item.Query,
 			Response:  item.Response,
+			Status:    structs.QueryStatus(item.Status),
+			Error:     item.Error,
 		})
 	}
 
@@ -131,6 +220,8 @@ func (*Service) ListQueryResults(conn qrm.DB, limit, offset int64) ([]QueryResul
 			CreatedAt: item.CreatedAt,
 			Query:     item.Query,
 			Response:  item.Response,
+			Status:    structs.QueryStatus(item.Status),
+			Error:     item.Error,
 		})
 	}
 
@@ -247,3 +338,283 @@ func (*Service) ListBookmarksByUser(conn qrm.DB, uid config.UserID) ([]Bookmark,
 
 	return out, nil
 }
+
+type InsertQueryApprovalReq struct {
+	ID          uuid6.UUID
+	UserID      config.UserID
+	UserName    string
+	UserRole    config.Role
+	TargetID    config.TargetID
+	Query       string
+	TargetQuery string
+	Vectors     json.RawMessage
+	CreatedAt   time.Time
+}
+
+func (*Service) InsertQueryApproval(conn qrm.DB, req InsertQueryApprovalReq) error { //nolint:gocritic
+	obj := model.QueryApprovals{
+		ID:            req.ID,
+		UserID:        req.UserID,
+		UserName:      req.UserName,
+		UserRole:      req.UserRole,
+		TargetID:      req.TargetID,
+		Query:         req.Query,
+		TargetQuery:   req.TargetQuery,
+		Vectors:       req.Vectors,
+		Status:        structs.ApprovalStatusPending.S(),
+		ReviewerID:    "",
+		ReviewComment: "",
+		Error:         "",
+		QueryResultID: nil,
+		CreatedAt:     req.CreatedAt,
+		ReviewedAt:    nil,
+	}
+	//nolint:unqueryvet // ok while reading into model
+	res, err := tbl.QueryApprovals.
+		INSERT(tbl.QueryApprovals.AllColumns).
+		MODEL(obj).
+		Exec(conn)
+	if err := handleError("insert query approval", err, res); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (*Service) GetQueryApprovalByID(conn qrm.DB, approvalID uuid6.UUID) (*QueryApproval, error) {
+	var obj model.QueryApprovals
+	//nolint:unqueryvet // ok while reading into model
+	err := tbl.QueryApprovals.
+		SELECT(tbl.QueryApprovals.AllColumns).
+		WHERE(tbl.QueryApprovals.ID.EQ(postgres.UUID(approvalID.ToUUID()))).
+		LIMIT(1).
+		Query(conn, &obj)
+	if err := handleError("get query approval by id", err, nil); err != nil {
+		return nil, err
+	}
+
+	return just.Pointer(adaptQueryApproval(obj)), nil
+}
+
+func (*Service) ListQueryApprovalsByStatus(
+	conn qrm.DB,
+	status structs.ApprovalStatus,
+	limit int64,
+) ([]QueryApproval, error) {
+	var items []model.QueryApprovals
+	//nolint:unqueryvet // ok while reading into model
+	err := tbl.QueryApprovals.
+		SELECT(tbl.QueryApprovals.AllColumns).
+		WHERE(tbl.QueryApprovals.Status.EQ(postgres.String(status.S()))).
+		ORDER_BY(tbl.QueryApprovals.CreatedAt.ASC()).
+		LIMIT(limit).
+		Query(conn, &items)
+	if err := handleError("list query approvals by status", err, nil); err != nil {
+		return nil, err
+	}
+
+	return just.SliceMap(items, adaptQueryApproval), nil
+}
+
+func (*Service) ListQueryApprovalsByUser(conn qrm.DB, uid config.UserID, limit int64) ([]QueryApproval, error) {
+	var items []model.QueryApprovals
+	//nolint:unqueryvet // ok while reading into model
+	err := tbl.QueryApprovals.
+		SELECT(tbl.QueryApprovals.AllColumns).
+		WHERE(tbl.QueryApprovals.UserID.EQ(postgres.String(uid.S()))).
+		ORDER_BY(tbl.QueryApprovals.CreatedAt.DESC()).
+		LIMIT(limit).
+		Query(conn, &items)
+	if err := handleError("list query approvals by user", err, nil); err != nil {
+		return nil, err
+	}
+
+	return just.SliceMap(items, adaptQueryApproval), nil
+}
+
+type ReviewQueryApprovalReq struct {
+	ID         uuid6.UUID
+	Status     structs.ApprovalStatus
+	ReviewerID config.UserID
+	Comment    string
+	ReviewedAt time.Time
+}
+
+// ReviewQueryApproval moves a pending approval to the given status. It returns ErrNotFound when the approval does
+// not exist or was already reviewed by someone else.
+func (*Service) ReviewQueryApproval(conn qrm.DB, req ReviewQueryApprovalReq) error { //nolint:gocritic
+	res, err := tbl.QueryApprovals.
+		UPDATE().
+		SET(
+			tbl.QueryApprovals.Status.SET(postgres.String(req.Status.S())),
+			tbl.QueryApprovals.ReviewerID.SET(postgres.String(req.ReviewerID.S())),
+			tbl.QueryApprovals.ReviewComment.SET(postgres.String(req.Comment)),
+			tbl.QueryApprovals.ReviewedAt.SET(postgres.TimestampzT(req.ReviewedAt)),
+		).
+		WHERE(postgres.AND(
+			tbl.QueryApprovals.ID.EQ(postgres.UUID(req.ID.ToUUID())),
+			tbl.QueryApprovals.Status.EQ(postgres.String(structs.ApprovalStatusPending.S())),
+		)).
+		Exec(conn)
+	if err := handleError("review query approval", err, res); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+type FinishQueryApprovalReq struct {
+	ID            uuid6.UUID
+	Status        structs.ApprovalStatus
+	QueryResultID uuid6.UUID
+	Error         string
+}
+
+// FinishQueryApproval records the outcome of an approved query.
+func (*Service) FinishQueryApproval(conn qrm.DB, req FinishQueryApprovalReq) error { //nolint:gocritic
+	resultID := just.If[postgres.StringExpression](
+		req.QueryResultID.IsNil(),
+		postgres.StringExp(postgres.NULL),
+		postgres.UUID(req.QueryResultID.ToUUID()),
+	)
+
+	res, err := tbl.QueryApprovals.
+		UPDATE().
+		SET(
+			tbl.QueryApprovals.Status.SET(postgres.String(req.Status.S())),
+			tbl.QueryApprovals.QueryResultID.SET(resultID),
+			tbl.QueryApprovals.Error.SET(postgres.String(req.Error)),
+		).
+		WHERE(postgres.AND(
+			tbl.QueryApprovals.ID.EQ(postgres.UUID(req.ID.ToUUID())),
+			tbl.QueryApprovals.Status.EQ(postgres.String(structs.ApprovalStatusApproved.S())),
+		)).
+		Exec(conn)
+	if err := handleError("finish query approval", err, res); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+type InsertBreakGlassGrantReq struct {
+	ID        uuid6.UUID
+	UserID    config.UserID
+	TargetID  config.TargetID
+	Reason    string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+func (*Service) InsertBreakGlassGrant(conn qrm.DB, req InsertBreakGlassGrantReq) error { //nolint:gocritic
+	obj := model.BreakGlassGrants{
+		ID:        req.ID,
+		UserID:    req.UserID,
+		TargetID:  req.TargetID,
+		Reason:    req.Reason,
+		CreatedAt: req.CreatedAt,
+		ExpiresAt: req.ExpiresAt,
+		RevokedAt: nil,
+		RevokedBy: "",
+	}
+	//nolint:unqueryvet // ok while reading into model
+	res, err := tbl.BreakGlassGrants.
+		INSERT(tbl.BreakGlassGrants.AllColumns).
+		MODEL(obj).
+		Exec(conn)
+	if err := handleError("insert break glass grant", err, res); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (*Service) GetBreakGlassGrantByID(conn qrm.DB, grantID uuid6.UUID) (*BreakGlassGrant, error) {
+	var obj model.BreakGlassGrants
+	//nolint:unqueryvet // ok while reading into model
+	err := tbl.BreakGlassGrants.
+		SELECT(tbl.BreakGlassGrants.AllColumns).
+		WHERE(tbl.BreakGlassGrants.ID.EQ(postgres.UUID(grantID.ToUUID()))).
+		LIMIT(1).
+		Query(conn, &obj)
+	if err := handleError("get break glass grant by id", err, nil); err != nil {
+		return nil, err
+	}
+
+	return just.Pointer(adaptBreakGlassGrant(obj)), nil
+}
+
+// ListActiveBreakGlassGrants returns grants that are not revoked and not expired at now. An empty uid means all users.
+func (*Service) ListActiveBreakGlassGrants(conn qrm.DB, uid config.UserID, now time.Time) ([]BreakGlassGrant, error) {
+	cond := postgres.AND(
+		tbl.BreakGlassGrants.RevokedAt.IS_NULL(),
+		tbl.BreakGlassGrants.ExpiresAt.GT(postgres.TimestampzT(now)),
+	)
+	if uid != "" {
+		cond = cond.AND(tbl.BreakGlassGrants.UserID.EQ(postgres.String(uid.S())))
+	}
+
+	var items []model.BreakGlassGrants
+	//nolint:unqueryvet // ok while reading into model
+	err := tbl.BreakGlassGrants.
+		SELECT(tbl.BreakGlassGrants.AllColumns).
+		WHERE(cond).
+		ORDER_BY(tbl.BreakGlassGrants.ExpiresAt.ASC()).
+		Query(conn, &items)
+	if err := handleError("list active break glass grants", err, nil); err != nil {
+		return nil, err
+	}
+
+	return just.SliceMap(items, adaptBreakGlassGrant), nil
+}
+
+// RevokeBreakGlassGrant revokes an active grant. It returns ErrNotFound when the grant is already revoked or expired.
+func (*Service) RevokeBreakGlassGrant(conn qrm.DB, grantID uuid6.UUID, revokedBy config.UserID, now time.Time) error {
+	res, err := tbl.BreakGlassGrants.
+		UPDATE().
+		SET(
+			tbl.BreakGlassGrants.RevokedAt.SET(postgres.TimestampzT(now)),
+			tbl.BreakGlassGrants.RevokedBy.SET(postgres.String(revokedBy.S())),
+		).
+		WHERE(postgres.AND(
+			tbl.BreakGlassGrants.ID.EQ(postgres.UUID(grantID.ToUUID())),
+			tbl.BreakGlassGrants.RevokedAt.IS_NULL(),
+			tbl.BreakGlassGrants.ExpiresAt.GT(postgres.TimestampzT(now)),
+		)).
+		Exec(conn)
+	if err := handleError("revoke break glass grant", err, res); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+type InsertAuditRecordReq struct {
+	ID        uuid6.UUID
+	UserID    config.UserID
+	Action    string
+	TargetID  config.TargetID
+	Details   json.RawMessage
+	CreatedAt time.Time
+}
+
+func (*Service) InsertAuditRecord(conn qrm.DB, req InsertAuditRecordReq) error { //nolint:gocritic
+	obj := model.AuditLog{
+		ID:        req.ID,
+		UserID:    req.UserID,
+		Action:    req.Action,
+		TargetID:  req.TargetID,
+		Details:   req.Details,
+		CreatedAt: req.CreatedAt,
+	}
+	//nolint:unqueryvet // ok while reading into model
+	res, err := tbl.AuditLog.
+		INSERT(tbl.AuditLog.AllColumns).
+		MODEL(obj).
+		Exec(conn)
+	if err := handleError("insert audit record", err, res); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -21,4 +21,6 @@ type QueryResults struct {
 	Query     string
 	Response  []byte
 	TargetID  config.TargetID
+	Status    string
+	Error     string
 }
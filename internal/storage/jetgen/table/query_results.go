@@ -23,6 +23,8 @@ type queryResultsTable struct {
 	Query     postgres.ColumnString
 	Response  postgres.ColumnString
 	TargetID  postgres.ColumnString
+	Status    postgres.ColumnString
+	Error     postgres.ColumnString
 
 	AllColumns     postgres.ColumnList
 	MutableColumns postgres.ColumnList
@@ -70,9 +72,11 @@ func newQueryResultsTableImpl(schemaName, tableName, alias string) queryResultsT
 		QueryColumn     = postgres.StringColumn("query")
 		ResponseColumn  = postgres.StringColumn("response")
 		TargetIDColumn  = postgres.StringColumn("target_id")
-		allColumns      = postgres.ColumnList{IDColumn, UserIDColumn, CreatedAtColumn, QueryColumn, ResponseColumn, TargetIDColumn}
-		mutableColumns  = postgres.ColumnList{UserIDColumn, CreatedAtColumn, QueryColumn, ResponseColumn, TargetIDColumn}
-		defaultColumns  = postgres.ColumnList{ResponseColumn}
+		StatusColumn    = postgres.StringColumn("status")
+		ErrorColumn     = postgres.StringColumn("error")
+		allColumns      = postgres.ColumnList{IDColumn, UserIDColumn, CreatedAtColumn, QueryColumn, ResponseColumn, TargetIDColumn, StatusColumn, ErrorColumn}
+		mutableColumns  = postgres.ColumnList{UserIDColumn, CreatedAtColumn, QueryColumn, ResponseColumn, TargetIDColumn, StatusColumn, ErrorColumn}
+		defaultColumns  = postgres.ColumnList{ResponseColumn, StatusColumn, ErrorColumn}
 	)
 
 	return queryResultsTable{
@@ -85,6 +89,8 @@ func newQueryResultsTableImpl(schemaName, tableName, alias string) queryResultsT
 		Query:     QueryColumn,
 		Response:  ResponseColumn,
 		TargetID:  TargetIDColumn,
+		Status:    StatusColumn,
+		Error:     ErrorColumn,
 
 		AllColumns:     allColumns,
 		MutableColumns: mutableColumns,
@@ -10,7 +10,10 @@ package table
 // UseSchema sets a new schema name for all generated table SQL builder types. It is recommended to invoke
 // this method only once at the beginning of the program.
 func UseSchema(schema string) {
+	AuditLog = AuditLog.FromSchema(schema)
 	Bookmarks = Bookmarks.FromSchema(schema)
+	BreakGlassGrants = BreakGlassGrants.FromSchema(schema)
 	GooseMigrations = GooseMigrations.FromSchema(schema)
+	QueryApprovals = QueryApprovals.FromSchema(schema)
 	QueryResults = QueryResults.FromSchema(schema)
 }
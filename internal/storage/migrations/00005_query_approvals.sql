@@ -0,0 +1,54 @@
+-- Database Gateway provides access to servers with ACL for safe and restricted database interactions.
+-- Copyright (C) 2024  Kirill Zhuravlev
+--
+-- This program is free software: you can redistribute it and/or modify
+-- it under the terms of the GNU General Public License as published by
+-- the Free Software Foundation, either version 3 of the License, or
+-- (at your option) any later version.
+--
+-- This program is distributed in the hope that it will be useful,
+-- but WITHOUT ANY WARRANTY; without even the implied warranty of
+-- MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+-- GNU General Public License for more details.
+--
+-- You should have received a copy of the GNU General Public License
+-- along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+-- +goose Up
+-- +goose StatementBegin
+
+create table query_approvals
+(
+    id              uuid        not null,
+    user_id         text        not null,
+    user_name       text        not null default '',
+    user_role       text        not null default '',
+    target_id       text        not null,
+    query           text        not null,
+    target_query    text        not null,
+    vectors         jsonb       not null default '[]',
+    status          text        not null,
+    reviewer_id     text        not null default '',
+    review_comment  text        not null default '',
+    error           text        not null default '',
+    query_result_id uuid,
+    created_at      timestamptz not null,
+    reviewed_at     timestamptz,
+
+    primary key (id)
+);
+
+create index idx_query_approvals_status_created_at
+    on query_approvals (status, created_at desc);
+
+create index idx_query_approvals_user_created_at
+    on query_approvals (user_id, created_at desc);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+drop table query_approvals;
+
+-- +goose StatementEnd
@@ -0,0 +1,60 @@
+-- Database Gateway provides access to servers with ACL for safe and restricted database interactions.
+-- Copyright (C) 2024  Kirill Zhuravlev
+--
+-- This program is free software: you can redistribute it and/or modify
+-- it under the terms of the GNU General Public License as published by
+-- the Free Software Foundation, either version 3 of the License, or
+-- (at your option) any later version.
+--
+-- This program is distributed in the hope that it will be useful,
+-- but WITHOUT ANY WARRANTY; without even the implied warranty of
+-- MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+-- GNU General Public License for more details.
+--
+-- You should have received a copy of the GNU General Public License
+-- along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+-- +goose Up
+-- +goose StatementBegin
+
+create table break_glass_grants
+(
+    id         uuid        not null,
+    user_id    text        not null,
+    target_id  text        not null,
+    reason     text        not null,
+    created_at timestamptz not null,
+    expires_at timestamptz not null,
+    revoked_at timestamptz,
+    revoked_by text        not null default '',
+
+    primary key (id)
+);
+
+create index idx_break_glass_grants_user_expires_at
+    on break_glass_grants (user_id, expires_at desc);
+
+create table audit_log
+(
+    id         uuid        not null,
+    user_id    text        not null,
+    action     text        not null,
+    target_id  text        not null,
+    details    jsonb       not null default '{}',
+    created_at timestamptz not null,
+
+    primary key (id)
+);
+
+create index idx_audit_log_created_at
+    on audit_log (created_at desc);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+drop table audit_log;
+drop table break_glass_grants;
+
+-- +goose StatementEnd
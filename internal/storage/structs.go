@@ -20,6 +20,7 @@ import (
 	"time"
 
 	"github.com/kazhuravlev/database-gateway/internal/config"
+	"github.com/kazhuravlev/database-gateway/internal/structs"
 	"github.com/kazhuravlev/database-gateway/internal/uuid6"
 )
 
@@ -39,4 +40,35 @@ type QueryResult struct {
 	CreatedAt time.Time
 	Query     string
 	Response  []byte
+	Status    structs.QueryStatus
+	Error     string
+}
+
+type QueryApproval struct {
+	ID            uuid6.UUID
+	UserID        config.UserID
+	UserName      string
+	UserRole      config.Role
+	TargetID      config.TargetID
+	Query         string
+	TargetQuery   string
+	Vectors       []byte
+	Status        structs.ApprovalStatus
+	ReviewerID    config.UserID
+	ReviewComment string
+	Error         string
+	QueryResultID uuid6.UUID
+	CreatedAt     time.Time
+	ReviewedAt    *time.Time
+}
+
+type BreakGlassGrant struct {
+	ID        uuid6.UUID
+	UserID    config.UserID
+	TargetID  config.TargetID
+	Reason    string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	RevokedAt *time.Time
+	RevokedBy config.UserID
 }
@@ -17,6 +17,8 @@
 package structs
 
 import (
+	"time"
+
 	"github.com/kazhuravlev/database-gateway/internal/config"
 )
 
@@ -31,11 +33,61 @@ type Server struct {
 	Tags        []Tag
 	Type        string
 	Tables      []config.TargetTable
+	Health      TargetHealth
+}
+
+// TargetStatus is the outcome of the last health probe of a target.
+type TargetStatus string
+
+const (
+	TargetStatusUnknown TargetStatus = "unknown"
+	TargetStatusUp      TargetStatus = "up"
+	TargetStatusDown    TargetStatus = "down"
+)
+
+// TargetHealth is cached by the background prober. Status is unknown until the first probe has finished.
+type TargetHealth struct {
+	Status    TargetStatus `json:"status"`
+	CheckedAt *time.Time   `json:"checked_at,omitempty"`
+	LatencyMs int64        `json:"latency_ms"`
+	Version   string       `json:"version,omitempty"`
+	IsReplica bool         `json:"is_replica"`
+	// ReplicationLagSeconds is the age of the last replayed transaction. It is set only for replicas.
+	ReplicationLagSeconds *float64 `json:"replication_lag_seconds,omitempty"`
+	Error                 string   `json:"error,omitempty"`
+}
+
+// TargetPoolStats is a snapshot of the connection pool of a target.
+type TargetPoolStats struct {
+	TargetID config.TargetID `json:"target_id"`
+	// Connected is false when nobody has used the target yet, so the pool does not exist.
+	Connected            bool  `json:"connected"`
+	MaxConns             int32 `json:"max_conns"`
+	TotalConns           int32 `json:"total_conns"`
+	IdleConns            int32 `json:"idle_conns"`
+	AcquiredConns        int32 `json:"acquired_conns"`
+	ConstructingConns    int32 `json:"constructing_conns"`
+	AcquireCount         int64 `json:"acquire_count"`
+	EmptyAcquireCount    int64 `json:"empty_acquire_count"`
+	CanceledAcquireCount int64 `json:"canceled_acquire_count"`
+	AcquireDurationMs    int64 `json:"acquire_duration_ms"`
 }
 
+// QTable is a query result. Cells are JSON-ready values: nil for NULL, bool, int64, float64 and json.Number for
+// numbers, decoded json and arrays as is, text representation for everything else.
 type QTable struct {
-	Headers []string   `json:"headers"`
-	Rows    [][]string `json:"rows"`
+	Headers []string `json:"headers"`
+	// Columns is empty for results stored before column metadata was collected.
+	Columns []QColumn `json:"columns,omitempty"`
+	Rows    [][]any   `json:"rows"`
+}
+
+type QColumn struct {
+	Name     string `json:"name"`
+	TypeOID  uint32 `json:"type_oid"`
+	TypeName string `json:"type_name"`
+	// Nullable is false only for table columns declared NOT NULL.
+	Nullable bool `json:"nullable"`
 }
 
 type QMeta struct {
@@ -45,6 +97,20 @@ type QMeta struct {
 	RowsCount          int   `json:"rows_count,omitempty"`
 	ColumnsCount       int   `json:"columns_count,omitempty"`
 	VectorsCount       int   `json:"vectors_count,omitempty"`
+	// Cancelled is set when the query was stopped by the user or by the statement timeout of the target.
+	Cancelled    bool   `json:"cancelled,omitempty"`
+	CancelReason string `json:"cancel_reason,omitempty"`
+	// Truncated is set when the result was cut at Limit rows or LimitBytes bytes of cells.
+	Truncated  bool  `json:"truncated,omitempty"`
+	Limit      int   `json:"limit,omitempty"`
+	LimitBytes int64 `json:"limit_bytes,omitempty"`
+	// AffectedRows and PreviewOutcome are filled for previewed writes: committed, rolled_back or expired.
+	AffectedRows   int64  `json:"affected_rows,omitempty"`
+	PreviewOutcome string `json:"preview_outcome,omitempty"`
+	// DBRole is the database role the query ran as on a target that impersonates users.
+	DBRole string `json:"db_role,omitempty"`
+	// Replica is the id of the read replica the query ran on. Empty when it ran on the primary.
+	Replica string `json:"replica,omitempty"`
 }
 
 type User struct {
@@ -84,3 +150,65 @@ type AdminRequestDetails struct {
 	QTable    QTable
 	Meta      *QMeta
 }
+
+type ApprovalStatus string
+
+const (
+	ApprovalStatusPending  ApprovalStatus = "pending"
+	ApprovalStatusApproved ApprovalStatus = "approved"
+	ApprovalStatusRejected ApprovalStatus = "rejected"
+	ApprovalStatusExecuted ApprovalStatus = "executed"
+	ApprovalStatusFailed   ApprovalStatus = "failed"
+)
+
+func (s ApprovalStatus) S() string {
+	return string(s)
+}
+
+// QueryStatus is the state of a query in history. Synchronous queries are stored already finished.
+type QueryStatus string
+
+const (
+	QueryStatusQueued    QueryStatus = "queued"
+	QueryStatusRunning   QueryStatus = "running"
+	QueryStatusSucceeded QueryStatus = "succeeded"
+	QueryStatusFailed    QueryStatus = "failed"
+	QueryStatusCancelled QueryStatus = "cancelled"
+)
+
+func (s QueryStatus) S() string {
+	return string(s)
+}
+
+type QueryVector struct {
+	Op      string   `json:"op"`
+	Table   string   `json:"table"`
+	Columns []string `json:"columns"`
+	Filter  []string `json:"filter,omitempty"`
+	Group   []string `json:"group,omitempty"`
+	Sort    []string `json:"sort,omitempty"`
+}
+
+type QueryApproval struct {
+	ID            string
+	UserID        config.UserID
+	TargetID      config.TargetID
+	Query         string
+	Vectors       []QueryVector
+	Status        ApprovalStatus
+	ReviewerID    config.UserID
+	ReviewComment string
+	Error         string
+	QueryResultID string
+	CreatedAt     string
+	ReviewedAt    string
+}
+
+type BreakGlassGrant struct {
+	ID        string
+	UserID    config.UserID
+	TargetID  config.TargetID
+	Reason    string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
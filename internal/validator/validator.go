@@ -19,6 +19,7 @@ package validator
 import (
 	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/kazhuravlev/just"
 )
@@ -31,8 +32,13 @@ var (
 	ErrUnknownColumn    = errors.New("unknown column")
 )
 
-// IsAllowed will tokenize query, validate schema and check access after all.
+// IsAllowed will expand star expressions, tokenize query, validate schema and check access after all.
 func IsAllowed(schema *DbSchema, haveAccess func(Vec) bool, query string) error {
+	query, err := ExpandStar(query, schema)
+	if err != nil {
+		return fmt.Errorf("expand star: %w", err)
+	}
+
 	vectors, err := MakeVectors(query)
 	if err != nil {
 		return fmt.Errorf("make vectors: %w", err)
@@ -69,10 +75,32 @@ func ValidateSchema(vectors []Vec, schema *DbSchema) error {
 	return nil
 }
 
-// ValidateAccess check that all vectors is allowed to run.
+// AccessDeniedError describes the vector that was denied by policy.
+type AccessDeniedError struct {
+	Vec Vec
+	// Reasons contains explanations from policy. Can be empty.
+	Reasons []string
+}
+
+func (e *AccessDeniedError) Error() string {
+	msg := fmt.Sprintf("denied operation: %s is not allowed on %s", e.Vec.Op, e.Vec.Tbl)
+	if len(e.Reasons) != 0 {
+		msg += ": " + strings.Join(e.Reasons, "; ")
+	}
+
+	return msg
+}
+
+func (e *AccessDeniedError) Unwrap() error {
+	return ErrAccessDenied
+}
+
+// ValidateAccess check that all vectors is allowed to run. Returns AccessDeniedError for the first denied vector.
 func ValidateAccess(vectors []Vec, haveAccess func(Vec) bool) error {
-	if !just.SliceAll(vectors, haveAccess) {
-		return fmt.Errorf("denied operation: %w", ErrAccessDenied)
+	for _, vec := range vectors {
+		if !haveAccess(vec) {
+			return &AccessDeniedError{Vec: vec, Reasons: nil}
+		}
 	}
 
 	return nil
@@ -20,6 +20,7 @@ import (
 	"testing"
 
 	"github.com/kazhuravlev/database-gateway/internal/config"
+	"github.com/kazhuravlev/database-gateway/internal/parser"
 	"github.com/kazhuravlev/database-gateway/internal/validator"
 	"github.com/stretchr/testify/require"
 )
@@ -176,8 +177,6 @@ WHERE total_sales > (SELECT SUM(total_sales) / 10 FROM regional_sales)`,
 		},
 		{name: "join_using_clause", query: `SELECT c.id FROM clients AS c JOIN orders AS o USING (id)`},
 		{name: "natural_join", query: `SELECT c.id FROM clients AS c NATURAL JOIN orders AS o`},
-		{name: "join_subquery_rhs", query: `SELECT c.id FROM clients AS c JOIN (SELECT client_id FROM orders) AS o ON c.id = o.client_id`},
-		{name: "union", query: `SELECT id FROM clients UNION SELECT id FROM orders`},
 	}
 
 	haveAccess := func(_ validator.Vec) bool { return true }
@@ -210,12 +209,63 @@ func TestValidatorSelect(t *testing.T) {
 		require.ErrorIs(t, err, validator.ErrAccessDenied)
 	})
 
-	t.Run("select_from_allowed_select__is_not_allowed", func(t *testing.T) {
+	t.Run("select_from_allowed_select", func(t *testing.T) {
 		t.Parallel()
 		haveAccess := func(_ validator.Vec) bool { return true }
-		query := `select id, name from (select id, name from clients)`
+		query := `select id, name from (select id, name from clients) as sub`
 		err := validator.IsAllowed(helpSchemaFromTables(testTargetTables()), haveAccess, query)
-		require.ErrorIs(t, err, validator.ErrComplicatedQuery)
+		require.NoError(t, err)
+	})
+
+	t.Run("subquery_schema_checks_nested_columns", func(t *testing.T) {
+		t.Parallel()
+		haveAccess := func(_ validator.Vec) bool { return true }
+		query := `select id from clients where id in (select id from clients where password = 'x')`
+		err := validator.IsAllowed(helpSchemaFromTables(testTargetTables()), haveAccess, query)
+		require.ErrorIs(t, err, validator.ErrAccessDenied)
+	})
+
+	t.Run("subquery_access_requires_nested_tables", func(t *testing.T) {
+		t.Parallel()
+
+		schema := helpSchemaFromTables([]config.TargetTable{
+			{
+				Table:  "public.clients",
+				Fields: []string{"id", "name"},
+			},
+			{
+				Table:  "public.transfers",
+				Fields: []string{"id", "client_id"},
+			},
+		})
+
+		haveAccess := func(vec validator.Vec) bool {
+			return vec.Tbl == "clients"
+		}
+
+		query := `select name from clients where id in (select client_id from transfers)`
+		err := validator.IsAllowed(schema, haveAccess, query)
+		require.ErrorIs(t, err, validator.ErrAccessDenied)
+	})
+
+	t.Run("dotted_alias_does_not_shadow_outer_table", func(t *testing.T) {
+		t.Parallel()
+
+		schema := helpSchemaFromTables([]config.TargetTable{
+			{
+				Table:  "public.clients",
+				Fields: []string{"id"},
+			},
+			{
+				Table:  "public.orders",
+				Fields: []string{"id", "email"},
+			},
+		})
+
+		haveAccess := func(_ validator.Vec) bool { return true }
+		query := `select id from clients where exists (select public.clients.email from orders as "public.clients")`
+		err := validator.IsAllowed(schema, haveAccess, query)
+		require.ErrorIs(t, err, parser.ErrInvalidAlias)
 	})
 
 	t.Run("join_access_requires_all_tables", func(t *testing.T) {
@@ -244,6 +294,41 @@ where o.status = 'paid'`
 		require.ErrorIs(t, err, validator.ErrAccessDenied)
 	})
 
+	t.Run("union_access_requires_all_branches", func(t *testing.T) {
+		t.Parallel()
+
+		schema := helpSchemaFromTables([]config.TargetTable{
+			{
+				Table:  "public.clients",
+				Fields: []string{"id", "name"},
+			},
+			{
+				Table:  "public.orders",
+				Fields: []string{"id", "client_id"},
+			},
+		})
+
+		query := `select id from clients union select client_id from orders`
+
+		allowAll := func(_ validator.Vec) bool { return true }
+		require.NoError(t, validator.IsAllowed(schema, allowAll, query))
+
+		onlyClients := func(vec validator.Vec) bool {
+			return vec.Tbl == "clients"
+		}
+		err := validator.IsAllowed(schema, onlyClients, query)
+		require.ErrorIs(t, err, validator.ErrAccessDenied)
+	})
+
+	t.Run("union_schema_checks_every_branch", func(t *testing.T) {
+		t.Parallel()
+
+		haveAccess := func(_ validator.Vec) bool { return true }
+		query := `select id from clients union select id from orders`
+		err := validator.IsAllowed(helpSchemaFromTables(testTargetTables()), haveAccess, query)
+		require.ErrorIs(t, err, validator.ErrAccessDenied)
+	})
+
 	t.Run("join_schema_checks_every_referenced_table", func(t *testing.T) {
 		t.Parallel()
 
@@ -263,6 +348,65 @@ join public.orders as o on c.id = o.client_id`
 	})
 }
 
+func TestExpandStar(t *testing.T) {
+	t.Parallel()
+
+	t.Run("only_allowlisted_columns", func(t *testing.T) {
+		t.Parallel()
+
+		query, err := validator.ExpandStar(`select * from clients`, helpSchemaFromTables(testTargetTables())) //nolint:unqueryvet
+		require.NoError(t, err)
+		require.Equal(t, `SELECT clients.id, clients.name, clients.email FROM clients`, query)
+	})
+
+	t.Run("unknown_table", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := validator.ExpandStar(`select * from orders`, helpSchemaFromTables(testTargetTables())) //nolint:unqueryvet
+		require.ErrorIs(t, err, validator.ErrAccessDenied)
+	})
+
+	t.Run("star_is_allowed", func(t *testing.T) {
+		t.Parallel()
+
+		var vectors []validator.Vec
+		haveAccess := func(vec validator.Vec) bool {
+			vectors = append(vectors, vec)
+
+			return true
+		}
+
+		err := validator.IsAllowed(helpSchemaFromTables(testTargetTables()), haveAccess, `select c.* from clients c`)
+		require.NoError(t, err)
+		require.Equal(t, []validator.Vec{
+			{Op: config.OpSelect, Tbl: "clients", Cols: []string{"id", "name", "email"}},
+		}, vectors)
+	})
+}
+
+func TestValidateAccessReturnsDeniedVector(t *testing.T) {
+	t.Parallel()
+
+	vectors := []validator.Vec{
+		{Op: config.OpSelect, Tbl: "clients", Cols: []string{"id"}, Filter: nil, Group: nil, Sort: nil},
+		{Op: config.OpSelect, Tbl: "orders", Cols: []string{"id"}, Filter: nil, Group: nil, Sort: nil},
+	}
+	haveAccess := func(vec validator.Vec) bool {
+		return vec.Tbl == "clients"
+	}
+
+	err := validator.ValidateAccess(vectors, haveAccess)
+	require.ErrorIs(t, err, validator.ErrAccessDenied)
+
+	var denied *validator.AccessDeniedError
+	require.ErrorAs(t, err, &denied)
+	require.Equal(t, vectors[1], denied.Vec)
+	require.Empty(t, denied.Reasons)
+
+	denied.Reasons = []string{"orders are restricted"}
+	require.Contains(t, err.Error(), "select is not allowed on orders: orders are restricted")
+}
+
 func TestValidatorUpdate(t *testing.T) {
 	t.Parallel()
 
@@ -23,12 +23,18 @@ import (
 
 	"github.com/kazhuravlev/database-gateway/internal/config"
 	parser2 "github.com/kazhuravlev/database-gateway/internal/parser"
+	"github.com/kazhuravlev/just"
 )
 
 type Vec struct {
-	Op   config.Op
-	Tbl  string
+	Op  config.Op
+	Tbl string
+	// Cols contains all columns of the table that used in query.
 	Cols []string
+	// Filter, Group and Sort are subsets of Cols that used in WHERE (and JOIN), GROUP BY and ORDER BY clauses.
+	Filter []string
+	Group  []string
+	Sort   []string
 }
 
 func (v Vec) String() string {
@@ -53,30 +59,93 @@ func MakeVectors(query string) ([]Vec, error) { //nolint:cyclop
 			return nil, fmt.Errorf("unexpected type (%T): %w", expr, ErrBadQuery)
 		case parser2.SelectVec:
 			vectors[i] = Vec{
-				Op:   config.OpSelect,
-				Tbl:  expr.Tbl,
-				Cols: expr.Columns(),
+				Op:     config.OpSelect,
+				Tbl:    expr.Tbl,
+				Cols:   expr.Columns(),
+				Filter: uniqColumns(expr.Filter),
+				Group:  uniqColumns(expr.Group),
+				Sort:   uniqColumns(expr.Sort),
 			}
 		case parser2.InsertVec:
 			vectors[i] = Vec{
-				Op:   config.OpInsert,
-				Tbl:  expr.Tbl,
-				Cols: expr.Columns(),
+				Op:     config.OpInsert,
+				Tbl:    expr.Tbl,
+				Cols:   expr.Columns(),
+				Filter: nil,
+				Group:  nil,
+				Sort:   nil,
 			}
 		case parser2.UpdateVec:
 			vectors[i] = Vec{
-				Op:   config.OpUpdate,
-				Tbl:  expr.Tbl,
-				Cols: expr.Columns(),
+				Op:     config.OpUpdate,
+				Tbl:    expr.Tbl,
+				Cols:   expr.Columns(),
+				Filter: uniqColumns(expr.Filter),
+				Group:  nil,
+				Sort:   nil,
 			}
 		case parser2.DeleteVec:
 			vectors[i] = Vec{
-				Op:   config.OpDelete,
-				Tbl:  expr.Tbl,
-				Cols: expr.Columns(),
+				Op:     config.OpDelete,
+				Tbl:    expr.Tbl,
+				Cols:   expr.Columns(),
+				Filter: uniqColumns(expr.Filter),
+				Group:  nil,
+				Sort:   nil,
 			}
 		}
 	}
 
 	return vectors, nil
 }
+
+func uniqColumns(columns []string) []string {
+	if len(columns) == 0 {
+		return nil
+	}
+
+	return just.SliceUniq(columns)
+}
+
+// ExpandStar rewrites star expressions of query into the list of columns that allowed by schema.
+func ExpandStar(query string, schema *DbSchema) (string, error) {
+	res, err := parser2.ExpandStar(query, schemaColumns(schema))
+	if err != nil {
+		if errors.Is(err, parser2.ErrNotImplemented) {
+			return "", errors.Join(err, ErrComplicatedQuery)
+		}
+
+		return "", fmt.Errorf("expand star: %w", err)
+	}
+
+	return res, nil
+}
+
+// AddReturning appends RETURNING with the columns allowed by schema to UPDATE and DELETE queries without one.
+func AddReturning(query string, schema *DbSchema) (string, error) {
+	res, err := parser2.AddReturning(query, schemaColumns(schema))
+	if err != nil {
+		if errors.Is(err, parser2.ErrNotImplemented) {
+			return "", errors.Join(err, ErrComplicatedQuery)
+		}
+
+		return "", fmt.Errorf("add returning: %w", err)
+	}
+
+	return res, nil
+}
+
+func schemaColumns(schema *DbSchema) parser2.ColumnsFn {
+	return func(table string) ([]string, error) {
+		if schema == nil {
+			return nil, fmt.Errorf("schema is not defined: %w", errors.Join(ErrUnknownTable, ErrAccessDenied))
+		}
+
+		tbl, ok := schema.GetTable(table)
+		if !ok {
+			return nil, fmt.Errorf("not known table: %w", errors.Join(ErrUnknownTable, ErrAccessDenied))
+		}
+
+		return tbl.Fields, nil
+	}
+}
@@ -48,9 +48,12 @@ func TestMakeVectors(t *testing.T) {
 		test("select_complex",
 			`select f1, count(f2) from clients where f3=1 group by f4 order by f5`,
 			[]validator.Vec{{
-				Op:   config.OpSelect,
-				Tbl:  "clients",
-				Cols: []string{"f1", "f2", "f3", "f4", "f5"},
+				Op:     config.OpSelect,
+				Tbl:    "clients",
+				Cols:   []string{"f1", "f2", "f3", "f4", "f5"},
+				Filter: []string{"f3"},
+				Group:  []string{"f4"},
+				Sort:   []string{"f5"},
 			}})
 		test("insert_complex",
 			`insert into clients (f1, f2) values (1, 2) on conflict (f3, f4) do update set f5=33 returning f6`,
@@ -62,16 +65,18 @@ func TestMakeVectors(t *testing.T) {
 		test("update_complex",
 			`update clients set f1=1 where f2=2 returning f3`,
 			[]validator.Vec{{
-				Op:   config.OpUpdate,
-				Tbl:  "clients",
-				Cols: []string{"f1", "f2", "f3"},
+				Op:     config.OpUpdate,
+				Tbl:    "clients",
+				Cols:   []string{"f1", "f2", "f3"},
+				Filter: []string{"f2"},
 			}})
 		test("delete_complex",
 			`delete from clients where f1=1 returning f2`,
 			[]validator.Vec{{
-				Op:   config.OpDelete,
-				Tbl:  "clients",
-				Cols: []string{"f1", "f2"},
+				Op:     config.OpDelete,
+				Tbl:    "clients",
+				Cols:   []string{"f1", "f2"},
+				Filter: []string{"f1"},
 			}})
 	})
 
@@ -88,14 +93,16 @@ func TestMakeVectors(t *testing.T) {
 				query: `select clients.id, orders.id from clients inner join orders on clients.id = orders.client_id`,
 				exp: []validator.Vec{
 					{
-						Op:   config.OpSelect,
-						Tbl:  "clients",
-						Cols: []string{"id"},
+						Op:     config.OpSelect,
+						Tbl:    "clients",
+						Cols:   []string{"id"},
+						Filter: []string{"id"},
 					},
 					{
-						Op:   config.OpSelect,
-						Tbl:  "orders",
-						Cols: []string{"client_id", "id"},
+						Op:     config.OpSelect,
+						Tbl:    "orders",
+						Cols:   []string{"client_id", "id"},
+						Filter: []string{"client_id"},
 					},
 				},
 			},
@@ -109,14 +116,18 @@ group by c.id
 order by c.id`,
 				exp: []validator.Vec{
 					{
-						Op:   config.OpSelect,
-						Tbl:  "clients",
-						Cols: []string{"id"},
+						Op:     config.OpSelect,
+						Tbl:    "clients",
+						Cols:   []string{"id"},
+						Filter: []string{"id"},
+						Group:  []string{"id"},
+						Sort:   []string{"id"},
 					},
 					{
-						Op:   config.OpSelect,
-						Tbl:  "orders",
-						Cols: []string{"client_id", "id", "status"},
+						Op:     config.OpSelect,
+						Tbl:    "orders",
+						Cols:   []string{"client_id", "id", "status"},
+						Filter: []string{"client_id", "status"},
 					},
 				},
 			},
@@ -128,14 +139,16 @@ join billing.orders on public.clients.id = billing.orders.client_id
 where billing.orders.status = 'paid'`,
 				exp: []validator.Vec{
 					{
-						Op:   config.OpSelect,
-						Tbl:  "billing.orders",
-						Cols: []string{"amount", "client_id", "status"},
+						Op:     config.OpSelect,
+						Tbl:    "billing.orders",
+						Cols:   []string{"amount", "client_id", "status"},
+						Filter: []string{"client_id", "status"},
 					},
 					{
-						Op:   config.OpSelect,
-						Tbl:  "public.clients",
-						Cols: []string{"id"},
+						Op:     config.OpSelect,
+						Tbl:    "public.clients",
+						Cols:   []string{"id"},
+						Filter: []string{"id"},
 					},
 				},
 			},
@@ -147,9 +160,28 @@ join clients as parent on c.parent_id = parent.id
 where parent.active = true`,
 				exp: []validator.Vec{
 					{
-						Op:   config.OpSelect,
-						Tbl:  "clients",
-						Cols: []string{"active", "id", "name", "parent_id"},
+						Op:     config.OpSelect,
+						Tbl:    "clients",
+						Cols:   []string{"active", "id", "name", "parent_id"},
+						Filter: []string{"active", "id", "parent_id"},
+					},
+				},
+			},
+			{
+				name:  "join_subquery_rhs",
+				query: `select c.id from clients as c join (select client_id from orders) as o on c.id = o.client_id`,
+				exp: []validator.Vec{
+					{
+						Op:     config.OpSelect,
+						Tbl:    "clients",
+						Cols:   []string{"id"},
+						Filter: []string{"id"},
+					},
+					{
+						Op:     config.OpSelect,
+						Tbl:    "orders",
+						Cols:   []string{"client_id"},
+						Filter: []string{"client_id"},
 					},
 				},
 			},
@@ -165,6 +197,7 @@ where parent.active = true`,
 
 				for i := range vecs {
 					sort.Strings(vecs[i].Cols)
+					sort.Strings(vecs[i].Filter)
 				}
 
 				sort.Slice(vecs, func(i, j int) bool {
@@ -208,8 +241,8 @@ where parent.active = true`,
 		test("natural_join",
 			`select c.id from clients as c natural join orders as o`,
 			validator.ErrComplicatedQuery)
-		test("join_subquery_rhs",
-			`select c.id from clients as c join (select client_id from orders) as o on c.id = o.client_id`,
+		test("lateral_subquery",
+			`select c.id from clients as c join lateral (select client_id from orders where client_id = c.id) as o on true`,
 			validator.ErrComplicatedQuery)
 		test("join_with_ambiguous_column",
 			`select c.id from clients as c join orders as o on id = o.client_id`,